@@ -14,6 +14,13 @@ import (
 // @description This is an Address API for Swagger documentation.
 // @host localhost:3048
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	log.Println("Starting api server")
 
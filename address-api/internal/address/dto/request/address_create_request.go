@@ -1,8 +1,17 @@
 package request
 
 type AddressCreateRequest struct {
-	City        string `json:"city" validate:"required,min=3,max=20"`
-	Country     string `json:"country" validate:"required,min=3,max=20"`
-	FullAddress string `json:"fullAddress" validate:"required,min=10,max=100"`
-	UserId      string `json:"userId" validate:"required"`
+	City         string   `json:"city" validate:"required,min=3,max=20"`
+	Country      string   `json:"country" validate:"required,min=3,max=20"`
+	FullAddress  string   `json:"fullAddress" validate:"required_without=AddressLine1,omitempty,min=10,max=100"`
+	UserId       string   `json:"userId" validate:"omitempty,max=50"`
+	AddressLine1 string   `json:"addressLine1" validate:"required_without=FullAddress,omitempty,max=100"`
+	AddressLine2 string   `json:"addressLine2" validate:"omitempty,max=100"`
+	HouseNumber  string   `json:"houseNumber" validate:"omitempty,max=20"`
+	District     string   `json:"district" validate:"omitempty,max=50"`
+	Region       string   `json:"region" validate:"omitempty,max=50"`
+	PostalCode   string   `json:"postalCode" validate:"omitempty,max=20"`
+	CountryCode  string   `json:"countryCode" validate:"omitempty,iso3166_1_alpha2"`
+	Latitude     *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,latitude"`
+	Longitude    *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,longitude"`
 }
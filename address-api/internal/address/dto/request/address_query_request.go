@@ -1 +1,36 @@
 package request
+
+const (
+	DefaultPage     = 1
+	DefaultPageSize = 10
+)
+
+const (
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+)
+
+// AddressQueryRequest is bound from the query string of the address listing endpoints
+// sort accepts a comma separated list of fields with an optional direction, e.g. sort=city:asc,createdAt:desc
+// cursor pagination is used when pagination=cursor or a cursor is given, it supports a single id, createdAt or updatedAt sort
+type AddressQueryRequest struct {
+	Page        int    `query:"page" validate:"omitempty,min=1"`
+	Size        int    `query:"size" validate:"omitempty,min=1,max=100"`
+	UserId      string `query:"userId" validate:"omitempty,max=50"`
+	City        string `query:"city" validate:"omitempty,max=20"`
+	Country     string `query:"country" validate:"omitempty,max=20"`
+	CreatedFrom string `query:"createdFrom" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedTo   string `query:"createdTo" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedFrom string `query:"updatedFrom" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedTo   string `query:"updatedTo" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Search      string `query:"q" validate:"omitempty,max=100"`
+	Sort        string `query:"sort" validate:"omitempty,sortable=id city country userId postalCode createdAt updatedAt"`
+	Pagination  string `query:"pagination" validate:"omitempty,oneof=offset cursor"`
+	Cursor      string `query:"cursor" validate:"omitempty,max=512"`
+	WithCount   bool   `query:"withCount"`
+}
+
+// IsCursorPagination reports whether the request asks for keyset pagination
+func (q AddressQueryRequest) IsCursorPagination() bool {
+	return q.Pagination == PaginationCursor || q.Cursor != ""
+}
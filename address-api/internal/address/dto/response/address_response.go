@@ -1,9 +1,18 @@
 package response
 
 type AddressResponse struct {
-	Id          int    `json:"id"`
-	City        string `json:"city"`
-	Country     string `json:"country"`
-	FullAddress string `json:"fullAddress"`
-	UserId      string `json:"userId"`
+	Id           int      `json:"id"`
+	City         string   `json:"city"`
+	Country      string   `json:"country"`
+	FullAddress  string   `json:"fullAddress"`
+	UserId       string   `json:"userId"`
+	AddressLine1 string   `json:"addressLine1,omitempty"`
+	AddressLine2 string   `json:"addressLine2,omitempty"`
+	HouseNumber  string   `json:"houseNumber,omitempty"`
+	District     string   `json:"district,omitempty"`
+	Region       string   `json:"region,omitempty"`
+	PostalCode   string   `json:"postalCode,omitempty"`
+	CountryCode  string   `json:"countryCode,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
 }
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Address struct {
 	Id          int       `gorm:"primary_key" json:"id"`
@@ -10,4 +13,40 @@ type Address struct {
 	Country     string    `json:"country"`
 	FullAddress string    `json:"full_address"`
 	UserId      string    `json:"user_id"`
+	TenantId    string    `gorm:"index;size:64" json:"tenant_id"`
+	// AddressLine1 is empty for addresses stored with the free-text address only
+	AddressLine1 string   `json:"address_line1"`
+	AddressLine2 string   `json:"address_line2"`
+	HouseNumber  string   `json:"house_number"`
+	District     string   `json:"district"`
+	Region       string   `json:"region"`
+	PostalCode   string   `gorm:"index" json:"postal_code"`
+	CountryCode  string   `gorm:"size:2" json:"country_code"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	// Version starts at 1 and grows with every change, address events carry it so consumers can order them
+	Version int64 `gorm:"not null;default:1" json:"version"`
+}
+
+// ComposeFullAddress builds a single line representation from the structured components, an address without
+// an address line keeps its free-text address
+func (a Address) ComposeFullAddress() string {
+	if a.AddressLine1 == "" && a.FullAddress != "" {
+		return a.FullAddress
+	}
+
+	street := strings.TrimSpace(strings.Join(nonEmpty(a.AddressLine1, a.HouseNumber), " "))
+	locality := strings.TrimSpace(strings.Join(nonEmpty(a.PostalCode, a.City), " "))
+
+	return strings.Join(nonEmpty(street, a.AddressLine2, a.District, locality, a.Region, a.Country), ", ")
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
 }
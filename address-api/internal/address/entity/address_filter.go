@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"github.com/sefikcan/address-api/internal/common"
+	"time"
+)
+
+// CursorSortFields are the fields usable as keyset, they are never null and indexed or monotonic
+var CursorSortFields = map[string]bool{
+	"id":        true,
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// AddressFilter holds the listing criteria applied by the repository
+type AddressFilter struct {
+	UserId      string
+	City        string
+	Country     string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Search      string
+	Sort        []SortField
+	Page        int
+	PageSize    int
+	Cursor      *common.Cursor
+	WithCount   bool
+}
+
+// SortField is a single sort criterion, Field is the public field name (e.g. createdAt)
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// SortValue returns the keyset value of the address for one of the CursorSortFields
+func (a Address) SortValue(field string) string {
+	switch field {
+	case "createdAt":
+		return a.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updatedAt":
+		return a.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
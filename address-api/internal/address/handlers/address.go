@@ -23,28 +23,52 @@ type addressHandler struct {
 
 // GetAll godoc
 // @Summary Get all addresses
-// @Description Get all addresses with pagination
+// @Description Get all addresses with pagination, filtering and sorting
 // @Tags addresses
-// @Param page query int false "Page number"
-// @Param size query int false "Page size"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param page query int false "Page number (starts from 1)"
+// @Param size query int false "Page size (max 100)"
+// @Param userId query string false "Filter by user id"
+// @Param city query string false "Filter by city (case insensitive)"
+// @Param country query string false "Filter by country (case insensitive)"
+// @Param createdFrom query string false "Created at lower bound (RFC3339)"
+// @Param createdTo query string false "Created at upper bound (RFC3339)"
+// @Param updatedFrom query string false "Updated at lower bound (RFC3339)"
+// @Param updatedTo query string false "Updated at upper bound (RFC3339)"
+// @Param q query string false "Search in full address"
+// @Param sort query string false "Sort fields, e.g. city:asc,createdAt:desc (id, city, country, userId, postalCode, createdAt, updatedAt)"
+// @Param pagination query string false "Pagination mode" Enums(offset, cursor)
+// @Param cursor query string false "Opaque cursor from nextCursor/prevCursor, implies cursor pagination"
+// @Param withCount query bool false "Include total item count in cursor mode"
 // @Success 200 {array} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses [get]
 func (a addressHandler) GetAll(c *fiber.Ctx) error {
-	page := c.Query("page", "0")
-	size := c.Query("size", "10")
+	var query request.AddressQueryRequest
+	if err := c.QueryParser(&query); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
 
-	pageNumber, err := strconv.Atoi(page)
-	if err != nil {
-		pageNumber = 0
+	if query.IsCursorPagination() {
+		return a.getAllByCursor(c, query)
 	}
-	pageSize, err := strconv.Atoi(size)
+
+	addresses, err := a.addressService.GetAll(c.UserContext(), query)
 	if err != nil {
-		pageSize = 10
+		return err
 	}
 
-	addresses, err := a.addressService.GetAll(c.Context(), pageNumber, pageSize)
+	return c.JSON(addresses)
+}
+
+func (a addressHandler) getAllByCursor(c *fiber.Ctx, query request.AddressQueryRequest) error {
+	addresses, err := a.addressService.GetAllByCursor(c.UserContext(), query)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "unable to retrieve addresses")
+		return err
 	}
 
 	return c.JSON(addresses)
@@ -54,8 +78,15 @@ func (a addressHandler) GetAll(c *fiber.Ctx) error {
 // @Summary Get an address by ID
 // @Description Retrieve an address by its ID
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Address ID"
 // @Success 200 {object} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses/{id} [get]
 func (a addressHandler) GetById(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -63,9 +94,9 @@ func (a addressHandler) GetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
 	}
 
-	currentAddress, err := a.addressService.GetById(c.Context(), id)
+	currentAddress, err := a.addressService.GetById(c.UserContext(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(currentAddress)
@@ -75,8 +106,15 @@ func (a addressHandler) GetById(c *fiber.Ctx) error {
 // @Summary Delete an address
 // @Description Delete an address by its ID
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Address ID"
 // @Success 204
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses/{id} [delete]
 func (a addressHandler) Delete(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -84,9 +122,9 @@ func (a addressHandler) Delete(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
 	}
 
-	err = a.addressService.Delete(c.Context(), id)
+	err = a.addressService.Delete(c.UserContext(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -96,9 +134,16 @@ func (a addressHandler) Delete(c *fiber.Ctx) error {
 // @Summary Update an address
 // @Description Update an address by its ID
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Address ID"
 // @Param address body request.AddressUpdateRequest true "Address update payload"
 // @Success 200 {object} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses/{id} [put]
 func (a addressHandler) Update(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -112,9 +157,9 @@ func (a addressHandler) Update(c *fiber.Ctx) error {
 	}
 
 	address.Id = id
-	updatedAddress, err := a.addressService.Update(c.Context(), address)
+	updatedAddress, err := a.addressService.Update(c.UserContext(), address)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(updatedAddress)
@@ -124,8 +169,15 @@ func (a addressHandler) Update(c *fiber.Ctx) error {
 // @Summary Create a new address
 // @Description Create a new address entry
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param address body request.AddressCreateRequest true "Address creation payload"
 // @Success 201 {object} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses [post]
 func (a addressHandler) Create(c *fiber.Ctx) error {
 	var address request.AddressCreateRequest
@@ -133,9 +185,9 @@ func (a addressHandler) Create(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Cannot parse JSON")
 	}
 
-	response, err := a.addressService.Create(c.Context(), address)
+	response, err := a.addressService.Create(c.UserContext(), address)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(response)
@@ -145,9 +197,16 @@ func (a addressHandler) Create(c *fiber.Ctx) error {
 // @Summary Patch an address
 // @Description Patch (partial update) an address by its ID
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "Address ID"
 // @Param address body request.AddressPatchRequest true "Address patch payload"
 // @Success 200 {object} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v1/addresses/{id} [patch]
 func (a addressHandler) Patch(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -161,9 +220,9 @@ func (a addressHandler) Patch(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	updatedAddress, err := a.addressService.Patch(c.Context(), convertedId, patchRequest)
+	updatedAddress, err := a.addressService.Patch(c.UserContext(), convertedId, patchRequest)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(updatedAddress)
@@ -173,26 +232,25 @@ func (a addressHandler) Patch(c *fiber.Ctx) error {
 // @Summary Get all addresses
 // @Description Get all addresses with pagination
 // @Tags addresses
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param page query int false "Page number"
 // @Param size query int false "Page size"
 // @Success 200 {array} response.AddressResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /api/v2/addresses [get]
 func (a addressHandler) GetAllV2(c *fiber.Ctx) error {
-	page := c.Query("page", "0")
-	size := c.Query("size", "10")
-
-	pageNumber, err := strconv.Atoi(page)
-	if err != nil {
-		pageNumber = 0
-	}
-	pageSize, err := strconv.Atoi(size)
-	if err != nil {
-		pageSize = 10
+	var query request.AddressQueryRequest
+	if err := c.QueryParser(&query); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
 	}
 
-	addresses, err := a.addressService.GetAll(c.Context(), pageNumber, pageSize)
+	addresses, err := a.addressService.GetAll(c.UserContext(), query)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Unable to retrieve addresses")
+		return err
 	}
 
 	return c.JSON(addresses)
@@ -3,13 +3,17 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sefikcan/address-api/internal/address/dto/request"
 	"github.com/sefikcan/address-api/internal/address/dto/response"
+	"github.com/sefikcan/address-api/internal/address/service"
 	"github.com/sefikcan/address-api/internal/address/service/mocks"
 	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -45,7 +49,7 @@ func TestAddressHandler_GetAll(t *testing.T) {
 		TotalItems: int64(len(mockAddresses)),
 	}
 
-	mockService.On("GetAll", mock.Anything, 0, 10).Return(pageableAddresses, nil)
+	mockService.On("GetAll", mock.Anything, request.AddressQueryRequest{}).Return(pageableAddresses, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/addresses", nil)
 	resp, _ := app.Test(req)
@@ -55,6 +59,38 @@ func TestAddressHandler_GetAll(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestAddressHandler_GetAll_WithQuery(t *testing.T) {
+	mockService := mocks.NewAddressService(t)
+	handler := NewAddressHandler(mockService)
+
+	app := fiber.New()
+	app.Get("/api/v1/addresses", middleware.QueryValidator(&request.AddressQueryRequest{}), handler.GetAll)
+
+	expectedQuery := request.AddressQueryRequest{
+		Page:    2,
+		Size:    20,
+		City:    "Istanbul",
+		Search:  "bagdat",
+		Sort:    "city:asc,createdAt:desc",
+		UserId:  "1",
+		Country: "Turkey",
+	}
+	mockService.On("GetAll", mock.Anything, expectedQuery).Return(&common.Pageable[response.AddressResponse]{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/addresses?page=2&size=20&city=Istanbul&country=Turkey&userId=1&q=bagdat&sort=city:asc,createdAt:desc", nil)
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// sort field outside of the whitelist is rejected before reaching the service
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/addresses?sort=full_address;drop", nil)
+	resp, _ = app.Test(req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestAddressHandler_Create(t *testing.T) {
 	mockService := mocks.NewAddressService(t)
 	handler := NewAddressHandler(mockService)
@@ -201,3 +237,44 @@ func TestAddressHandler_Patch(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	mockService.AssertExpectations(t)
 }
+
+func TestAddressHandler_ErrorsAreMappedWithoutInternals(t *testing.T) {
+	mockService := mocks.NewAddressService(t)
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	handler := NewAddressHandler(mockService)
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(mockLogger)})
+	app.Get("/api/v1/addresses/:id", handler.GetById)
+	app.Delete("/api/v1/addresses/:id", handler.Delete)
+
+	mockService.On("GetById", mock.Anything, 1).Return(nil, service.ErrAddressNotFound)
+	mockService.On("GetById", mock.Anything, 2).Return(nil, service.ErrForbidden)
+	mockService.On("Delete", mock.Anything, 3).Return(common.DbError(gorm.ErrRecordNotFound, "addressRepository.Delete"))
+	mockService.On("Delete", mock.Anything, 4).Return(errors.New("addressRepository.Delete.DbError: pq: relation does not exist"))
+
+	tests := []struct {
+		method     string
+		id         int
+		wantStatus int
+		wantError  string
+	}{
+		{method: http.MethodGet, id: 1, wantStatus: http.StatusNotFound, wantError: "address not found"},
+		{method: http.MethodGet, id: 2, wantStatus: http.StatusForbidden, wantError: "operation is not allowed for the authenticated user"},
+		{method: http.MethodDelete, id: 3, wantStatus: http.StatusNotFound, wantError: "record not found"},
+		{method: http.MethodDelete, id: 4, wantStatus: http.StatusInternalServerError, wantError: "An unexpected error occurred"},
+	}
+
+	for _, tt := range tests {
+		resp, _ := app.Test(httptest.NewRequest(tt.method, "/api/v1/addresses/"+strconv.Itoa(tt.id), nil))
+
+		var problem common.Problem
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+
+		assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		assert.Equal(t, common.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, tt.wantStatus, problem.Status)
+		assert.Equal(t, tt.wantError, problem.Detail)
+		assert.Equal(t, "/api/v1/addresses/"+strconv.Itoa(tt.id), problem.Instance)
+	}
+}
@@ -3,26 +3,20 @@ package handlers
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sefikcan/address-api/internal/address/dto/request"
-	idempotency "github.com/sefikcan/address-api/internal/idempotency/service"
+	"github.com/sefikcan/address-api/internal/auth"
 	"github.com/sefikcan/address-api/internal/middleware"
 	"github.com/sefikcan/address-api/internal/ratelimiter"
 	"github.com/sefikcan/address-api/pkg/logger"
 	"github.com/sefikcan/address-api/pkg/util"
 )
 
-func MapAddressRotes(app *fiber.App, addressHandler AddressHandler, logger logger.Logger, manager *middleware.Manager, idempotencyService idempotency.IdempotencyService) {
+// MapAddressRotes mounts the tenantScoped middlewares after the authentication bound the tenant
+func MapAddressRotes(app *fiber.App, addressHandler AddressHandler, logger logger.Logger, manager *middleware.Manager, apiKeyAuthenticator auth.ApiKeyAuthenticator, tenantScoped []fiber.Handler) {
 	v1 := app.Group("/api/v1/addresses")
 
 	rtb := ratelimiter.NewEPDistributedTokenBucket("localhost:6379")
 
-	v1.Get("/", manager.IdempotencyMiddleware(idempotencyService), addressHandler.GetAll)
-	v1.Post("/", manager.EPDistributedRateLimitMiddleware(rtb, "create-address"), middleware.Validator(&request.AddressCreateRequest{}), addressHandler.Create)
-	v1.Delete("/:id", addressHandler.Delete)
-	v1.Get("/:id", addressHandler.GetById)
-	v1.Put("/:id", middleware.Validator(&request.AddressUpdateRequest{}), addressHandler.Update)
-	v1.Patch("/:id", middleware.Validator(&request.AddressPatchRequest{}), addressHandler.Patch)
-
-	// health endpoint
+	// health endpoint, registered before /:id so it is public and not shadowed by it
 	health := v1.Group("/health")
 	// Health check endpoint
 	health.Get("/", func(c *fiber.Ctx) error {
@@ -30,7 +24,21 @@ func MapAddressRotes(app *fiber.App, addressHandler AddressHandler, logger logge
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "OK"})
 	})
 
+	// every address route requires a user token or an api key, the service scopes data to that principal
+	authenticated := append([]fiber.Handler{manager.JwtOrApiKeyAuthentication(apiKeyAuthenticator)}, tenantScoped...)
+	addresses := app.Group("/api/v1/addresses", authenticated...)
+	canRead := manager.RequireScopes(auth.ScopeRead)
+	canWrite := manager.RequireScopes(auth.ScopeWrite)
+	canDelete := manager.RequireScopes(auth.ScopeDelete)
+
+	addresses.Get("/", canRead, middleware.QueryValidator(&request.AddressQueryRequest{}), addressHandler.GetAll)
+	addresses.Post("/", canWrite, manager.EPDistributedRateLimitMiddleware(rtb, "create-address"), middleware.Validator(&request.AddressCreateRequest{}), addressHandler.Create)
+	addresses.Delete("/:id", canDelete, addressHandler.Delete)
+	addresses.Get("/:id", canRead, addressHandler.GetById)
+	addresses.Put("/:id", canWrite, middleware.Validator(&request.AddressUpdateRequest{}), addressHandler.Update)
+	addresses.Patch("/:id", canWrite, middleware.Validator(&request.AddressPatchRequest{}), addressHandler.Patch)
+
 	// Version 2 routes sample
-	v2 := app.Group("/api/v2")
-	v2.Get("/", addressHandler.GetAllV2)
+	v2 := app.Group("/api/v2", authenticated...)
+	v2.Get("/", canRead, middleware.QueryValidator(&request.AddressQueryRequest{}), addressHandler.GetAllV2)
 }
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/middleware"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const routesTestJwtSecret = "test_secret"
+
+func TestMapAddressRotes_TenantScopedRunAfterAuthentication(t *testing.T) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Info", mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warn", mock.Anything).Maybe()
+
+	manager := middleware.NewMiddlewareManager(&config.Config{
+		Auth:   config.AuthenticationConfig{JwtSecret: routesTestJwtSecret},
+		Tenant: config.TenantConfig{Default: "default"},
+	}, mockLogger)
+
+	// stands in for the idempotency and rate limit middlewares, they build their keys from the tenant of the context
+	var scopedTenant string
+	var scopedCalls int
+	scoped := func(c *fiber.Ctx) error {
+		scopedCalls++
+		scopedTenant, _ = tenant.FromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	app := fiber.New()
+	app.Use(manager.Tenant())
+	MapAddressRotes(app, NewAddressHandler(mocks.NewAddressService(t)), mockLogger, manager, nil, []fiber.Handler{scoped})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   "42",
+		"tenant_id": "brand-a",
+		"scope":     "address:read",
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(routesTestJwtSecret))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantCalls  int
+		wantTenant string
+	}{
+		{name: "claim without header", wantStatus: http.StatusOK, wantCalls: 1, wantTenant: "brand-a"},
+		{name: "claim and matching header", header: "brand-a", wantStatus: http.StatusOK, wantCalls: 1, wantTenant: "brand-a"},
+		{name: "header disagrees with claim", header: "brand-b", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopedTenant, scopedCalls = "", 0
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/addresses/1", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.header != "" {
+				req.Header.Set(tenant.HeaderTenantId, tt.header)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantCalls, scopedCalls)
+			assert.Equal(t, tt.wantTenant, scopedTenant)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package mapping
+
+import (
+	"github.com/sefikcan/address-api/internal/address/entity"
+	events "github.com/sefikcan/address-events"
+)
+
+// MapEvent maps a change of an address, before is nil for creates and after is nil for deletes
+func MapEvent(eventType string, before, after *entity.Address) events.AddressEvent {
+	current := after
+	if current == nil {
+		current = before
+	}
+
+	// a delete is the last change of the address, it follows the version of the deleted row
+	version := current.Version
+	if after == nil {
+		version++
+	}
+
+	return events.AddressEvent{
+		EventType: eventType,
+		AddressId: current.Id,
+		UserId:    current.UserId,
+		TenantId:  current.TenantId,
+		Version:   version,
+		Before:    MapSnapshot(before),
+		After:     MapSnapshot(after),
+	}
+}
+
+func MapSnapshot(a *entity.Address) *events.AddressSnapshot {
+	if a == nil {
+		return nil
+	}
+
+	return &events.AddressSnapshot{
+		City:         a.City,
+		Country:      a.Country,
+		FullAddress:  a.FullAddress,
+		UserId:       a.UserId,
+		AddressLine1: a.AddressLine1,
+		AddressLine2: a.AddressLine2,
+		HouseNumber:  a.HouseNumber,
+		District:     a.District,
+		Region:       a.Region,
+		PostalCode:   a.PostalCode,
+		CountryCode:  a.CountryCode,
+		Latitude:     a.Latitude,
+		Longitude:    a.Longitude,
+	}
+}
@@ -3,13 +3,114 @@ package mapping
 import (
 	"github.com/sefikcan/address-api/internal/address/dto/request"
 	"github.com/sefikcan/address-api/internal/address/entity"
+	"strings"
+	"time"
 )
 
 func CreateMapEntity(address *request.AddressCreateRequest) entity.Address {
-	return entity.Address{
-		City:        address.City,
-		Country:     address.Country,
-		FullAddress: address.FullAddress,
-		UserId:      address.UserId,
+	mapped := entity.Address{
+		City:         address.City,
+		Country:      address.Country,
+		FullAddress:  address.FullAddress,
+		UserId:       address.UserId,
+		AddressLine1: address.AddressLine1,
+		AddressLine2: address.AddressLine2,
+		HouseNumber:  address.HouseNumber,
+		District:     address.District,
+		Region:       address.Region,
+		PostalCode:   address.PostalCode,
+		CountryCode:  strings.ToUpper(address.CountryCode),
+		Latitude:     address.Latitude,
+		Longitude:    address.Longitude,
 	}
+
+	// clients sending only structured components still get a printable full address
+	if mapped.FullAddress == "" {
+		mapped.FullAddress = mapped.ComposeFullAddress()
+	}
+
+	return mapped
+}
+
+// UpdateMapEntity applies the non-empty fields of the update request to the current address
+func UpdateMapEntity(address *request.AddressUpdateRequest, current *entity.Address) {
+	setIfNotEmpty(&current.City, address.City)
+	setIfNotEmpty(&current.Country, address.Country)
+	setIfNotEmpty(&current.FullAddress, address.FullAddress)
+	setIfNotEmpty(&current.AddressLine1, address.AddressLine1)
+	setIfNotEmpty(&current.AddressLine2, address.AddressLine2)
+	setIfNotEmpty(&current.HouseNumber, address.HouseNumber)
+	setIfNotEmpty(&current.District, address.District)
+	setIfNotEmpty(&current.Region, address.Region)
+	setIfNotEmpty(&current.PostalCode, address.PostalCode)
+	setIfNotEmpty(&current.CountryCode, strings.ToUpper(address.CountryCode))
+
+	if address.Latitude != nil && address.Longitude != nil {
+		current.Latitude = address.Latitude
+		current.Longitude = address.Longitude
+	}
+
+	// like a create, an update sending only structured components gets a matching full address and one
+	// sending only the free-text address replaces the street, the previous lines would not match it anymore
+	switch {
+	case address.FullAddress == "":
+		current.FullAddress = current.ComposeFullAddress()
+	case address.AddressLine1 == "":
+		current.AddressLine1 = ""
+		current.AddressLine2 = ""
+		current.HouseNumber = ""
+	}
+}
+
+func setIfNotEmpty(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+// QueryMapFilter converts the validated listing query into a repository filter
+func QueryMapFilter(query *request.AddressQueryRequest) entity.AddressFilter {
+	filter := entity.AddressFilter{
+		UserId:      query.UserId,
+		City:        query.City,
+		Country:     query.Country,
+		CreatedFrom: parseTime(query.CreatedFrom),
+		CreatedTo:   parseTime(query.CreatedTo),
+		UpdatedFrom: parseTime(query.UpdatedFrom),
+		UpdatedTo:   parseTime(query.UpdatedTo),
+		Search:      strings.TrimSpace(query.Search),
+		Page:        query.Page,
+		PageSize:    query.Size,
+		WithCount:   query.WithCount,
+	}
+
+	if filter.Page < 1 {
+		filter.Page = request.DefaultPage
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = request.DefaultPageSize
+	}
+
+	for _, part := range strings.Split(query.Sort, ",") {
+		field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if field == "" {
+			continue
+		}
+		filter.Sort = append(filter.Sort, entity.SortField{Field: field, Desc: direction == "desc"})
+	}
+
+	return filter
+}
+
+func parseTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+
+	return &parsed
 }
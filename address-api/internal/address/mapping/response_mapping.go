@@ -7,10 +7,28 @@ import (
 
 func MapDto(a entity.Address) *response.AddressResponse {
 	return &response.AddressResponse{
-		Id:          a.Id,
-		City:        a.City,
-		Country:     a.Country,
-		FullAddress: a.FullAddress,
-		UserId:      a.UserId,
+		Id:           a.Id,
+		City:         a.City,
+		Country:      a.Country,
+		FullAddress:  a.FullAddress,
+		UserId:       a.UserId,
+		AddressLine1: a.AddressLine1,
+		AddressLine2: a.AddressLine2,
+		HouseNumber:  a.HouseNumber,
+		District:     a.District,
+		Region:       a.Region,
+		PostalCode:   a.PostalCode,
+		CountryCode:  a.CountryCode,
+		Latitude:     a.Latitude,
+		Longitude:    a.Longitude,
 	}
 }
+
+func MapDtos(addresses []entity.Address) []response.AddressResponse {
+	mapped := make([]response.AddressResponse, 0, len(addresses))
+	for _, a := range addresses {
+		mapped = append(mapped, *MapDto(a))
+	}
+
+	return mapped
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sefikcan/address-api/internal/address/entity"
 	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
 	"gorm.io/gorm"
 )
 
@@ -12,7 +14,9 @@ type AddressRepository interface {
 	Create(ctx context.Context, address entity.Address) (entity.Address, error)
 	Update(ctx context.Context, address entity.Address) (entity.Address, error)
 	GetById(ctx context.Context, id int) (entity.Address, error)
-	GetAll(ctx context.Context, page, pageSize int) (*common.Pageable[entity.Address], error)
+	GetAll(ctx context.Context, filter entity.AddressFilter) (*common.Pageable[entity.Address], error)
+	GetAllByCursor(ctx context.Context, filter entity.AddressFilter) ([]entity.Address, bool, error)
+	Count(ctx context.Context, filter entity.AddressFilter) (int64, error)
 	Delete(ctx context.Context, id int) error
 }
 
@@ -21,70 +25,168 @@ type addressRepository struct {
 }
 
 func (a addressRepository) Update(ctx context.Context, address entity.Address) (entity.Address, error) {
-	if result := a.db.WithContext(ctx).Save(&address); result.Error != nil {
-		return entity.Address{}, errors.Wrap(result.Error, "addressRepository.Update.DbError")
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return entity.Address{}, errors.Wrap(err, "addressRepository.Update.TenantError")
+	}
+
+	// Save would insert the row when the update matches nothing, so an explicit update is used
+	address.TenantId, _ = tenant.FromContext(ctx)
+	result := db.Model(&entity.Address{}).Where(`id = ?`, address.Id).Select("*").Omit("created_at", "version").Updates(&address)
+	if result.Error != nil {
+		return entity.Address{}, common.DbError(result.Error, "addressRepository.Update")
+	}
+	if result.RowsAffected == 0 {
+		return entity.Address{}, common.DbError(gorm.ErrRecordNotFound, "addressRepository.Update")
+	}
+
+	// the row stays locked until the transaction commits, so concurrent updates get consecutive versions
+	err = db.Model(&entity.Address{}).Where(`id = ?`, address.Id).UpdateColumn("version", gorm.Expr("version + 1")).Error
+	if err != nil {
+		return entity.Address{}, common.DbError(err, "addressRepository.Update.Version")
+	}
+	if err := db.Model(&entity.Address{}).Where(`id = ?`, address.Id).Pluck("version", &address.Version).Error; err != nil {
+		return entity.Address{}, common.DbError(err, "addressRepository.Update.Version")
 	}
 
 	return address, nil
 }
 
-func (a addressRepository) GetAll(ctx context.Context, page, pageSize int) (*common.Pageable[entity.Address], error) {
+func (a addressRepository) GetAll(ctx context.Context, filter entity.AddressFilter) (*common.Pageable[entity.Address], error) {
 	var addresses []entity.Address
 	var totalItems int64
 
-	query := a.db.Model(&entity.Address{})
-
-	// TODO: Add filter for query
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "addressRepository.GetAll.TenantError")
+	}
 
-	if err := query.WithContext(ctx).Count(&totalItems).Error; err != nil {
-		return nil, errors.Wrap(err, "addressRepository.GetAll.CountDbError")
+	if err := db.Model(&entity.Address{}).Scopes(applyFilter(filter)).Count(&totalItems).Error; err != nil {
+		return nil, common.DbError(err, "addressRepository.GetAll.Count")
 	}
 
-	offset := (page - 1) * pageSize
+	offset := (filter.Page - 1) * filter.PageSize
 
-	if err := query.Limit(pageSize).Offset(offset).WithContext(ctx).Find(&addresses).Error; err != nil {
-		return nil, errors.Wrap(err, "addressRepository.GetAll.DbError")
+	query := db.Model(&entity.Address{}).Scopes(applyFilter(filter), applySort(filter.Sort))
+	if err := query.Limit(filter.PageSize).Offset(offset).Find(&addresses).Error; err != nil {
+		return nil, common.DbError(err, "addressRepository.GetAll")
 	}
 
-	totalPages := int((totalItems + int64(pageSize) - 1) / int64(pageSize))
+	totalPages := int((totalItems + int64(filter.PageSize) - 1) / int64(filter.PageSize))
 
 	pageable := &common.Pageable[entity.Address]{
 		Items:       addresses,
 		TotalItems:  totalItems,
 		TotalPages:  totalPages,
-		CurrentPage: page,
-		PageSize:    pageSize,
+		CurrentPage: filter.Page,
+		PageSize:    filter.PageSize,
 	}
 
 	return pageable, nil
 }
 
+// GetAllByCursor reads one keyset page, the returned flag reports whether more rows exist in the read direction
+func (a addressRepository) GetAllByCursor(ctx context.Context, filter entity.AddressFilter) ([]entity.Address, bool, error) {
+	var addresses []entity.Address
+
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "addressRepository.GetAllByCursor.TenantError")
+	}
+
+	keyset, err := applyKeyset(filter)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "addressRepository.GetAllByCursor.CursorError")
+	}
+
+	query := db.Model(&entity.Address{}).Scopes(applyFilter(filter), keyset)
+	if err := query.Limit(filter.PageSize + 1).Find(&addresses).Error; err != nil {
+		return nil, false, common.DbError(err, "addressRepository.GetAllByCursor")
+	}
+
+	hasMore := len(addresses) > filter.PageSize
+	if hasMore {
+		addresses = addresses[:filter.PageSize]
+	}
+
+	// pages before the cursor are read in reverse order
+	if filter.Cursor != nil && filter.Cursor.Before {
+		for i, j := 0, len(addresses)-1; i < j; i, j = i+1, j-1 {
+			addresses[i], addresses[j] = addresses[j], addresses[i]
+		}
+	}
+
+	return addresses, hasMore, nil
+}
+
+func (a addressRepository) Count(ctx context.Context, filter entity.AddressFilter) (int64, error) {
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "addressRepository.Count.TenantError")
+	}
+
+	var totalItems int64
+	if err := db.Model(&entity.Address{}).Scopes(applyFilter(filter)).Count(&totalItems).Error; err != nil {
+		return 0, common.DbError(err, "addressRepository.Count")
+	}
+
+	return totalItems, nil
+}
+
 func (a addressRepository) Create(ctx context.Context, address entity.Address) (entity.Address, error) {
-	if result := a.db.WithContext(ctx).Create(&address); result.Error != nil {
-		return entity.Address{}, errors.Wrap(result.Error, "addressRepository.Create.DbError")
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return entity.Address{}, errors.Wrap(tenant.ErrMissingTenant, "addressRepository.Create.TenantError")
+	}
+
+	address.TenantId = tenantId
+	address.Version = 1
+	if result := postgres.DB(ctx, a.db).Create(&address); result.Error != nil {
+		return entity.Address{}, common.DbError(result.Error, "addressRepository.Create")
 	}
 
 	return address, nil
 }
 
 func (a addressRepository) GetById(ctx context.Context, id int) (entity.Address, error) {
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return entity.Address{}, errors.Wrap(err, "addressRepository.GetById.TenantError")
+	}
+
 	currentAddress := entity.Address{}
-	err := a.db.WithContext(ctx).Where(`id = ?`, id).First(&currentAddress).Error
+	err = db.Where(`id = ?`, id).First(&currentAddress).Error
 	if err != nil {
-		return entity.Address{}, errors.Wrap(err, "addressRepository.GetById.DbError")
+		return entity.Address{}, common.DbError(err, "addressRepository.GetById")
 	}
 
 	return currentAddress, err
 }
 
 func (a addressRepository) Delete(ctx context.Context, id int) error {
-	if result := a.db.WithContext(ctx).Delete(&entity.Address{Id: id}); result.Error != nil {
-		return errors.Wrap(result.Error, "addressRepository.Delete.DbError")
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return errors.Wrap(err, "addressRepository.Delete.TenantError")
+	}
+
+	if result := db.Where(`id = ?`, id).Delete(&entity.Address{}); result.Error != nil {
+		return common.DbError(result.Error, "addressRepository.Delete")
 	}
 
 	return nil
 }
 
+// tenantDB returns a session limited to the tenant of ctx, queries without a tenant are refused
+func (a addressRepository) tenantDB(ctx context.Context) (*gorm.DB, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	// a new session keeps the tenant condition while the returned db is reused for several queries
+	return postgres.DB(ctx, a.db).Where(`tenant_id = ?`, tenantId).Session(&gorm.Session{}), nil
+}
+
 func NewAddressRepository(db *gorm.DB) AddressRepository {
 	return &addressRepository{
 		db: db,
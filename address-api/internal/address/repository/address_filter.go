@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/sefikcan/address-api/internal/address/entity"
+	"github.com/sefikcan/address-api/internal/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// sortColumns maps the public sort fields to table columns, anything else is ignored
+var sortColumns = map[string]string{
+	"id":         "id",
+	"city":       "city",
+	"country":    "country",
+	"userId":     "user_id",
+	"postalCode": "postal_code",
+	"createdAt":  "created_at",
+	"updatedAt":  "updated_at",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func applyFilter(filter entity.AddressFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.UserId != "" {
+			db = db.Where("user_id = ?", filter.UserId)
+		}
+		if filter.City != "" {
+			db = db.Where("LOWER(city) = ?", strings.ToLower(filter.City))
+		}
+		if filter.Country != "" {
+			db = db.Where("LOWER(country) = ?", strings.ToLower(filter.Country))
+		}
+		if filter.CreatedFrom != nil {
+			db = db.Where("created_at >= ?", *filter.CreatedFrom)
+		}
+		if filter.CreatedTo != nil {
+			db = db.Where("created_at <= ?", *filter.CreatedTo)
+		}
+		if filter.UpdatedFrom != nil {
+			db = db.Where("updated_at >= ?", *filter.UpdatedFrom)
+		}
+		if filter.UpdatedTo != nil {
+			db = db.Where("updated_at <= ?", *filter.UpdatedTo)
+		}
+		if filter.Search != "" {
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Search)) + "%"
+			db = db.Where(`LOWER(full_address) LIKE ? ESCAPE '\'`, pattern)
+		}
+
+		return db
+	}
+}
+
+func applySort(sort []entity.SortField) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		hasId := false
+		for _, s := range sort {
+			column, ok := sortColumns[s.Field]
+			if !ok {
+				continue
+			}
+			if column == "id" {
+				hasId = true
+			}
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: s.Desc})
+		}
+
+		// id as tie-breaker keeps the page boundaries stable
+		if !hasId {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+		}
+
+		return db
+	}
+}
+
+// applyKeyset orders by the single sort field plus id and seeks past the cursor row,
+// id follows the direction of the sort field so (field, id) is a strict total order
+func applyKeyset(filter entity.AddressFilter) (func(db *gorm.DB) *gorm.DB, error) {
+	sort := entity.SortField{Field: "id"}
+	if len(filter.Sort) > 0 {
+		sort = filter.Sort[0]
+	}
+
+	column, ok := sortColumns[sort.Field]
+	if !ok || !entity.CursorSortFields[sort.Field] {
+		return nil, common.ErrUnsupportedCursorSort
+	}
+
+	desc := sort.Desc
+	if filter.Cursor != nil && filter.Cursor.Before {
+		desc = !desc
+	}
+
+	operator := ">"
+	if desc {
+		operator = "<"
+	}
+
+	var value interface{}
+	if filter.Cursor != nil && column != "id" {
+		parsed, err := time.Parse(time.RFC3339Nano, filter.Cursor.Value)
+		if err != nil {
+			return nil, common.ErrInvalidCursor
+		}
+		value = parsed
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Cursor != nil {
+			if column == "id" {
+				db = db.Where(fmt.Sprintf("id %s ?", operator), filter.Cursor.Id)
+			} else {
+				db = db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, operator, column, operator),
+					value, value, filter.Cursor.Id)
+			}
+		}
+
+		if column != "id" {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc})
+		}
+
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc})
+	}, nil
+}
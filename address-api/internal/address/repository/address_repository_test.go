@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"github.com/sefikcan/address-api/internal/address/entity"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/tenant"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -10,6 +12,10 @@ import (
 	"testing"
 )
 
+func tenantContext(tenantId string) context.Context {
+	return tenant.WithTenant(context.Background(), tenantId)
+}
+
 func SetupTestDB() (*gorm.DB, func()) {
 	// Create a new in-memory database
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
@@ -44,7 +50,7 @@ func TestAddressRepository_Create(t *testing.T) {
 		FullAddress: "test test",
 		UserId:      "1",
 	}
-	result, err := repo.Create(context.Background(), address)
+	result, err := repo.Create(tenantContext("brand-a"), address)
 
 	assert.NoError(t, err)
 	assert.Equal(t, address.Id, result.Id)
@@ -66,9 +72,9 @@ func TestAddressRepository_GetById(t *testing.T) {
 		FullAddress: "test test",
 		UserId:      "1",
 	}
-	_, _ = repo.Create(context.Background(), address)
+	_, _ = repo.Create(tenantContext("brand-a"), address)
 
-	result, err := repo.GetById(context.Background(), 1)
+	result, err := repo.GetById(tenantContext("brand-a"), 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, address.Id, result.Id)
@@ -90,9 +96,9 @@ func TestAddressRepository_Delete(t *testing.T) {
 		FullAddress: "test test",
 		UserId:      "1",
 	}
-	_, _ = repo.Create(context.Background(), address)
+	_, _ = repo.Create(tenantContext("brand-a"), address)
 
-	err := repo.Delete(context.Background(), 1)
+	err := repo.Delete(tenantContext("brand-a"), 1)
 
 	assert.NoError(t, err)
 }
@@ -121,10 +127,10 @@ func TestAddressRepository_GetAll(t *testing.T) {
 		},
 	}
 	for _, addr := range addresses {
-		_, _ = repo.Create(context.Background(), addr)
+		_, _ = repo.Create(tenantContext("brand-a"), addr)
 	}
 
-	pageable, err := repo.GetAll(context.Background(), 1, 10)
+	pageable, err := repo.GetAll(tenantContext("brand-a"), entity.AddressFilter{Page: 1, PageSize: 10})
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(len(addresses)), pageable.TotalItems)
@@ -145,11 +151,11 @@ func TestAddressRepository_Update(t *testing.T) {
 		FullAddress: "test test",
 		UserId:      "1",
 	}
-	_, _ = repo.Create(context.Background(), address)
+	_, _ = repo.Create(tenantContext("brand-a"), address)
 
 	// Update the address
 	address.City = "Updated St 2"
-	result, err := repo.Update(context.Background(), address)
+	result, err := repo.Update(tenantContext("brand-a"), address)
 
 	assert.NoError(t, err)
 	assert.Equal(t, address.Id, result.Id)
@@ -158,3 +164,154 @@ func TestAddressRepository_Update(t *testing.T) {
 	assert.Equal(t, address.UserId, result.UserId)
 	assert.Equal(t, address.FullAddress, result.FullAddress)
 }
+
+func TestAddressRepository_Update_IncrementsVersion(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewAddressRepository(db)
+
+	created, err := repo.Create(tenantContext("brand-a"), entity.Address{Id: 1, Country: "Turkey", City: "Istanbul", FullAddress: "Bagdat Cad. 1", UserId: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), created.Version)
+
+	// the version of the caller is ignored, the stored row decides the next version
+	created.City = "Ankara"
+	created.Version = 10
+	updated, err := repo.Update(tenantContext("brand-a"), created)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+
+	updated, err = repo.Update(tenantContext("brand-a"), updated)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), updated.Version)
+
+	stored, err := repo.GetById(tenantContext("brand-a"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stored.Version)
+}
+
+func TestAddressRepository_GetAll_FilterAndSort(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewAddressRepository(db)
+
+	addresses := []entity.Address{
+		{Id: 1, Country: "Turkey", City: "Istanbul", FullAddress: "Bagdat Cad. 100%", UserId: "1"},
+		{Id: 2, Country: "Turkey", City: "Ankara", FullAddress: "Ataturk Blv. 5", UserId: "1"},
+		{Id: 3, Country: "Germany", City: "Berlin", FullAddress: "Unter den Linden 1", UserId: "2"},
+		{Id: 4, Country: "turkey", City: "Izmir", FullAddress: "Kordon Boyu 7", UserId: "1"},
+	}
+	for _, addr := range addresses {
+		_, _ = repo.Create(tenantContext("brand-a"), addr)
+	}
+
+	pageable, err := repo.GetAll(tenantContext("brand-a"), entity.AddressFilter{
+		UserId:   "1",
+		Country:  "TURKEY",
+		Sort:     []entity.SortField{{Field: "city", Desc: true}},
+		Page:     1,
+		PageSize: 2,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), pageable.TotalItems)
+	assert.Equal(t, 2, pageable.TotalPages)
+	assert.Len(t, pageable.Items, 2)
+	assert.Equal(t, "Izmir", pageable.Items[0].City)
+	assert.Equal(t, "Istanbul", pageable.Items[1].City)
+
+	// LIKE wildcards in the search term are matched literally
+	pageable, err = repo.GetAll(tenantContext("brand-a"), entity.AddressFilter{Search: "100%", Page: 1, PageSize: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pageable.TotalItems)
+	assert.Equal(t, 1, pageable.Items[0].Id)
+}
+
+func TestAddressRepository_GetAllByCursor(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewAddressRepository(db)
+	for i := 1; i <= 5; i++ {
+		_, _ = repo.Create(tenantContext("brand-a"), entity.Address{Id: i, Country: "Turkey", City: "Istanbul", FullAddress: "test test", UserId: "1"})
+	}
+
+	sort := []entity.SortField{{Field: "id", Desc: true}}
+
+	firstPage, hasMore, err := repo.GetAllByCursor(tenantContext("brand-a"), entity.AddressFilter{Sort: sort, PageSize: 2})
+	assert.NoError(t, err)
+	assert.True(t, hasMore)
+	assert.Equal(t, []int{5, 4}, addressIds(firstPage))
+
+	secondPage, hasMore, err := repo.GetAllByCursor(tenantContext("brand-a"), entity.AddressFilter{
+		Sort:     sort,
+		PageSize: 2,
+		Cursor:   &common.Cursor{Field: "id", Desc: true, Id: 4},
+	})
+	assert.NoError(t, err)
+	assert.True(t, hasMore)
+	assert.Equal(t, []int{3, 2}, addressIds(secondPage))
+
+	previousPage, hasMore, err := repo.GetAllByCursor(tenantContext("brand-a"), entity.AddressFilter{
+		Sort:     sort,
+		PageSize: 2,
+		Cursor:   &common.Cursor{Field: "id", Desc: true, Id: 3, Before: true},
+	})
+	assert.NoError(t, err)
+	assert.False(t, hasMore)
+	assert.Equal(t, []int{5, 4}, addressIds(previousPage))
+
+	_, _, err = repo.GetAllByCursor(tenantContext("brand-a"), entity.AddressFilter{
+		Sort:     []entity.SortField{{Field: "city"}},
+		PageSize: 2,
+	})
+	assert.ErrorIs(t, err, common.ErrUnsupportedCursorSort)
+}
+
+func addressIds(addresses []entity.Address) []int {
+	var ids []int
+	for _, a := range addresses {
+		ids = append(ids, a.Id)
+	}
+	return ids
+}
+
+func TestAddressRepository_TenantIsolation(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewAddressRepository(db)
+	brandA, brandB := tenantContext("brand-a"), tenantContext("brand-b")
+
+	created, err := repo.Create(brandA, entity.Address{Country: "Turkey", City: "Istanbul", FullAddress: "test test", UserId: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-a", created.TenantId)
+
+	_, err = repo.GetById(brandB, created.Id)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	page, err := repo.GetAll(brandB, entity.AddressFilter{Page: 1, PageSize: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+	assert.Equal(t, int64(0), page.TotalItems)
+
+	count, err := repo.Count(brandB, entity.AddressFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	created.City = "Ankara"
+	_, err = repo.Update(brandB, created)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, repo.Delete(brandB, created.Id))
+
+	current, err := repo.GetById(brandA, created.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Istanbul", current.City)
+
+	_, err = repo.GetAll(context.Background(), entity.AddressFilter{Page: 1, PageSize: 10})
+	assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+}
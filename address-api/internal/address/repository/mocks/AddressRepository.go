@@ -17,6 +17,34 @@ type AddressRepository struct {
 	mock.Mock
 }
 
+// Count provides a mock function with given fields: ctx, filter
+func (_m *AddressRepository) Count(ctx context.Context, filter entity.AddressFilter) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Count")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) (int64, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AddressFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, address
 func (_m *AddressRepository) Create(ctx context.Context, address entity.Address) (entity.Address, error) {
 	ret := _m.Called(ctx, address)
@@ -63,9 +91,9 @@ func (_m *AddressRepository) Delete(ctx context.Context, id int) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: ctx, page, pageSize
-func (_m *AddressRepository) GetAll(ctx context.Context, page int, pageSize int) (*common.Pageable[entity.Address], error) {
-	ret := _m.Called(ctx, page, pageSize)
+// GetAll provides a mock function with given fields: ctx, filter
+func (_m *AddressRepository) GetAll(ctx context.Context, filter entity.AddressFilter) (*common.Pageable[entity.Address], error) {
+	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
@@ -73,19 +101,19 @@ func (_m *AddressRepository) GetAll(ctx context.Context, page int, pageSize int)
 
 	var r0 *common.Pageable[entity.Address]
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*common.Pageable[entity.Address], error)); ok {
-		return rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) (*common.Pageable[entity.Address], error)); ok {
+		return rf(ctx, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, int) *common.Pageable[entity.Address]); ok {
-		r0 = rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) *common.Pageable[entity.Address]); ok {
+		r0 = rf(ctx, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*common.Pageable[entity.Address])
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
-		r1 = rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AddressFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -93,6 +121,43 @@ func (_m *AddressRepository) GetAll(ctx context.Context, page int, pageSize int)
 	return r0, r1
 }
 
+// GetAllByCursor provides a mock function with given fields: ctx, filter
+func (_m *AddressRepository) GetAllByCursor(ctx context.Context, filter entity.AddressFilter) ([]entity.Address, bool, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllByCursor")
+	}
+
+	var r0 []entity.Address
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) ([]entity.Address, bool, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AddressFilter) []entity.Address); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Address)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.AddressFilter) bool); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, entity.AddressFilter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetById provides a mock function with given fields: ctx, id
 func (_m *AddressRepository) GetById(ctx context.Context, id int) (entity.Address, error) {
 	ret := _m.Called(ctx, id)
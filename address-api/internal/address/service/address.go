@@ -3,18 +3,32 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/sefikcan/address-api/internal/address/dto/request"
 	"github.com/sefikcan/address-api/internal/address/dto/response"
 	"github.com/sefikcan/address-api/internal/address/entity"
-	"github.com/sefikcan/address-api/internal/address/event"
 	"github.com/sefikcan/address-api/internal/address/mapping"
 	"github.com/sefikcan/address-api/internal/address/repository"
+	"github.com/sefikcan/address-api/internal/auth"
 	"github.com/sefikcan/address-api/internal/common"
-	"github.com/sefikcan/address-api/internal/constants"
+	outbox "github.com/sefikcan/address-api/internal/outbox/entity"
+	outboxRepository "github.com/sefikcan/address-api/internal/outbox/repository"
 	"github.com/sefikcan/address-api/pkg/config"
-	"github.com/sefikcan/address-api/pkg/kafka"
 	"github.com/sefikcan/address-api/pkg/logger"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"github.com/sefikcan/address-api/pkg/util"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+var (
+	// ErrAddressNotFound is also returned for addresses of other users so their existence is not leaked
+	ErrAddressNotFound = common.NewError(common.KindNotFound, "address not found")
+	ErrForbidden       = common.NewError(common.KindForbidden, "operation is not allowed for the authenticated user")
+	ErrInvalidPatch    = common.NewError(common.KindValidation, "invalid patch document")
 )
 
 type AddressService interface {
@@ -23,80 +37,60 @@ type AddressService interface {
 	Delete(ctx context.Context, id int) error
 	Patch(ctx context.Context, id int, patchRequest request.AddressPatchRequest) (*response.AddressResponse, error)
 	GetById(ctx context.Context, id int) (*response.AddressResponse, error)
-	GetAll(ctx context.Context, page, pageSize int) (*common.Pageable[response.AddressResponse], error)
+	GetAll(ctx context.Context, query request.AddressQueryRequest) (*common.Pageable[response.AddressResponse], error)
+	GetAllByCursor(ctx context.Context, query request.AddressQueryRequest) (*common.CursorPageable[response.AddressResponse], error)
 }
 
 type addressService struct {
 	cfg               *config.Config
 	addressRepository repository.AddressRepository
+	outboxRepository  outboxRepository.OutboxRepository
+	transactor        postgres.Transactor
 	logger            logger.Logger
-	messageBroker     kafka.Producer
+	cursorCodec       *common.CursorCodec
 }
 
 func (a addressService) Update(ctx context.Context, request request.AddressUpdateRequest) (*response.AddressResponse, error) {
-	currentAddress, err := a.addressRepository.GetById(ctx, request.Id)
-	if err != nil {
-		return nil, err
-	}
-
-	if request.City != "" {
-		currentAddress.City = request.City
-	}
-
-	if request.Country != "" {
-		currentAddress.Country = request.Country
-	}
-
-	if request.FullAddress != "" {
-		currentAddress.FullAddress = request.FullAddress
-	}
-
-	updatedAddress, err := a.addressRepository.Update(ctx, currentAddress)
+	currentAddress, err := a.getOwned(ctx, request.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	addressEvent := event.AddressEvent{
-		EventType:   "AddressUpdated",
-		AddressId:   updatedAddress.Id,
-		City:        updatedAddress.City,
-		Country:     updatedAddress.Country,
-		FullAddress: updatedAddress.FullAddress,
-		UserId:      updatedAddress.UserId,
-	}
+	before := currentAddress
+	mapping.UpdateMapEntity(&request, &currentAddress)
 
-	eventBytes, err := json.Marshal(addressEvent)
+	var updatedAddress entity.Address
+	err = a.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		updatedAddress, err = a.addressRepository.Update(ctx, currentAddress)
+		if err != nil {
+			return err
+		}
+
+		return a.addEvent(ctx, events.KafkaTopics.AddressUpdated, mapping.MapEvent(events.AddressUpdated, &before, &updatedAddress))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	eventMessage := string(eventBytes)
-
-	err = a.messageBroker.SendMessage(ctx, constants.KafkaTopics.AddressUpdated, eventMessage)
-
 	mappedResponse := mapping.MapDto(updatedAddress)
 
 	return mappedResponse, nil
 }
 
-func (a addressService) GetAll(ctx context.Context, page, pageSize int) (*common.Pageable[response.AddressResponse], error) {
-	addresses, err := a.addressRepository.GetAll(ctx, page, pageSize)
+func (a addressService) GetAll(ctx context.Context, query request.AddressQueryRequest) (*common.Pageable[response.AddressResponse], error) {
+	filter, err := scopeFilter(ctx, mapping.QueryMapFilter(&query))
 	if err != nil {
 		return nil, err
 	}
 
-	// Map entities to DTOs
-	var addressDTOs []response.AddressResponse
-	for _, addr := range addresses.Items {
-		addressDTOs = append(addressDTOs, response.AddressResponse{
-			Id:          addr.Id,
-			City:        addr.City,
-			Country:     addr.Country,
-			FullAddress: addr.FullAddress,
-			UserId:      addr.UserId,
-		})
+	addresses, err := a.addressRepository.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
+	// Map entities to DTOs
+	addressDTOs := mapping.MapDtos(addresses.Items)
+
 	return &common.Pageable[response.AddressResponse]{
 		Items:       addressDTOs,
 		TotalItems:  addresses.TotalItems,
@@ -106,65 +100,121 @@ func (a addressService) GetAll(ctx context.Context, page, pageSize int) (*common
 	}, nil
 }
 
-func (a addressService) Create(ctx context.Context, request request.AddressCreateRequest) (*response.AddressResponse, error) {
-	address := mapping.CreateMapEntity(&request)
-	resp, err := a.addressRepository.Create(ctx, address)
+func (a addressService) GetAllByCursor(ctx context.Context, query request.AddressQueryRequest) (*common.CursorPageable[response.AddressResponse], error) {
+	filter, err := scopeFilter(ctx, mapping.QueryMapFilter(&query))
 	if err != nil {
 		return nil, err
 	}
 
-	addressEvent := event.AddressEvent{
-		EventType:   "AddressCreated",
-		AddressId:   address.Id,
-		City:        request.City,
-		Country:     request.Country,
-		FullAddress: request.FullAddress,
-		UserId:      request.UserId,
+	if len(filter.Sort) > 1 || (len(filter.Sort) == 1 && !entity.CursorSortFields[filter.Sort[0].Field]) {
+		return nil, common.ErrUnsupportedCursorSort
+	}
+	if len(filter.Sort) == 0 {
+		filter.Sort = []entity.SortField{{Field: "id"}}
+	}
+	sort := filter.Sort[0]
+
+	if query.Cursor != "" {
+		cursor, err := a.cursorCodec.Decode(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		// a cursor is only meaningful for the sort it was created with
+		if cursor.Field != sort.Field || cursor.Desc != sort.Desc {
+			return nil, common.ErrInvalidCursor
+		}
+		filter.Cursor = &cursor
 	}
 
-	eventBytes, err := json.Marshal(addressEvent)
+	addresses, hasMore, err := a.addressRepository.GetAllByCursor(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	eventMessage := string(eventBytes)
+	result := &common.CursorPageable[response.AddressResponse]{
+		Items:    mapping.MapDtos(addresses),
+		PageSize: filter.PageSize,
+	}
 
-	err = a.messageBroker.SendMessage(ctx, constants.KafkaTopics.AddressCreated, eventMessage)
+	if len(addresses) > 0 {
+		backward := filter.Cursor != nil && filter.Cursor.Before
+		hasNext := backward || hasMore
+		hasPrev := (backward && hasMore) || (!backward && filter.Cursor != nil)
+
+		if hasNext {
+			last := addresses[len(addresses)-1]
+			result.NextCursor = a.cursorCodec.Encode(common.Cursor{Field: sort.Field, Desc: sort.Desc, Value: last.SortValue(sort.Field), Id: last.Id})
+		}
+		if hasPrev {
+			first := addresses[0]
+			result.PrevCursor = a.cursorCodec.Encode(common.Cursor{Field: sort.Field, Desc: sort.Desc, Value: first.SortValue(sort.Field), Id: first.Id, Before: true})
+		}
+	}
 
-	mappedResponse := mapping.MapDto(resp)
+	// counting is the expensive part on large tables, so it is opt-in
+	if filter.WithCount {
+		totalItems, err := a.addressRepository.Count(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalItems = &totalItems
+	}
 
-	return mappedResponse, err
+	return result, nil
 }
 
-func (a addressService) Delete(ctx context.Context, id int) error {
-	_, err := a.addressRepository.GetById(ctx, id)
-	if err != nil {
-		return err
+func (a addressService) Create(ctx context.Context, request request.AddressCreateRequest) (*response.AddressResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrForbidden
 	}
 
-	if err = a.addressRepository.Delete(ctx, id); err != nil {
-		return err
+	// regular users can only create addresses for themselves, admins may create for anyone
+	if request.UserId == "" {
+		request.UserId = principal.UserId
+	} else if !principal.CanAccess(request.UserId) {
+		return nil, ErrForbidden
 	}
 
-	addressEvent := event.AddressEvent{
-		EventType: "AddressDeleted",
-		AddressId: id,
+	address := mapping.CreateMapEntity(&request)
+
+	var resp entity.Address
+	err := a.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = a.addressRepository.Create(ctx, address)
+		if err != nil {
+			return err
+		}
+
+		return a.addEvent(ctx, events.KafkaTopics.AddressCreated, mapping.MapEvent(events.AddressCreated, nil, &resp))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	eventBytes, err := json.Marshal(addressEvent)
+	mappedResponse := mapping.MapDto(resp)
+
+	return mappedResponse, nil
+}
+
+func (a addressService) Delete(ctx context.Context, id int) error {
+	currentAddress, err := a.getOwned(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	eventMessage := string(eventBytes)
-
-	err = a.messageBroker.SendMessage(ctx, constants.KafkaTopics.AddressDeleted, eventMessage)
+	return a.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := a.addressRepository.Delete(ctx, id); err != nil {
+			return err
+		}
 
-	return nil
+		return a.addEvent(ctx, events.KafkaTopics.AddressDeleted, mapping.MapEvent(events.AddressDeleted, &currentAddress, nil))
+	})
 }
 
 func (a addressService) Patch(ctx context.Context, id int, patchRequest request.AddressPatchRequest) (*response.AddressResponse, error) {
-	currentAddress, err := a.addressRepository.GetById(ctx, id)
+	currentAddress, err := a.getOwned(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -181,62 +231,138 @@ func (a addressService) Patch(ctx context.Context, id int, patchRequest request.
 
 	patch, err := jsonpatch.DecodePatch(patchRequestBytes)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidPatch.Wrap(err)
 	}
 
 	modifiedAddressBytes, err := patch.Apply(currentAddressBytes)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidPatch.Wrap(err)
 	}
 
 	var updatedAddress entity.Address
 	if err := json.Unmarshal(modifiedAddressBytes, &updatedAddress); err != nil {
-		return nil, err
+		return nil, ErrInvalidPatch.Wrap(err)
 	}
 
-	if _, err := a.addressRepository.Update(ctx, updatedAddress); err != nil {
+	// the patch document must not move the address to another row, owner or tenant
+	updatedAddress.Id = currentAddress.Id
+	updatedAddress.CreatedAt = currentAddress.CreatedAt
+	updatedAddress.UserId = currentAddress.UserId
+	updatedAddress.TenantId = currentAddress.TenantId
+	updatedAddress.Version = currentAddress.Version
+
+	err = a.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		updatedAddress, err = a.addressRepository.Update(ctx, updatedAddress)
+		if err != nil {
+			return err
+		}
+
+		return a.addEvent(ctx, events.KafkaTopics.AddressUpdated, mapping.MapEvent(events.AddressUpdated, &currentAddress, &updatedAddress))
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	addressEvent := event.AddressEvent{
-		EventType:   "AddressUpdated",
-		AddressId:   updatedAddress.Id,
-		City:        updatedAddress.City,
-		Country:     updatedAddress.Country,
-		FullAddress: updatedAddress.FullAddress,
-		UserId:      updatedAddress.UserId,
-	}
+	mappedResponse := mapping.MapDto(updatedAddress)
+
+	return mappedResponse, nil
+}
 
-	eventBytes, err := json.Marshal(addressEvent)
+func (a addressService) GetById(ctx context.Context, id int) (*response.AddressResponse, error) {
+	address, err := a.getOwned(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	eventMessage := string(eventBytes)
+	mappedResponse := mapping.MapDto(address)
 
-	err = a.messageBroker.SendMessage(ctx, constants.KafkaTopics.AddressUpdated, eventMessage)
+	return mappedResponse, nil
+}
 
-	mappedResponse := mapping.MapDto(updatedAddress)
+// addEvent stores the event as a structured CloudEvent in the outbox, it must run in the transaction
+// of the change it describes and is published to kafka by the outbox relay after commit
+func (a addressService) addEvent(ctx context.Context, topic string, addressEvent events.AddressEvent) error {
+	cloudEvent, err := cloudevents.New(events.Source, events.CloudEventType(addressEvent.EventType), events.DataSchema(events.CurrentVersion), addressEvent)
+	if err != nil {
+		return err
+	}
 
-	return mappedResponse, nil
+	cloudEvent.Subject = strconv.Itoa(addressEvent.AddressId)
+	cloudEvent.TenantId = addressEvent.TenantId
+	cloudEvent.RequestId = util.RequestIdFromContext(ctx)
+	traceContext := util.TraceContextFromContext(ctx)
+	cloudEvent.Traceparent = traceContext.Traceparent
+	cloudEvent.Tracestate = traceContext.Tracestate
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		cloudEvent.Actor = principal.UserId
+	}
+
+	payload, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return err
+	}
+
+	return a.outboxRepository.Add(ctx, outbox.OutboxMessage{
+		AggregateType: "address",
+		AggregateId:   strconv.Itoa(addressEvent.AddressId),
+		TenantId:      addressEvent.TenantId,
+		PartitionKey:  a.partitionKey(addressEvent),
+		Topic:         topic,
+		Payload:       string(payload),
+	})
 }
 
-func (a addressService) GetById(ctx context.Context, id int) (*response.AddressResponse, error) {
+// partitionKey keys the events of an address by its id, or by its owner when the events of a user must stay ordered
+func (a addressService) partitionKey(addressEvent events.AddressEvent) string {
+	if a.cfg.Kafka.PartitionKey == config.PartitionKeyUserId {
+		return addressEvent.UserId
+	}
+	return strconv.Itoa(addressEvent.AddressId)
+}
+
+// getOwned loads the address and hides it when the authenticated user is not allowed to see it
+func (a addressService) getOwned(ctx context.Context, id int) (entity.Address, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return entity.Address{}, ErrAddressNotFound
+	}
+
 	address, err := a.addressRepository.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.Address{}, ErrAddressNotFound
+	}
 	if err != nil {
-		return nil, err
+		return entity.Address{}, err
 	}
 
-	mappedResponse := mapping.MapDto(address)
+	if !principal.CanAccess(address.UserId) {
+		return entity.Address{}, ErrAddressNotFound
+	}
 
-	return mappedResponse, nil
+	return address, nil
+}
+
+// scopeFilter limits listings to the authenticated user unless the principal is an admin
+func scopeFilter(ctx context.Context, filter entity.AddressFilter) (entity.AddressFilter, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return filter, ErrForbidden
+	}
+
+	if !principal.IsAdmin() {
+		filter.UserId = principal.UserId
+	}
+
+	return filter, nil
 }
 
-func NewAddressService(cfg *config.Config, addressRepository repository.AddressRepository, logger logger.Logger, messageBroker kafka.Producer) AddressService {
+func NewAddressService(cfg *config.Config, addressRepository repository.AddressRepository, outboxRepository outboxRepository.OutboxRepository, transactor postgres.Transactor, logger logger.Logger) AddressService {
 	return &addressService{
 		cfg:               cfg,
 		addressRepository: addressRepository,
+		outboxRepository:  outboxRepository,
+		transactor:        transactor,
 		logger:            logger,
-		messageBroker:     messageBroker,
+		cursorCodec:       common.NewCursorCodec(cfg.Pagination.CursorSecret),
 	}
 }
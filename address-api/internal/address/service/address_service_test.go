@@ -2,20 +2,43 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/sefikcan/address-api/internal/address/dto/request"
 	"github.com/sefikcan/address-api/internal/address/entity"
 	"github.com/sefikcan/address-api/internal/address/repository/mocks"
 	mocks2 "github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/auth"
 	"github.com/sefikcan/address-api/internal/common"
+	outbox "github.com/sefikcan/address-api/internal/outbox/entity"
+	mocks4 "github.com/sefikcan/address-api/internal/outbox/repository/mocks"
 	"github.com/sefikcan/address-api/pkg/config"
+	mocks3 "github.com/sefikcan/address-api/pkg/storage/postgres/mocks"
+	"github.com/sefikcan/address-api/pkg/util"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"strings"
 	"testing"
 )
 
+func principalContext(userId string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserId: userId})
+}
+
+// inlineTransactor runs the transaction function directly, commit and rollback are covered by the repositories
+func inlineTransactor() *mocks3.Transactor {
+	transactor := new(mocks3.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	}).Maybe()
+	return transactor
+}
+
 func TestAddressService_Create(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
 	mockRepo.On("Create", mock.Anything, mock.Anything).Return(entity.Address{
 		Id:          1,
 		City:        "Test City",
@@ -24,7 +47,9 @@ func TestAddressService_Create(t *testing.T) {
 		UserId:      "1",
 	}, nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
 
 	createReq := request.AddressCreateRequest{
 		City:        "Test City",
@@ -34,7 +59,7 @@ func TestAddressService_Create(t *testing.T) {
 	}
 
 	// Test Create Address
-	resp, err := addressService.Create(context.Background(), createReq)
+	resp, err := addressService.Create(principalContext("1"), createReq)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -46,12 +71,14 @@ func TestAddressService_Create(t *testing.T) {
 
 	// Verify the mock interactions
 	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
 }
 
 func TestAddressService_GetAll(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
-	mockRepo.On("GetAll", mock.Anything, mock.Anything, mock.Anything).Return(&common.Pageable[entity.Address]{
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetAll", mock.Anything, mock.Anything).Return(&common.Pageable[entity.Address]{
 		Items: []entity.Address{
 			{Id: 1, City: "Test City", Country: "Test Country", FullAddress: "123 Test St", UserId: "1"},
 		},
@@ -61,9 +88,9 @@ func TestAddressService_GetAll(t *testing.T) {
 		PageSize:    10,
 	}, nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
 
-	resp, err := addressService.GetAll(context.Background(), 1, 10)
+	resp, err := addressService.GetAll(principalContext("1"), request.AddressQueryRequest{Page: 1, Size: 10})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -76,6 +103,7 @@ func TestAddressService_GetAll(t *testing.T) {
 func TestAddressService_GetById(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
 	mockRepo.On("GetById", mock.Anything, mock.Anything).Return(entity.Address{
 		Id:          1,
 		City:        "Test City",
@@ -84,8 +112,8 @@ func TestAddressService_GetById(t *testing.T) {
 		UserId:      "1",
 	}, nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
-	resp, err := addressService.GetById(context.Background(), 1)
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+	resp, err := addressService.GetById(principalContext("1"), 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -100,6 +128,7 @@ func TestAddressService_GetById(t *testing.T) {
 func TestAddressService_Delete(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
 	mockRepo.On("GetById", mock.Anything, mock.Anything).Return(entity.Address{
 		Id:          1,
 		City:        "City",
@@ -109,18 +138,22 @@ func TestAddressService_Delete(t *testing.T) {
 	}, nil)
 	mockRepo.On("Delete", mock.Anything, mock.Anything).Return(nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
 
-	err := addressService.Delete(context.Background(), 1)
+	err := addressService.Delete(principalContext("1"), 1)
 
 	assert.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
 }
 
 func TestAddressService_Update(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
 	mockRepo.On("GetById", mock.Anything, mock.Anything).Return(entity.Address{
 		Id:          1,
 		City:        "Old City",
@@ -136,7 +169,9 @@ func TestAddressService_Update(t *testing.T) {
 		UserId:      "1",
 	}, nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
 
 	updateReq := request.AddressUpdateRequest{
 		Id:          1,
@@ -145,7 +180,7 @@ func TestAddressService_Update(t *testing.T) {
 		FullAddress: "123 New St",
 	}
 
-	resp, err := addressService.Update(context.Background(), updateReq)
+	resp, err := addressService.Update(principalContext("1"), updateReq)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -155,11 +190,13 @@ func TestAddressService_Update(t *testing.T) {
 	assert.Equal(t, "123 New St", resp.FullAddress)
 
 	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
 }
 
 func TestAddressService_Patch(t *testing.T) {
 	mockRepo := new(mocks.AddressRepository)
 	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
 	mockRepo.On("GetById", mock.Anything, mock.Anything).Return(entity.Address{
 		Id:          1,
 		City:        "Old City",
@@ -175,7 +212,9 @@ func TestAddressService_Patch(t *testing.T) {
 		UserId:      "1",
 	}, nil)
 
-	addressService := NewAddressService(&config.Config{}, mockRepo, mockLogger)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
 
 	patchReq := request.AddressPatchRequest{
 		Doc: []request.PatchRequest{
@@ -192,7 +231,7 @@ func TestAddressService_Patch(t *testing.T) {
 		},
 	}
 
-	resp, err := addressService.Patch(context.Background(), 1, patchReq)
+	resp, err := addressService.Patch(principalContext("1"), 1, patchReq)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
@@ -200,4 +239,321 @@ func TestAddressService_Patch(t *testing.T) {
 	assert.Equal(t, "New City", resp.City)
 
 	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestAddressService_Create_StructuredOnly(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(a entity.Address) bool {
+		return a.FullAddress == "Bagdat Cad. 12, 34710 Istanbul, Turkey" && a.CountryCode == "TR"
+	})).Return(entity.Address{
+		Id:           1,
+		City:         "Istanbul",
+		Country:      "Turkey",
+		FullAddress:  "Bagdat Cad. 12, 34710 Istanbul, Turkey",
+		UserId:       "1",
+		AddressLine1: "Bagdat Cad.",
+		HouseNumber:  "12",
+		PostalCode:   "34710",
+		CountryCode:  "TR",
+	}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+
+	resp, err := addressService.Create(principalContext("1"), request.AddressCreateRequest{
+		City:         "Istanbul",
+		Country:      "Turkey",
+		UserId:       "1",
+		AddressLine1: "Bagdat Cad.",
+		HouseNumber:  "12",
+		PostalCode:   "34710",
+		CountryCode:  "tr",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "34710", resp.PostalCode)
+	assert.Equal(t, "Bagdat Cad. 12, 34710 Istanbul, Turkey", resp.FullAddress)
+
+	mockRepo.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestAddressService_Update_StructuredOnlyComposesFullAddress(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.Address{
+		Id:           1,
+		City:         "Istanbul",
+		Country:      "Turkey",
+		FullAddress:  "Old Street 1, Istanbul",
+		AddressLine1: "Old Street 1, Istanbul",
+		UserId:       "1",
+	}, nil)
+
+	var updated entity.Address
+	mockRepo.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(1).(entity.Address)
+	}).Return(func(_ context.Context, address entity.Address) entity.Address {
+		return address
+	}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+	resp, err := addressService.Update(principalContext("1"), request.AddressUpdateRequest{
+		Id:           1,
+		City:         "Istanbul",
+		Country:      "Turkey",
+		AddressLine1: "Bagdat Cad.",
+		HouseNumber:  "12",
+		PostalCode:   "34710",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bagdat Cad. 12, 34710 Istanbul, Turkey", updated.FullAddress)
+	assert.Equal(t, "Bagdat Cad.", resp.AddressLine1)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddressService_Update_FullAddressOnlyReplacesTheStreet(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.Address{
+		Id:           1,
+		City:         "Istanbul",
+		Country:      "Turkey",
+		FullAddress:  "Bagdat Cad. 12, 34710 Istanbul, Turkey",
+		AddressLine1: "Bagdat Cad.",
+		HouseNumber:  "12",
+		PostalCode:   "34710",
+		UserId:       "1",
+	}, nil)
+
+	var updated entity.Address
+	mockRepo.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(1).(entity.Address)
+	}).Return(func(_ context.Context, address entity.Address) entity.Address {
+		return address
+	}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+	resp, err := addressService.Update(principalContext("1"), request.AddressUpdateRequest{
+		Id:          1,
+		City:        "Istanbul",
+		Country:     "Turkey",
+		FullAddress: "Moda Cad. No 5, Istanbul",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Moda Cad. No 5, Istanbul", updated.FullAddress)
+	assert.Empty(t, updated.AddressLine1)
+	assert.Empty(t, updated.HouseNumber)
+	assert.Equal(t, "34710", updated.PostalCode)
+	assert.Empty(t, resp.AddressLine1)
+
+	// an address without an address line keeps its free-text address when it is composed
+	assert.Equal(t, "Moda Cad. No 5, Istanbul", updated.ComposeFullAddress())
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddressService_GetById_OtherUsersAddressIsNotFound(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.Address{Id: 1, UserId: "2"}, nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+
+	resp, err := addressService.GetById(principalContext("1"), 1)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+
+	// admins can act across users
+	adminCtx := auth.WithPrincipal(context.Background(), auth.Principal{UserId: "1", Scopes: []string{auth.ScopeAdmin}})
+	resp, err = addressService.GetById(adminCtx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2", resp.UserId)
+
+	// a request without principal never reaches the data
+	_, err = addressService.GetById(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+}
+
+func TestAddressService_GetAll_ScopedToPrincipal(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetAll", mock.Anything, mock.MatchedBy(func(f entity.AddressFilter) bool {
+		return f.UserId == "1"
+	})).Return(&common.Pageable[entity.Address]{}, nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+
+	_, err := addressService.GetAll(principalContext("1"), request.AddressQueryRequest{UserId: "2"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddressService_Create_ForOtherUserIsForbidden(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+
+	_, err := addressService.Create(principalContext("1"), request.AddressCreateRequest{UserId: "2", City: "City", Country: "Country", FullAddress: "123 Test St"})
+
+	assert.ErrorIs(t, err, ErrForbidden)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestAddressService_Patch_CannotChangeOwner(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockLogger := new(mocks2.Logger)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.Address{Id: 1, City: "Old City", UserId: "1"}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(a entity.Address) bool {
+		return a.Id == 1 && a.UserId == "1"
+	})).Return(entity.Address{Id: 1, City: "Old City", UserId: "1"}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), mockLogger)
+
+	resp, err := addressService.Patch(principalContext("1"), 1, request.AddressPatchRequest{
+		Doc: []request.PatchRequest{
+			{Op: "replace", Path: "/user_id", Value: "2"},
+			{Op: "replace", Path: "/id", Value: 99},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1", resp.UserId)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddressService_Create_StoresEventInOutbox(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(entity.Address{Id: 7, City: "Istanbul", UserId: "1", TenantId: "brand-a"}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(m outbox.OutboxMessage) bool {
+		return m.AggregateType == "address" && m.AggregateId == "7" && m.TenantId == "brand-a" &&
+			m.Topic == "address-created" && strings.Contains(m.Payload, `"event_type":"AddressCreated"`)
+	})).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), new(mocks2.Logger))
+
+	_, err := addressService.Create(principalContext("1"), request.AddressCreateRequest{City: "Istanbul", Country: "Turkey", FullAddress: "Bagdat Cad. No 1"})
+
+	assert.NoError(t, err)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestAddressService_Create_FailsWhenOutboxFails(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(entity.Address{Id: 7, UserId: "1"}, nil)
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Return(errors.New("outboxRepository.Add.DbError: connection reset"))
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), new(mocks2.Logger))
+
+	resp, err := addressService.Create(principalContext("1"), request.AddressCreateRequest{City: "Istanbul", Country: "Turkey", FullAddress: "Bagdat Cad. No 1"})
+
+	// the error rolls the transaction back, so the address is not stored without its event
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+}
+
+func TestAddressService_Update_StoresCloudEventWithSnapshots(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 7).Return(entity.Address{Id: 7, City: "Old City", Country: "Turkey", UserId: "1", TenantId: "brand-a", Version: 4}, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything).Return(entity.Address{Id: 7, City: "New City", Country: "Turkey", UserId: "1", TenantId: "brand-a", Version: 5}, nil)
+
+	var stored outbox.OutboxMessage
+	mockOutbox.On("Add", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(outbox.OutboxMessage)
+	}).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), new(mocks2.Logger))
+
+	ctx := util.WithRequestId(principalContext("1"), "req-1")
+	ctx = util.WithTraceContext(ctx, util.TraceContext{Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+	_, err := addressService.Update(ctx, request.AddressUpdateRequest{Id: 7, City: "New City", Country: "Turkey", FullAddress: "Bagdat Cad. No 1"})
+	assert.NoError(t, err)
+
+	cloudEvent, err := cloudevents.Decode(nil, []byte(stored.Payload))
+	assert.NoError(t, err)
+	assert.Equal(t, "com.sefikcan.address.updated", cloudEvent.Type)
+	assert.Equal(t, events.DataSchema(events.CurrentVersion), cloudEvent.DataSchema)
+	assert.Equal(t, "7", cloudEvent.Subject)
+	assert.Equal(t, "req-1", cloudEvent.RequestId)
+	assert.Equal(t, "1", cloudEvent.Actor)
+	assert.Equal(t, "brand-a", cloudEvent.TenantId)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", cloudEvent.Traceparent)
+	assert.NotEmpty(t, cloudEvent.Id)
+	assert.Equal(t, "7", stored.PartitionKey)
+
+	var addressEvent events.AddressEvent
+	assert.NoError(t, cloudEvent.DataAs(&addressEvent))
+	assert.Equal(t, "Old City", addressEvent.Before.City)
+	assert.Equal(t, "New City", addressEvent.After.City)
+	assert.Equal(t, int64(5), addressEvent.Version)
+}
+
+func TestAddressService_Delete_StoresSnapshotOfDeletedAddress(t *testing.T) {
+	mockRepo := new(mocks.AddressRepository)
+	mockOutbox := new(mocks4.OutboxRepository)
+	mockRepo.On("GetById", mock.Anything, 7).Return(entity.Address{Id: 7, City: "Istanbul", UserId: "1", Version: 2}, nil)
+	mockRepo.On("Delete", mock.Anything, 7).Return(nil)
+	mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(m outbox.OutboxMessage) bool {
+		cloudEvent, err := cloudevents.Decode(nil, []byte(m.Payload))
+		var addressEvent events.AddressEvent
+		return err == nil && cloudEvent.DataAs(&addressEvent) == nil &&
+			addressEvent.After == nil && addressEvent.Before != nil && addressEvent.Before.City == "Istanbul" &&
+			addressEvent.Version == 3
+	})).Return(nil)
+
+	addressService := NewAddressService(&config.Config{}, mockRepo, mockOutbox, inlineTransactor(), new(mocks2.Logger))
+
+	assert.NoError(t, addressService.Delete(principalContext("1"), 7))
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestAddressService_Create_KeysEventsByConfiguredPartitionKey(t *testing.T) {
+	tests := []struct {
+		partitionKey string
+		wantKey      string
+	}{
+		{partitionKey: "", wantKey: "7"},
+		{partitionKey: config.PartitionKeyAddressId, wantKey: "7"},
+		{partitionKey: config.PartitionKeyUserId, wantKey: "1"},
+	}
+
+	for _, tt := range tests {
+		mockRepo := new(mocks.AddressRepository)
+		mockOutbox := new(mocks4.OutboxRepository)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(entity.Address{Id: 7, UserId: "1"}, nil)
+		mockOutbox.On("Add", mock.Anything, mock.MatchedBy(func(m outbox.OutboxMessage) bool {
+			return m.PartitionKey == tt.wantKey
+		})).Return(nil)
+
+		cfg := &config.Config{Kafka: config.KafkaConfig{PartitionKey: tt.partitionKey}}
+		addressService := NewAddressService(cfg, mockRepo, mockOutbox, inlineTransactor(), new(mocks2.Logger))
+
+		_, err := addressService.Create(principalContext("1"), request.AddressCreateRequest{City: "Istanbul", Country: "Turkey", FullAddress: "Bagdat Cad. No 1"})
+
+		assert.NoError(t, err)
+		mockOutbox.AssertExpectations(t)
+	}
 }
@@ -67,9 +67,9 @@ func (_m *AddressService) Delete(ctx context.Context, id int) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: ctx, page, pageSize
-func (_m *AddressService) GetAll(ctx context.Context, page int, pageSize int) (*common.Pageable[response.AddressResponse], error) {
-	ret := _m.Called(ctx, page, pageSize)
+// GetAll provides a mock function with given fields: ctx, query
+func (_m *AddressService) GetAll(ctx context.Context, query request.AddressQueryRequest) (*common.Pageable[response.AddressResponse], error) {
+	ret := _m.Called(ctx, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
@@ -77,19 +77,49 @@ func (_m *AddressService) GetAll(ctx context.Context, page int, pageSize int) (*
 
 	var r0 *common.Pageable[response.AddressResponse]
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*common.Pageable[response.AddressResponse], error)); ok {
-		return rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(0).(func(context.Context, request.AddressQueryRequest) (*common.Pageable[response.AddressResponse], error)); ok {
+		return rf(ctx, query)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, int) *common.Pageable[response.AddressResponse]); ok {
-		r0 = rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(0).(func(context.Context, request.AddressQueryRequest) *common.Pageable[response.AddressResponse]); ok {
+		r0 = rf(ctx, query)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*common.Pageable[response.AddressResponse])
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
-		r1 = rf(ctx, page, pageSize)
+	if rf, ok := ret.Get(1).(func(context.Context, request.AddressQueryRequest) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAllByCursor provides a mock function with given fields: ctx, query
+func (_m *AddressService) GetAllByCursor(ctx context.Context, query request.AddressQueryRequest) (*common.CursorPageable[response.AddressResponse], error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllByCursor")
+	}
+
+	var r0 *common.CursorPageable[response.AddressResponse]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, request.AddressQueryRequest) (*common.CursorPageable[response.AddressResponse], error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, request.AddressQueryRequest) *common.CursorPageable[response.AddressResponse]); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*common.CursorPageable[response.AddressResponse])
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, request.AddressQueryRequest) error); ok {
+		r1 = rf(ctx, query)
 	} else {
 		r1 = ret.Error(1)
 	}
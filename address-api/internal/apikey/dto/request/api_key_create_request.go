@@ -0,0 +1,10 @@
+package request
+
+import "time"
+
+type ApiKeyCreateRequest struct {
+	Name      string     `json:"name" validate:"required,min=3,max=50"`
+	Owner     string     `json:"owner" validate:"omitempty,max=50"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=address:read address:write address:delete address:admin"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
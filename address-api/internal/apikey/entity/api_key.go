@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// ApiKey is a server-to-server credential, only the SHA-256 hash of the secret is stored
+type ApiKey struct {
+	Id         int        `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Name       string     `json:"name"`
+	Owner      string     `gorm:"index" json:"owner"`
+	TenantId   string     `gorm:"index;size:64" json:"tenant_id"`
+	Prefix     string     `gorm:"uniqueIndex;size:16" json:"prefix"`
+	KeyHash    string     `gorm:"size:64" json:"-"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// ScopeList returns the space separated scopes as a slice
+func (k ApiKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// IsActive reports whether the key is neither revoked nor expired at the given time
+func (k ApiKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/apikey/dto/request"
+	"github.com/sefikcan/address-api/internal/apikey/service"
+	"strconv"
+)
+
+type ApiKeyHandler interface {
+	Create(c *fiber.Ctx) error
+	GetAll(c *fiber.Ctx) error
+	Revoke(c *fiber.Ctx) error
+	Rotate(c *fiber.Ctx) error
+}
+
+type apiKeyHandler struct {
+	apiKeyService service.ApiKeyService
+}
+
+// Create godoc
+// @Summary Create an api key
+// @Description Create an api key for a server-to-server client, the secret is only returned once
+// @Tags api-keys
+// @Security BearerAuth
+// @Param apiKey body request.ApiKeyCreateRequest true "Api key creation payload"
+// @Success 201 {object} response.ApiKeySecretResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/api-keys [post]
+func (a apiKeyHandler) Create(c *fiber.Ctx) error {
+	var apiKey request.ApiKeyCreateRequest
+	if err := c.BodyParser(&apiKey); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	response, err := a.apiKeyService.Create(c.UserContext(), apiKey)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetAll godoc
+// @Summary Get all api keys
+// @Description Get all api keys, secrets are never returned
+// @Tags api-keys
+// @Security BearerAuth
+// @Param owner query string false "Filter by owner"
+// @Success 200 {array} response.ApiKeyResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/api-keys [get]
+func (a apiKeyHandler) GetAll(c *fiber.Ctx) error {
+	apiKeys, err := a.apiKeyService.GetAll(c.UserContext(), c.Query("owner"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(apiKeys)
+}
+
+// Revoke godoc
+// @Summary Revoke an api key
+// @Description Revoke an api key by its ID, the key is rejected immediately
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path int true "Api key ID"
+// @Success 204
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/api-keys/{id} [delete]
+func (a apiKeyHandler) Revoke(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	if err = a.apiKeyService.Revoke(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Rotate godoc
+// @Summary Rotate an api key
+// @Description Replace the secret of an api key, the new secret is only returned once
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path int true "Api key ID"
+// @Success 200 {object} response.ApiKeySecretResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/api-keys/{id}/rotate [post]
+func (a apiKeyHandler) Rotate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	response, err := a.apiKeyService.Rotate(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func NewApiKeyHandler(apiKeyService service.ApiKeyService) ApiKeyHandler {
+	return &apiKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
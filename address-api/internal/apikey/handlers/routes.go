@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/apikey/dto/request"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/internal/middleware"
+)
+
+// MapApiKeyRoutes mounts the tenantScoped middlewares after the authentication bound the tenant
+func MapApiKeyRoutes(app *fiber.App, apiKeyHandler ApiKeyHandler, manager *middleware.Manager, tenantScoped []fiber.Handler) {
+	// key management is restricted to user tokens with the admin scope, api keys cannot mint api keys
+	admin := app.Group("/api/v1/admin/api-keys", append([]fiber.Handler{manager.Authentication(), manager.RequireScopes(auth.ScopeAdmin)}, tenantScoped...)...)
+
+	admin.Post("/", middleware.Validator(&request.ApiKeyCreateRequest{}), apiKeyHandler.Create)
+	admin.Get("/", apiKeyHandler.GetAll)
+	admin.Delete("/:id", apiKeyHandler.Revoke)
+	admin.Post("/:id/rotate", apiKeyHandler.Rotate)
+}
@@ -0,0 +1,27 @@
+package mapping
+
+import (
+	"github.com/sefikcan/address-api/internal/apikey/dto/response"
+	"github.com/sefikcan/address-api/internal/apikey/entity"
+)
+
+func MapDto(k entity.ApiKey) *response.ApiKeyResponse {
+	return &response.ApiKeyResponse{
+		Id:         k.Id,
+		Name:       k.Name,
+		Owner:      k.Owner,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func MapSecretDto(k entity.ApiKey, secret string) *response.ApiKeySecretResponse {
+	return &response.ApiKeySecretResponse{
+		ApiKeyResponse: *MapDto(k),
+		Secret:         secret,
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/internal/apikey/entity"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ApiKeyRepository interface {
+	Create(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error)
+	Update(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error)
+	GetById(ctx context.Context, id int) (entity.ApiKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (entity.ApiKey, error)
+	GetAll(ctx context.Context, owner string) ([]entity.ApiKey, error)
+	UpdateLastUsed(ctx context.Context, id int, lastUsedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (a apiKeyRepository) Create(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return entity.ApiKey{}, errors.Wrap(tenant.ErrMissingTenant, "apiKeyRepository.Create.TenantError")
+	}
+
+	apiKey.TenantId = tenantId
+	if result := postgres.DB(ctx, a.db).Create(&apiKey); result.Error != nil {
+		return entity.ApiKey{}, common.DbError(result.Error, "apiKeyRepository.Create")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) Update(ctx context.Context, apiKey entity.ApiKey) (entity.ApiKey, error) {
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return entity.ApiKey{}, errors.Wrap(err, "apiKeyRepository.Update.TenantError")
+	}
+
+	if result := db.Model(&entity.ApiKey{}).Where(`id = ?`, apiKey.Id).Select("*").Omit("created_at", "tenant_id").Updates(&apiKey); result.Error != nil {
+		return entity.ApiKey{}, common.DbError(result.Error, "apiKeyRepository.Update")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) GetById(ctx context.Context, id int) (entity.ApiKey, error) {
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return entity.ApiKey{}, errors.Wrap(err, "apiKeyRepository.GetById.TenantError")
+	}
+
+	apiKey := entity.ApiKey{}
+	if err := db.Where(`id = ?`, id).First(&apiKey).Error; err != nil {
+		return entity.ApiKey{}, common.DbError(err, "apiKeyRepository.GetById")
+	}
+
+	return apiKey, nil
+}
+
+// GetByPrefix is not tenant scoped, the tenant of a request is only known after its key is resolved
+func (a apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (entity.ApiKey, error) {
+	apiKey := entity.ApiKey{}
+	if err := postgres.DB(ctx, a.db).Where(`prefix = ?`, prefix).First(&apiKey).Error; err != nil {
+		return entity.ApiKey{}, common.DbError(err, "apiKeyRepository.GetByPrefix")
+	}
+
+	return apiKey, nil
+}
+
+func (a apiKeyRepository) GetAll(ctx context.Context, owner string) ([]entity.ApiKey, error) {
+	db, err := a.tenantDB(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "apiKeyRepository.GetAll.TenantError")
+	}
+
+	var apiKeys []entity.ApiKey
+
+	query := db.Model(&entity.ApiKey{})
+	if owner != "" {
+		query = query.Where(`owner = ?`, owner)
+	}
+
+	if err := query.Order("id").Find(&apiKeys).Error; err != nil {
+		return nil, common.DbError(err, "apiKeyRepository.GetAll")
+	}
+
+	return apiKeys, nil
+}
+
+// UpdateLastUsed only touches last_used_at so concurrent requests do not overwrite other columns
+func (a apiKeyRepository) UpdateLastUsed(ctx context.Context, id int, lastUsedAt time.Time) error {
+	err := postgres.DB(ctx, a.db).Model(&entity.ApiKey{}).Where(`id = ?`, id).UpdateColumn("last_used_at", lastUsedAt).Error
+	if err != nil {
+		return common.DbError(err, "apiKeyRepository.UpdateLastUsed")
+	}
+
+	return nil
+}
+
+// tenantDB returns a session limited to the tenant of ctx, queries without a tenant are refused
+func (a apiKeyRepository) tenantDB(ctx context.Context) (*gorm.DB, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	return postgres.DB(ctx, a.db).Where(`tenant_id = ?`, tenantId).Session(&gorm.Session{}), nil
+}
+
+func NewApiKeyRepository(db *gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"github.com/sefikcan/address-api/internal/apikey/entity"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+func tenantContext(tenantId string) context.Context {
+	return tenant.WithTenant(context.Background(), tenantId)
+}
+
+func SetupTestDB() (*gorm.DB, func()) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	err = db.AutoMigrate(&entity.ApiKey{})
+	if err != nil {
+		return nil, nil
+	}
+
+	return db, func() {
+		db.Exec(`DROP TABLE api_keys`)
+	}
+}
+
+func TestApiKeyRepository_CreateAndGetByPrefix(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewApiKeyRepository(db)
+
+	created, err := repo.Create(tenantContext("brand-a"), entity.ApiKey{
+		Name:    "batch job",
+		Owner:   "1",
+		Prefix:  "0011223344556677",
+		KeyHash: "hash",
+		Scopes:  "address:read address:write",
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, created.Id)
+
+	// keys are looked up before the tenant of the request is known
+	result, err := repo.GetByPrefix(context.Background(), "0011223344556677")
+	assert.NoError(t, err)
+	assert.Equal(t, created.Id, result.Id)
+	assert.Equal(t, "brand-a", result.TenantId)
+	assert.Equal(t, []string{"address:read", "address:write"}, result.ScopeList())
+
+	_, err = repo.GetByPrefix(tenantContext("brand-a"), "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestApiKeyRepository_GetAllByOwner(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewApiKeyRepository(db)
+	_, _ = repo.Create(tenantContext("brand-a"), entity.ApiKey{Name: "first", Owner: "1", Prefix: "a"})
+	_, _ = repo.Create(tenantContext("brand-a"), entity.ApiKey{Name: "second", Owner: "2", Prefix: "b"})
+
+	_, _ = repo.Create(tenantContext("brand-b"), entity.ApiKey{Name: "other tenant", Owner: "2", Prefix: "c"})
+
+	all, err := repo.GetAll(tenantContext("brand-a"), "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	owned, err := repo.GetAll(tenantContext("brand-a"), "2")
+	assert.NoError(t, err)
+	assert.Len(t, owned, 1)
+	assert.Equal(t, "second", owned[0].Name)
+}
+
+func TestApiKeyRepository_UpdateLastUsed(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewApiKeyRepository(db)
+	created, _ := repo.Create(tenantContext("brand-a"), entity.ApiKey{Name: "batch job", Owner: "1", Prefix: "a"})
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, repo.UpdateLastUsed(tenantContext("brand-a"), created.Id, usedAt))
+
+	result, err := repo.GetById(tenantContext("brand-a"), created.Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.LastUsedAt)
+	assert.True(t, usedAt.Equal(*result.LastUsedAt))
+}
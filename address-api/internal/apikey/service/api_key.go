@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/sefikcan/address-api/internal/apikey/dto/request"
+	"github.com/sefikcan/address-api/internal/apikey/dto/response"
+	"github.com/sefikcan/address-api/internal/apikey/entity"
+	"github.com/sefikcan/address-api/internal/apikey/mapping"
+	"github.com/sefikcan/address-api/internal/apikey/repository"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	// keyPrefix marks api keys so they can be recognized, e.g. by secret scanners
+	keyPrefix = "ak"
+	// lastUsedResolution limits the last-used writes to one per key and interval
+	lastUsedResolution = time.Minute
+)
+
+var (
+	ErrApiKeyNotFound = common.NewError(common.KindNotFound, "api key not found")
+	ErrApiKeyRevoked  = common.NewError(common.KindConflict, "api key is revoked")
+	ErrOwnerRequired  = common.NewError(common.KindValidation, "api key owner is required")
+)
+
+type ApiKeyService interface {
+	Create(ctx context.Context, request request.ApiKeyCreateRequest) (*response.ApiKeySecretResponse, error)
+	GetAll(ctx context.Context, owner string) ([]response.ApiKeyResponse, error)
+	Revoke(ctx context.Context, id int) error
+	Rotate(ctx context.Context, id int) (*response.ApiKeySecretResponse, error)
+	Authenticate(ctx context.Context, rawKey string) (auth.Principal, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepository repository.ApiKeyRepository
+	logger           logger.Logger
+	now              func() time.Time
+}
+
+func (a apiKeyService) Create(ctx context.Context, request request.ApiKeyCreateRequest) (*response.ApiKeySecretResponse, error) {
+	// keys without an explicit owner act on behalf of the admin creating them
+	if request.Owner == "" {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		if !ok || principal.UserId == "" {
+			return nil, ErrOwnerRequired
+		}
+		request.Owner = principal.UserId
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := a.apiKeyRepository.Create(ctx, entity.ApiKey{
+		Name:      request.Name,
+		Owner:     request.Owner,
+		Prefix:    prefix,
+		KeyHash:   hashSecret(secret),
+		Scopes:    strings.Join(request.Scopes, " "),
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapSecretDto(apiKey, formatKey(prefix, secret)), nil
+}
+
+func (a apiKeyService) GetAll(ctx context.Context, owner string) ([]response.ApiKeyResponse, error) {
+	apiKeys, err := a.apiKeyRepository.GetAll(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]response.ApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		dtos = append(dtos, *mapping.MapDto(apiKey))
+	}
+
+	return dtos, nil
+}
+
+func (a apiKeyService) Revoke(ctx context.Context, id int) error {
+	apiKey, err := a.get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// revoking twice keeps the original revocation time
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := a.now()
+	apiKey.RevokedAt = &now
+	_, err = a.apiKeyRepository.Update(ctx, apiKey)
+
+	return err
+}
+
+// Rotate replaces the secret in place, the old secret stops working immediately
+func (a apiKeyService) Rotate(ctx context.Context, id int) (*response.ApiKeySecretResponse, error) {
+	apiKey, err := a.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil, ErrApiKeyRevoked
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hashSecret(secret)
+	apiKey.LastUsedAt = nil
+
+	updated, err := a.apiKeyRepository.Update(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapSecretDto(updated, formatKey(prefix, secret)), nil
+}
+
+func (a apiKeyService) Authenticate(ctx context.Context, rawKey string) (auth.Principal, error) {
+	prefix, secret, ok := parseKey(rawKey)
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidApiKey
+	}
+
+	apiKey, err := a.apiKeyRepository.GetByPrefix(ctx, prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return auth.Principal{}, auth.ErrInvalidApiKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(apiKey.KeyHash)) != 1 {
+		return auth.Principal{}, auth.ErrInvalidApiKey
+	}
+
+	now := a.now()
+	if !apiKey.IsActive(now) {
+		return auth.Principal{}, auth.ErrInvalidApiKey
+	}
+
+	// last-used is informational, a failed write must not reject the request
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		if err := a.apiKeyRepository.UpdateLastUsed(ctx, apiKey.Id, now); err != nil {
+			a.logger.Warnf("Unable to update api key last used, Prefix: %s, Error: %v", apiKey.Prefix, err)
+		}
+	}
+
+	return auth.Principal{
+		UserId:   apiKey.Owner,
+		Scopes:   apiKey.ScopeList(),
+		TenantId: apiKey.TenantId,
+	}, nil
+}
+
+func (a apiKeyService) get(ctx context.Context, id int) (entity.ApiKey, error) {
+	apiKey, err := a.apiKeyRepository.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ApiKey{}, ErrApiKeyNotFound
+	}
+
+	return apiKey, err
+}
+
+// generateKey returns a random lookup prefix and secret
+func generateKey() (string, string, error) {
+	prefix := make([]byte, 8)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(prefix), base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func formatKey(prefix, secret string) string {
+	return keyPrefix + "_" + prefix + "_" + secret
+}
+
+// parseKey splits a raw key of the form ak_<prefix>_<secret>
+func parseKey(rawKey string) (string, string, bool) {
+	parts := strings.SplitN(rawKey, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewApiKeyService(apiKeyRepository repository.ApiKeyRepository, logger logger.Logger) ApiKeyService {
+	return &apiKeyService{
+		apiKeyRepository: apiKeyRepository,
+		logger:           logger,
+		now:              time.Now,
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	mocks2 "github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/apikey/dto/request"
+	"github.com/sefikcan/address-api/internal/apikey/entity"
+	"github.com/sefikcan/address-api/internal/apikey/repository/mocks"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func adminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserId: "admin", Scopes: []string{auth.ScopeAdmin}})
+}
+
+// createKey runs Create against the mock and returns the stored entity together with the raw key
+func createKey(t *testing.T, mockRepo *mocks.ApiKeyRepository, svc ApiKeyService) (entity.ApiKey, string) {
+	var stored entity.ApiKey
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(func(_ context.Context, k entity.ApiKey) (entity.ApiKey, error) {
+		k.Id = 1
+		stored = k
+		return k, nil
+	}).Once()
+
+	resp, err := svc.Create(adminContext(), request.ApiKeyCreateRequest{Name: "batch job", Scopes: []string{auth.ScopeRead}})
+	assert.NoError(t, err)
+
+	return stored, resp.Secret
+}
+
+func TestApiKeyService_CreateStoresOnlyHash(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	stored, secret := createKey(t, mockRepo, svc)
+
+	assert.Contains(t, secret, "ak_"+stored.Prefix+"_")
+	assert.NotContains(t, stored.KeyHash, secret)
+	assert.Len(t, stored.KeyHash, 64)
+	assert.Equal(t, "admin", stored.Owner)
+}
+
+func TestApiKeyService_Authenticate(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	stored, secret := createKey(t, mockRepo, svc)
+	mockRepo.On("GetByPrefix", mock.Anything, stored.Prefix).Return(stored, nil)
+	mockRepo.On("UpdateLastUsed", mock.Anything, 1, mock.Anything).Return(nil).Once()
+
+	principal, err := svc.Authenticate(context.Background(), secret)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", principal.UserId)
+	assert.Equal(t, []string{auth.ScopeRead}, principal.Scopes)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApiKeyService_AuthenticateSkipsRecentLastUsed(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	stored, secret := createKey(t, mockRepo, svc)
+	recently := time.Now().Add(-10 * time.Second)
+	stored.LastUsedAt = &recently
+	mockRepo.On("GetByPrefix", mock.Anything, stored.Prefix).Return(stored, nil)
+
+	_, err := svc.Authenticate(context.Background(), secret)
+
+	assert.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "UpdateLastUsed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApiKeyService_AuthenticateRejectsInvalidKeys(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	stored, secret := createKey(t, mockRepo, svc)
+	past := time.Now().Add(-time.Hour)
+	revoked, expired := stored, stored
+	revoked.Prefix, revoked.RevokedAt = "revoked", &past
+	expired.Prefix, expired.ExpiresAt = "expired", &past
+
+	mockRepo.On("GetByPrefix", mock.Anything, stored.Prefix).Return(stored, nil)
+	mockRepo.On("GetByPrefix", mock.Anything, "revoked").Return(revoked, nil)
+	mockRepo.On("GetByPrefix", mock.Anything, "expired").Return(expired, nil)
+	mockRepo.On("GetByPrefix", mock.Anything, "unknown").Return(entity.ApiKey{}, gorm.ErrRecordNotFound)
+
+	secretPart := secret[len("ak_"+stored.Prefix+"_"):]
+	for name, rawKey := range map[string]string{
+		"malformed":    "not-a-key",
+		"wrong secret": "ak_" + stored.Prefix + "_wrong",
+		"revoked":      "ak_revoked_" + secretPart,
+		"expired":      "ak_expired_" + secretPart,
+		"unknown":      "ak_unknown_" + secretPart,
+	} {
+		_, err := svc.Authenticate(context.Background(), rawKey)
+		assert.ErrorIs(t, err, auth.ErrInvalidApiKey, name)
+	}
+}
+
+func TestApiKeyService_RotateReplacesSecret(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	stored, secret := createKey(t, mockRepo, svc)
+	mockRepo.On("GetById", mock.Anything, 1).Return(stored, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything).Return(func(_ context.Context, k entity.ApiKey) (entity.ApiKey, error) {
+		return k, nil
+	})
+
+	resp, err := svc.Rotate(adminContext(), 1)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, resp.Secret)
+	assert.NotEqual(t, stored.Prefix, resp.Prefix)
+}
+
+func TestApiKeyService_RotateRevokedKey(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	revokedAt := time.Now()
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.ApiKey{Id: 1, RevokedAt: &revokedAt}, nil)
+	mockRepo.On("GetById", mock.Anything, 2).Return(entity.ApiKey{}, gorm.ErrRecordNotFound)
+
+	_, err := svc.Rotate(adminContext(), 1)
+	assert.ErrorIs(t, err, ErrApiKeyRevoked)
+
+	_, err = svc.Rotate(adminContext(), 2)
+	assert.ErrorIs(t, err, ErrApiKeyNotFound)
+}
+
+func TestApiKeyService_Revoke(t *testing.T) {
+	mockRepo := new(mocks.ApiKeyRepository)
+	svc := NewApiKeyService(mockRepo, new(mocks2.Logger))
+
+	mockRepo.On("GetById", mock.Anything, 1).Return(entity.ApiKey{Id: 1}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(k entity.ApiKey) bool {
+		return k.RevokedAt != nil
+	})).Return(entity.ApiKey{}, nil).Once()
+
+	assert.NoError(t, svc.Revoke(adminContext(), 1))
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,170 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	auth "github.com/sefikcan/address-api/internal/auth"
+
+	mock "github.com/stretchr/testify/mock"
+
+	request "github.com/sefikcan/address-api/internal/apikey/dto/request"
+
+	response "github.com/sefikcan/address-api/internal/apikey/dto/response"
+)
+
+// ApiKeyService is an autogenerated mock type for the ApiKeyService type
+type ApiKeyService struct {
+	mock.Mock
+}
+
+// Authenticate provides a mock function with given fields: ctx, rawKey
+func (_m *ApiKeyService) Authenticate(ctx context.Context, rawKey string) (auth.Principal, error) {
+	ret := _m.Called(ctx, rawKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authenticate")
+	}
+
+	var r0 auth.Principal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (auth.Principal, error)); ok {
+		return rf(ctx, rawKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) auth.Principal); ok {
+		r0 = rf(ctx, rawKey)
+	} else {
+		r0 = ret.Get(0).(auth.Principal)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, rawKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, _a1
+func (_m *ApiKeyService) Create(ctx context.Context, _a1 request.ApiKeyCreateRequest) (*response.ApiKeySecretResponse, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *response.ApiKeySecretResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, request.ApiKeyCreateRequest) (*response.ApiKeySecretResponse, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, request.ApiKeyCreateRequest) *response.ApiKeySecretResponse); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.ApiKeySecretResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, request.ApiKeyCreateRequest) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAll provides a mock function with given fields: ctx, owner
+func (_m *ApiKeyService) GetAll(ctx context.Context, owner string) ([]response.ApiKeyResponse, error) {
+	ret := _m.Called(ctx, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []response.ApiKeyResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]response.ApiKeyResponse, error)); ok {
+		return rf(ctx, owner)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []response.ApiKeyResponse); ok {
+		r0 = rf(ctx, owner)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]response.ApiKeyResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revoke provides a mock function with given fields: ctx, id
+func (_m *ApiKeyService) Revoke(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rotate provides a mock function with given fields: ctx, id
+func (_m *ApiKeyService) Rotate(ctx context.Context, id int) (*response.ApiKeySecretResponse, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rotate")
+	}
+
+	var r0 *response.ApiKeySecretResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*response.ApiKeySecretResponse, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *response.ApiKeySecretResponse); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.ApiKeySecretResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewApiKeyService creates a new instance of ApiKeyService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewApiKeyService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ApiKeyService {
+	mock := &ApiKeyService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
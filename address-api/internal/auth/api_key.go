@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidApiKey is returned for unknown, malformed, revoked or expired api keys
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+// ApiKeyAuthenticator resolves the principal of a raw api key
+type ApiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (Principal, error)
+}
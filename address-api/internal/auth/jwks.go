@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"golang.org/x/sync/singleflight"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJwksRefreshInterval = 15 * time.Minute
+	// minJwksRefreshInterval throttles refreshes triggered by unknown key ids, also while the provider is down
+	minJwksRefreshInterval = 10 * time.Second
+)
+
+var (
+	ErrKeyNotFound = errors.New("signing key not found")
+	errUnsupported = errors.New("unsupported key")
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed for RSA and EC verification keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// KeySet caches the public keys of a JWKS document loaded from a file path or an http(s) url
+// Keys are reloaded by Run every refresh interval, and when the cache is stale or an unknown kid is requested
+type KeySet struct {
+	source          string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+	logger          logger.Logger
+	// refreshes shares a fetch between the requests missing a key at the same time
+	refreshes singleflight.Group
+
+	mutex       sync.RWMutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+	// lastAttempt is set by failed refreshes too, so an unreachable provider is not asked on every request
+	lastAttempt time.Time
+}
+
+func NewKeySet(source string, refreshInterval time.Duration, httpClient *http.Client, logger logger.Logger) *KeySet {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJwksRefreshInterval
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return &KeySet{
+		source:          source,
+		refreshInterval: refreshInterval,
+		httpClient:      httpClient,
+		logger:          logger,
+		keys:            map[string]interface{}{},
+	}
+}
+
+// Run refreshes the keys every refresh interval until the context is done
+func (k *KeySet) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Refresh(ctx); err != nil && ctx.Err() == nil {
+				k.logger.Warnf("JWKS could not be refreshed, serving the cached keys: %v", err)
+			}
+		}
+	}
+}
+
+// Key returns the public key for kid, an empty kid matches when the set holds a single key
+func (k *KeySet) Key(ctx context.Context, kid string) (interface{}, error) {
+	k.mutex.RLock()
+	key, found := k.lookup(kid)
+	stale := time.Since(k.lastRefresh) > k.refreshInterval
+	canRefresh := time.Since(k.lastAttempt) > minJwksRefreshInterval
+	k.mutex.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	if canRefresh {
+		if err := k.Refresh(ctx); err != nil {
+			// keep serving cached keys when the provider is temporarily unreachable
+			if found {
+				return key, nil
+			}
+			return nil, err
+		}
+
+		k.mutex.RLock()
+		key, found = k.lookup(kid)
+		k.mutex.RUnlock()
+	}
+
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// Refresh reloads the key set from its source, concurrent calls share one fetch
+func (k *KeySet) Refresh(ctx context.Context) error {
+	// the shared fetch must not fail because the request that started it went away
+	_, err, _ := k.refreshes.Do("refresh", func() (interface{}, error) {
+		err := k.refresh(context.WithoutCancel(ctx))
+
+		k.mutex.Lock()
+		k.lastAttempt = time.Now()
+		k.mutex.Unlock()
+
+		return nil, err
+	})
+
+	return err
+}
+
+func (k *KeySet) refresh(ctx context.Context) error {
+	document, err := k.load(ctx)
+	if err != nil {
+		return errors.Wrap(err, "KeySet.Refresh.Load")
+	}
+
+	var set jsonWebKeySet
+	if err := json.Unmarshal(document, &set); err != nil {
+		return errors.Wrap(err, "KeySet.Refresh.Decode")
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if errors.Is(err, errUnsupported) {
+			// providers publish keys for other algorithms too, they can not sign tokens for this api
+			k.logger.Warnf("JWKS key %s is skipped: %v", jwk.Kid, err)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "KeySet.Refresh.Key(%s)", jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	k.mutex.Lock()
+	k.keys = keys
+	k.lastRefresh = time.Now()
+	k.mutex.Unlock()
+
+	return nil
+}
+
+func (k *KeySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+
+	key, found := k.keys[kid]
+	return key, found
+}
+
+func (k *KeySet) load(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(k.source, "http://") && !strings.HasPrefix(k.source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(k.source, "file://"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (j jsonWebKey) publicKey() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %q", errUnsupported, j.Crv)
+		}
+
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("%w: key type %q", errUnsupported, j.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func rsaJwk(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJwk(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func TestKeySet_LoadsKeysFromFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	document, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{rsaJwk("rsa-1", &rsaKey.PublicKey), ecJwk("ec-1", &ecKey.PublicKey)},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, document, 0o600))
+
+	keySet := NewKeySet(path, 0, nil, new(mocks.Logger))
+
+	key, err := keySet.Key(context.Background(), "rsa-1")
+	assert.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(key))
+
+	key, err = keySet.Key(context.Background(), "ec-1")
+	assert.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	_, err = keySet.Key(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeySet_PicksUpRotatedKeys(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var mutex sync.Mutex
+	current := []map[string]string{rsaJwk("old", &oldKey.PublicKey)}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": current})
+	}))
+	defer server.Close()
+
+	keySet := NewKeySet(server.URL, 0, server.Client(), new(mocks.Logger))
+
+	_, err := keySet.Key(context.Background(), "old")
+	assert.NoError(t, err)
+
+	// cached keys are served without hitting the provider again
+	_, err = keySet.Key(context.Background(), "old")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	mutex.Lock()
+	current = []map[string]string{rsaJwk("new", &newKey.PublicKey)}
+	mutex.Unlock()
+
+	// refresh is throttled, a forced refresh picks up the rotated key
+	assert.NoError(t, keySet.Refresh(context.Background()))
+
+	key, err := keySet.Key(context.Background(), "new")
+	assert.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(key))
+}
+
+func TestKeySet_SkipsUnsupportedKeys(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	document, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "oct", "kid": "hmac-1", "k": "c2VjcmV0"},
+			{"kty": "EC", "kid": "ec-224", "crv": "P-224", "x": "AA", "y": "AA"},
+			rsaJwk("rsa-1", &rsaKey.PublicKey),
+		},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, document, 0o600))
+
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything).Return()
+	keySet := NewKeySet(path, 0, nil, mockLogger)
+
+	key, err := keySet.Key(context.Background(), "rsa-1")
+	assert.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(key))
+	mockLogger.AssertNumberOfCalls(t, "Warnf", 2)
+}
+
+func TestKeySet_ThrottlesRefreshWhileProviderIsDown(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	keySet := NewKeySet(server.URL, 0, server.Client(), new(mocks.Logger))
+
+	_, err := keySet.Key(context.Background(), "kid-1")
+	assert.Error(t, err)
+
+	// the failed attempt counts, unknown kids do not hit the provider again right away
+	_, err = keySet.Key(context.Background(), "kid-2")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestKeySet_ConcurrentMissesShareOneFetch(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{rsaJwk("rsa-1", &rsaKey.PublicKey)}})
+	}))
+	defer server.Close()
+
+	keySet := NewKeySet(server.URL, 0, server.Client(), new(mocks.Logger))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := keySet.Key(context.Background(), "rsa-1")
+			assert.NoError(t, err)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestKeySet_RunRefreshesPeriodically(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{}})
+	}))
+	defer server.Close()
+
+	keySet := NewKeySet(server.URL, 10*time.Millisecond, server.Client(), new(mocks.Logger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keySet.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) >= 2 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,51 @@
+package auth
+
+import "context"
+
+const (
+	ScopeRead   = "address:read"
+	ScopeWrite  = "address:write"
+	ScopeDelete = "address:delete"
+	// ScopeAdmin allows acting on addresses of every user and implies every other address scope
+	ScopeAdmin = "address:admin"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserId   string
+	Scopes   []string
+	TenantId string
+}
+
+// HasScope reports whether the principal was granted the scope, admins are granted every scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal may act across users
+func (p Principal) IsAdmin() bool {
+	return p.HasScope(ScopeAdmin)
+}
+
+// CanAccess reports whether the principal may act on a resource owned by userId
+func (p Principal) CanAccess(userId string) bool {
+	return p.IsAdmin() || (p.UserId != "" && p.UserId == userId)
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by the authentication middleware
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCursorCodec_RoundTrip(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	cursor := Cursor{Field: "createdAt", Desc: true, Value: "2024-01-02T03:04:05.123456Z", Id: 42, Before: true}
+
+	decoded, err := codec.Decode(codec.Encode(cursor))
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+}
+
+func TestCursorCodec_RejectsTamperedToken(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	token := codec.Encode(Cursor{Field: "id", Id: 10})
+
+	forged := NewCursorCodec("other").Encode(Cursor{Field: "id", Id: 1000})
+
+	_, err := codec.Decode(forged)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = codec.Decode(token[:len(token)-2])
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = codec.Decode("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net"
+)
+
+// ErrorKind classifies domain errors, the http layer maps every kind to a status code
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindForbidden
+	KindUnavailable
+)
+
+// DomainError carries a client safe message, the cause is only meant for logs
+type DomainError struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+func (e *DomainError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches sentinel domain errors by kind and message so wrapped copies still match them
+func (e *DomainError) Is(target error) bool {
+	t, ok := target.(*DomainError)
+	return ok && t.Err == nil && t.Kind == e.Kind && t.Message == e.Message
+}
+
+// NewError returns a domain error without cause, suitable as sentinel
+func NewError(kind ErrorKind, message string) *DomainError {
+	return &DomainError{Kind: kind, Message: message}
+}
+
+// Wrap attaches a cause to the domain error
+func (e *DomainError) Wrap(err error) error {
+	return &DomainError{Kind: e.Kind, Message: e.Message, Err: err}
+}
+
+func NotFound(message string, err error) error {
+	return &DomainError{Kind: KindNotFound, Message: message, Err: err}
+}
+
+func Conflict(message string, err error) error {
+	return &DomainError{Kind: KindConflict, Message: message, Err: err}
+}
+
+func Validation(message string, err error) error {
+	return &DomainError{Kind: KindValidation, Message: message, Err: err}
+}
+
+func Forbidden(message string, err error) error {
+	return &DomainError{Kind: KindForbidden, Message: message, Err: err}
+}
+
+func Unavailable(message string, err error) error {
+	return &DomainError{Kind: KindUnavailable, Message: message, Err: err}
+}
+
+// KindOf returns the kind of the first domain error in the chain, KindInternal otherwise
+func KindOf(err error) ErrorKind {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return KindInternal
+}
+
+// DbError classifies a gorm error and wraps it with the failing operation, e.g. "addressRepository.GetById"
+func DbError(err error, operation string) error {
+	wrapped := fmt.Errorf("%s.DbError: %w", operation, err)
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound("record not found", wrapped)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict("record already exists", wrapped)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, driver.ErrBadConn), errors.As(err, &netErr):
+		return Unavailable("database is unavailable", wrapped)
+	default:
+		return wrapped
+	}
+}
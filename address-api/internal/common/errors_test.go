@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestDbError(t *testing.T) {
+	assert.Equal(t, KindNotFound, KindOf(DbError(gorm.ErrRecordNotFound, "repo.GetById")))
+	assert.Equal(t, KindConflict, KindOf(DbError(gorm.ErrDuplicatedKey, "repo.Create")))
+	assert.Equal(t, KindUnavailable, KindOf(DbError(context.DeadlineExceeded, "repo.GetAll")))
+	assert.Equal(t, KindInternal, KindOf(DbError(errors.New("syntax error"), "repo.GetAll")))
+
+	// the cause stays reachable for callers and logs
+	err := DbError(gorm.ErrRecordNotFound, "repo.GetById")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Contains(t, err.Error(), "repo.GetById.DbError")
+}
+
+func TestDomainError_IsSentinel(t *testing.T) {
+	sentinel := NewError(KindNotFound, "address not found")
+
+	assert.ErrorIs(t, sentinel.Wrap(gorm.ErrRecordNotFound), sentinel)
+	assert.NotErrorIs(t, NotFound("api key not found", nil), sentinel)
+	assert.Equal(t, KindNotFound, KindOf(sentinel))
+}
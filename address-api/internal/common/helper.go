@@ -7,3 +7,12 @@ type Pageable[T any] struct {
 	CurrentPage int   `json:"currentPage"`
 	PageSize    int   `json:"pageSize"`
 }
+
+// CursorPageable is the keyset paginated counterpart of Pageable, TotalItems is only filled when requested
+type CursorPageable[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	PageSize   int    `json:"pageSize"`
+	TotalItems *int64 `json:"totalItems,omitempty"`
+}
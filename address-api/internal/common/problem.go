@@ -0,0 +1,51 @@
+package common
+
+import "encoding/json"
+
+const (
+	// ProblemContentType is the media type of RFC 7807 error responses
+	ProblemContentType = "application/problem+json"
+	// ProblemTypeBlank is used when the status code and title describe the problem sufficiently
+	ProblemTypeBlank = "about:blank"
+	// ProblemTypeValidation problems carry the failed fields in Errors
+	ProblemTypeValidation = "/problems/validation-error"
+)
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestId string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Extensions are additional members serialized next to the standard ones
+	Extensions map[string]interface{} `json:"-" swaggerignore:"true"`
+}
+
+// FieldError describes a single failed validation rule, Field is the json or query name of the field
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	// alias drops the MarshalJSON method so the standard members are encoded as usual
+	type alias Problem
+	body, err := json.Marshal(alias(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+
+	members := map[string]interface{}{}
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
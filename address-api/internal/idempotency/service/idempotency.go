@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/address-api/internal/tenant"
 	"time"
 )
 
@@ -17,7 +18,7 @@ type idempotencyService struct {
 }
 
 func (i idempotencyService) GetByKey(ctx context.Context, key string) (interface{}, error) {
-	result, err := i.redisClient.Get(ctx, key).Result()
+	result, err := i.redisClient.Get(ctx, tenant.Key(ctx, key)).Result()
 	if err == redis.Nil {
 		return nil, nil
 	} else if err != nil {
@@ -34,7 +35,8 @@ func (i idempotencyService) GetByKey(ctx context.Context, key string) (interface
 
 func (i idempotencyService) Save(ctx context.Context, key string, result []byte) error {
 	expiration := time.Hour // TODO: Move configuration or another constant
-	return i.redisClient.Set(ctx, key, result, expiration).Err()
+	// keys are tenant prefixed, equal keys sent by two tenants must not share a result
+	return i.redisClient.Set(ctx, tenant.Key(ctx, key), result, expiration).Err()
 }
 
 func NewIdempotencyService(redisClient *redis.Client) IdempotencyService {
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/auth"
+)
+
+const HeaderApiKey = "X-API-Key"
+
+// ApiKeyAuthentication protects routes for server-to-server clients sending the X-API-Key header
+func (mw Manager) ApiKeyAuthentication(authenticator auth.ApiKeyAuthenticator) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return mw.apiKeyAuthentication(ctx, authenticator)
+	}
+}
+
+// JwtOrApiKeyAuthentication accepts either a Bearer JWT or an api key
+// The Authorization header wins when both are sent so a user token is never downgraded to a key
+func (mw Manager) JwtOrApiKeyAuthentication(authenticator auth.ApiKeyAuthenticator) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Get(fiber.HeaderAuthorization) == "" && ctx.Get(HeaderApiKey) != "" {
+			return mw.apiKeyAuthentication(ctx, authenticator)
+		}
+
+		return mw.jwtAuthentication(ctx)
+	}
+}
+
+func (mw Manager) apiKeyAuthentication(ctx *fiber.Ctx, authenticator auth.ApiKeyAuthenticator) error {
+	rawKey := ctx.Get(HeaderApiKey)
+	if rawKey == "" {
+		mw.logger.Warn("Missing api key")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Missing api key")
+	}
+
+	principal, err := authenticator.Authenticate(ctx.UserContext(), rawKey)
+	if errors.Is(err, auth.ErrInvalidApiKey) {
+		mw.logger.Info("Invalid, revoked or expired api key")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Invalid or expired api key")
+	}
+	if err != nil {
+		mw.logger.Errorf("Api key authentication error: %v", err)
+
+		return Problem(ctx, fiber.StatusInternalServerError, "Unable to authenticate api key")
+	}
+
+	if !mw.bindTenant(ctx, &principal) {
+		return Problem(ctx, fiber.StatusForbidden, "Api key is not valid for the requested tenant")
+	}
+	ctx.SetUserContext(auth.WithPrincipal(ctx.UserContext(), principal))
+
+	return ctx.Next()
+}
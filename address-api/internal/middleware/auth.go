@@ -1,69 +1,196 @@
 package middleware
 
 import (
+	"context"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/internal/auth"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Authentication to protect routes
 func (mw Manager) Authentication() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		// Get the token from the Authorization header
-		authHeader := ctx.Get("Authorization")
-		if authHeader == "" {
-			mw.logger.Warn("Missing or malformed token")
-
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing or malformed token",
-			})
+		return mw.jwtAuthentication(ctx)
+	}
+}
+
+// jwtAuthentication validates the Bearer token and stores its principal in the user context
+func (mw Manager) jwtAuthentication(ctx *fiber.Ctx) error {
+	// Get the token from the Authorization header
+	authHeader := ctx.Get("Authorization")
+	if authHeader == "" {
+		mw.logger.Warn("Missing or malformed token")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Missing or malformed token")
+	}
+
+	// Check if the token format is `Bearer <token>`
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		mw.logger.Info("Invalid token format")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Invalid token format")
+	}
+
+	tokenStr := parts[1]
+	// Parse the token, signature algorithm, exp/nbf, issuer and audience are validated by the parser
+	token, err := jwt.Parse(tokenStr, mw.signingKey(ctx.UserContext()), mw.parserOptions()...)
+
+	if err != nil || !token.Valid {
+		mw.logger.Infof("Invalid or expired token: %v", err)
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	// Token is valid; set user info in context if needed
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		mw.logger.Info("Invalid or expired token")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Failed to parse token claims")
+	}
+
+	userId := claimString(claims, "user_id")
+	if userId == "" {
+		mw.logger.Info("Token has no user_id claim")
+
+		return Problem(ctx, fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	// handlers read the principal with GetPrincipal, services from the user context
+	principal := auth.Principal{
+		UserId:   userId,
+		Scopes:   scopesFromClaims(claims),
+		TenantId: claimString(claims, "tenant_id"),
+	}
+	if !mw.bindTenant(ctx, &principal) {
+		return Problem(ctx, fiber.StatusForbidden, "Token is not valid for the requested tenant")
+	}
+	ctx.SetUserContext(auth.WithPrincipal(ctx.UserContext(), principal))
+
+	return ctx.Next()
+}
+
+// RequireScopes allows the request only when the authenticated principal has every given scope
+// It must be mounted after Authentication
+func (mw Manager) RequireScopes(scopes ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		principal, ok := GetPrincipal(ctx)
+		if !ok {
+			return Problem(ctx, fiber.StatusUnauthorized, "Missing or malformed token")
 		}
 
-		// Check if the token format is `Bearer <token>`
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			mw.logger.Info("Invalid token format")
+		var missing []string
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				missing = append(missing, scope)
+			}
+		}
 
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token format",
-			})
+		if len(missing) > 0 {
+			mw.logger.Infof("Insufficient scope, UserId: %s, Missing: %v", principal.UserId, missing)
+
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+			problem := NewProblem(ctx, fiber.StatusForbidden, "Insufficient scope")
+			problem.Extensions = map[string]interface{}{
+				"reason":         "insufficient_scope",
+				"requiredScopes": scopes,
+				"missingScopes":  missing,
+			}
+			return SendProblem(ctx, problem)
 		}
 
-		tokenStr := parts[1]
-		// Parse the token
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing algorithm
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				mw.logger.Error("unexpected signing method")
+		return ctx.Next()
+	}
+}
+
+// GetPrincipal returns the principal resolved by the authentication middleware
+func GetPrincipal(ctx *fiber.Ctx) (auth.Principal, bool) {
+	return auth.PrincipalFromContext(ctx.UserContext())
+}
 
-				return nil, errors.New("unexpected signing method")
+// signingKey resolves the verification key, HMAC tokens use the shared secret and
+// RSA/ECDSA tokens the JWKS key referenced by the kid header
+func (mw Manager) signingKey(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if mw.cfg.Auth.JwtSecret == "" {
+				return nil, errors.New("hmac tokens are not accepted")
 			}
 			// Return the signing key
 			return []byte(mw.cfg.Auth.JwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			mw.logger.Info("Invalid or expired token")
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+			if mw.keySet == nil {
+				return nil, errors.New("no jwks configured for asymmetric tokens")
+			}
+			kid, _ := token.Header["kid"].(string)
+			return mw.keySet.Key(ctx, kid)
+		default:
+			mw.logger.Error("unexpected signing method")
 
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
+			return nil, errors.New("unexpected signing method")
 		}
+	}
+}
 
-		// Token is valid; set user info in context if needed
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			mw.logger.Info("Invalid or expired token")
+func (mw Manager) parserOptions() []jwt.ParserOption {
+	algorithms := mw.cfg.Auth.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{jwt.SigningMethodHS256.Alg()}
+	}
 
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Failed to parse token claims",
-			})
-		}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(mw.cfg.Auth.ClockSkew * time.Second),
+	}
+	if mw.cfg.Auth.Issuer != "" {
+		options = append(options, jwt.WithIssuer(mw.cfg.Auth.Issuer))
+	}
+	if mw.cfg.Auth.Audience != "" {
+		options = append(options, jwt.WithAudience(mw.cfg.Auth.Audience))
+	}
 
-		// You can set the user ID in the context to access it in handlers
-		ctx.Locals("userID", claims["user_id"])
+	return options
+}
 
-		return ctx.Next()
+// claimString returns a string or numeric claim as string
+func claimString(claims jwt.MapClaims, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
 	}
 }
+
+// scopesFromClaims reads the OAuth2 style space separated `scope` claim or a `scopes`/`scp` array
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	for _, name := range []string{"scopes", "scp"} {
+		switch value := claims[name].(type) {
+		case string:
+			return strings.Fields(value)
+		case []interface{}:
+			var scopes []string
+			for _, v := range value {
+				if s, ok := v.(string); ok {
+					scopes = append(scopes, s)
+				}
+			}
+			return scopes
+		}
+	}
+
+	return nil
+}
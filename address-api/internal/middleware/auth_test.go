@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testJwtSecret = "test_secret"
+
+// testTenantConfig binds credentials without tenant claim to the default tenant
+var testTenantConfig = config.TenantConfig{Default: "default"}
+
+func newAuthTestApp(t *testing.T) (*fiber.App, *auth.Principal) {
+	return newAuthTestAppWithConfig(t, config.AuthenticationConfig{JwtSecret: testJwtSecret})
+}
+
+func newAuthTestAppWithConfig(t *testing.T, authConfig config.AuthenticationConfig) (*fiber.App, *auth.Principal) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Info", mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warn", mock.Anything).Maybe()
+	mockLogger.On("Error", mock.Anything).Maybe()
+
+	manager := NewMiddlewareManager(&config.Config{Auth: authConfig, Tenant: testTenantConfig}, mockLogger)
+
+	var captured auth.Principal
+	app := fiber.New()
+	app.Get("/", manager.Authentication(), func(c *fiber.Ctx) error {
+		captured, _ = auth.PrincipalFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	return app, &captured
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJwtSecret))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestAuthentication_RejectsMissingToken(t *testing.T) {
+	app, _ := newAuthTestApp(t)
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAuthentication_SetsPrincipal(t *testing.T) {
+	app, captured := newAuthTestApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{
+		"user_id": "42",
+		"scope":   "address:read address:admin",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}))
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "42", captured.UserId)
+	assert.True(t, captured.IsAdmin())
+	assert.Equal(t, []string{"address:read", "address:admin"}, captured.Scopes)
+}
+
+func TestAuthentication_RejectsTokenWithoutUser(t *testing.T) {
+	app, _ := newAuthTestApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()}))
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// newJwksServer is a local stand-in for the identity provider JWKS endpoint
+func newJwksServer(t *testing.T, rsaKey *rsa.PublicKey, ecKey *ecdsa.PublicKey) string {
+	document, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-key",
+				"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-key",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(document)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestAuthentication_AsymmetricTokensFromJwks(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	app, captured := newAuthTestAppWithConfig(t, config.AuthenticationConfig{
+		Algorithms: []string{"RS256", "ES256"},
+		JwksSource: newJwksServer(t, &rsaKey.PublicKey, &ecKey.PublicKey),
+		Issuer:     "https://idp.example.com",
+		Audience:   "address-api",
+		ClockSkew:  30,
+	})
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return signed
+	}
+	validClaims := func(userId string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"user_id": userId,
+			"iss":     "https://idp.example.com",
+			"aud":     "address-api",
+			"exp":     time.Now().Add(time.Minute).Unix(),
+		}
+	}
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, validClaims("rsa-user"))))
+	assert.Equal(t, "rsa-user", captured.UserId)
+
+	assert.Equal(t, http.StatusOK, request(sign(jwt.SigningMethodES256, "ec-key", ecKey, validClaims("ec-user"))))
+	assert.Equal(t, "ec-user", captured.UserId)
+
+	// expired within the clock skew is still accepted
+	claims := validClaims("1")
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	assert.Equal(t, http.StatusOK, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims)))
+
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims)))
+
+	claims = validClaims("1")
+	claims["nbf"] = time.Now().Add(time.Minute).Unix()
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims)))
+
+	claims = validClaims("1")
+	claims["iss"] = "https://evil.example.com"
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims)))
+
+	claims = validClaims("1")
+	claims["aud"] = "other-api"
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims)))
+
+	// a key that is not in the jwks, and HMAC which is not an allowed algorithm
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodRS256, "rsa-key", otherKey, validClaims("1"))))
+	assert.Equal(t, http.StatusUnauthorized, request(sign(jwt.SigningMethodHS256, "rsa-key", []byte("secret"), validClaims("1"))))
+}
+
+func TestRequireScopes(t *testing.T) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	manager := NewMiddlewareManager(&config.Config{Auth: config.AuthenticationConfig{JwtSecret: testJwtSecret}, Tenant: testTenantConfig}, mockLogger)
+
+	app := fiber.New()
+	app.Delete("/", manager.Authentication(), manager.RequireScopes(auth.ScopeDelete), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	request := func(scope string) *http.Response {
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+signHS256(t, jwt.MapClaims{
+			"user_id": "1",
+			"scope":   scope,
+			"exp":     time.Now().Add(time.Minute).Unix(),
+		}))
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	resp := request("address:read address:write")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, "insufficient_scope", body["reason"])
+	assert.Equal(t, []interface{}{"address:delete"}, body["missingScopes"])
+
+	assert.Equal(t, http.StatusNoContent, request("address:delete").StatusCode)
+	assert.Equal(t, http.StatusNoContent, request("address:admin").StatusCode)
+}
+
+type stubApiKeyAuthenticator struct {
+	principal auth.Principal
+}
+
+func (s stubApiKeyAuthenticator) Authenticate(_ context.Context, rawKey string) (auth.Principal, error) {
+	if rawKey != "ak_valid_secret" {
+		return auth.Principal{}, auth.ErrInvalidApiKey
+	}
+	return s.principal, nil
+}
+
+func TestJwtOrApiKeyAuthentication(t *testing.T) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Info", mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warn", mock.Anything).Maybe()
+
+	manager := NewMiddlewareManager(&config.Config{Auth: config.AuthenticationConfig{JwtSecret: testJwtSecret}, Tenant: testTenantConfig}, mockLogger)
+	authenticator := stubApiKeyAuthenticator{principal: auth.Principal{UserId: "batch", Scopes: []string{auth.ScopeRead}}}
+
+	var captured auth.Principal
+	app := fiber.New()
+	app.Get("/", manager.JwtOrApiKeyAuthentication(authenticator), func(c *fiber.Ctx) error {
+		captured, _ = auth.PrincipalFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	token := signHS256(t, jwt.MapClaims{"user_id": "42", "exp": time.Now().Add(time.Minute).Unix()})
+
+	tests := []struct {
+		name       string
+		bearer     string
+		apiKey     string
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "api key", apiKey: "ak_valid_secret", wantStatus: http.StatusOK, wantUser: "batch"},
+		{name: "invalid api key", apiKey: "ak_other_secret", wantStatus: http.StatusUnauthorized},
+		{name: "bearer token", bearer: token, wantStatus: http.StatusOK, wantUser: "42"},
+		{name: "bearer wins over api key", bearer: token, apiKey: "ak_valid_secret", wantStatus: http.StatusOK, wantUser: "42"},
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured = auth.Principal{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(HeaderApiKey, tt.apiKey)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantUser, captured.UserId)
+		})
+	}
+}
@@ -11,11 +11,9 @@ func (mw *Manager) DistributedRateLimitMiddleware(dtb *ratelimiter.DistributedTo
 		clientIP := ctx.IP()
 		key := clientIP
 		// check if we can request a token from distributed token bucket structure
-		if !dtb.AllowRequest(ctx.Context(), key, 1) {
+		if !dtb.AllowRequest(ctx.UserContext(), key, 1) {
 			// if token not found, return HTTP 429(Too many requests)
-			return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "Rate limit exceeded",
-			})
+			return Problem(ctx, fiber.StatusTooManyRequests, "Rate limit exceeded")
 		}
 		// if token exist, we move on the next middleware
 		return ctx.Next()
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"github.com/sefikcan/address-api/pkg/util"
+)
+
+// kindStatus maps the domain error kinds to http status codes
+var kindStatus = map[common.ErrorKind]int{
+	common.KindNotFound:    fiber.StatusNotFound,
+	common.KindConflict:    fiber.StatusConflict,
+	common.KindValidation:  fiber.StatusBadRequest,
+	common.KindForbidden:   fiber.StatusForbidden,
+	common.KindUnavailable: fiber.StatusServiceUnavailable,
+}
+
+// ErrorStatus returns the status code and the client safe message of err
+// Errors that are neither fiber nor domain errors are reported without details
+func ErrorStatus(err error) (int, string) {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code, fiberErr.Message
+	}
+
+	var domainErr *common.DomainError
+	if errors.As(err, &domainErr) {
+		if status, ok := kindStatus[domainErr.Kind]; ok {
+			return status, domainErr.Message
+		}
+	}
+
+	return fiber.StatusInternalServerError, "An unexpected error occurred"
+}
+
+// ErrorHandler is the fiber error handler, every error returned by a handler is answered here as problem+json
+func ErrorHandler(logger logger.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		status, message := ErrorStatus(err)
+		if status >= fiber.StatusInternalServerError {
+			util.PrepareLogging(c, logger, err)
+		}
+
+		return Problem(c, status, message)
+	}
+}
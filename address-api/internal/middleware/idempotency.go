@@ -9,16 +9,14 @@ func (mw *Manager) IdempotencyMiddleware(idempotencyService idempotency.Idempote
 	return func(ctx *fiber.Ctx) error {
 		idempotencyKey := ctx.Get("idempotent-Key")
 		if idempotencyKey == "" {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "idempotent-Key header is required",
-			})
+			return Problem(ctx, fiber.StatusBadRequest, "Missing idempotent-Key header")
 		}
 
-		_, err := idempotencyService.GetByKey(ctx.Context(), idempotencyKey)
+		_, err := idempotencyService.GetByKey(ctx.UserContext(), idempotencyKey)
 		err = ctx.Next()
 		if err == nil {
 			response := ctx.Response().Body()
-			if err := idempotencyService.Save(ctx.Context(), idempotencyKey, response); err != nil {
+			if err := idempotencyService.Save(ctx.UserContext(), idempotencyKey, response); err != nil {
 				mw.logger.Info("Error saving idempotency result: ", err)
 			}
 		}
@@ -1,18 +1,38 @@
 package middleware
 
 import (
+	"context"
+	"github.com/sefikcan/address-api/internal/auth"
 	"github.com/sefikcan/address-api/pkg/config"
 	"github.com/sefikcan/address-api/pkg/logger"
+	"time"
 )
 
 type Manager struct {
 	cfg    *config.Config
 	logger logger.Logger
+	keySet *auth.KeySet
 }
 
 func NewMiddlewareManager(cfg *config.Config, logger logger.Logger) *Manager {
-	return &Manager{
+	manager := &Manager{
 		cfg:    cfg,
 		logger: logger,
 	}
+
+	// asymmetric tokens are verified with the public keys of the identity provider
+	if cfg.Auth.JwksSource != "" {
+		manager.keySet = auth.NewKeySet(cfg.Auth.JwksSource, cfg.Auth.JwksRefreshInterval*time.Second, nil, logger)
+	}
+
+	return manager
+}
+
+// RefreshKeys keeps the keys of the identity provider fresh until the context is done
+func (mw *Manager) RefreshKeys(ctx context.Context) {
+	if mw.keySet == nil {
+		return
+	}
+
+	mw.keySet.Run(ctx)
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/pkg/util"
+	"net/http"
+)
+
+// NewProblem builds the problem details of the current request
+func NewProblem(c *fiber.Ctx, status int, detail string) *common.Problem {
+	return &common.Problem{
+		Type:      common.ProblemTypeBlank,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.OriginalURL(),
+		RequestId: util.GetRequestId(c),
+	}
+}
+
+// SendProblem writes the problem as application/problem+json
+func SendProblem(c *fiber.Ctx, problem *common.Problem) error {
+	return c.Status(problem.Status).JSON(problem, common.ProblemContentType)
+}
+
+// Problem answers the request with a problem of the given status
+func Problem(c *fiber.Ctx, status int, detail string) error {
+	return SendProblem(c, NewProblem(c, status, detail))
+}
@@ -9,9 +9,7 @@ func (mw *Manager) RateLimitMiddleware(tb *ratelimiter.TokenBucket) fiber.Handle
 	return func(ctx *fiber.Ctx) error {
 		// check 1 token in bucket
 		if !tb.AllowRequest(1) {
-			return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "Rate limit exceeded",
-			})
+			return Problem(ctx, fiber.StatusTooManyRequests, "Rate limit exceeded")
 		}
 		return ctx.Next()
 	}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/pkg/util"
+)
+
+// RequestContext makes the request id and the W3C trace context available to services through the user context,
+// requests without a valid traceparent start a new trace. It must be mounted after the requestid middleware
+func (mw Manager) RequestContext(c *fiber.Ctx) error {
+	traceContext := util.TraceContext{
+		Traceparent: c.Get(util.HeaderTraceparent),
+		Tracestate:  c.Get(util.HeaderTracestate),
+	}
+	if !util.IsValidTraceparent(traceContext.Traceparent) {
+		// tracestate is meaningless without the trace it belongs to
+		traceContext = util.TraceContext{Traceparent: util.NewTraceparent()}
+	}
+
+	c.SetUserContext(util.WithTraceContext(util.GetRequestCtx(c), traceContext))
+	return c.Next()
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/sefikcan/address-api/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestContext(t *testing.T) {
+	manager := NewMiddlewareManager(&config.Config{}, nil)
+
+	var requestId string
+	var traceContext util.TraceContext
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Get("/", manager.RequestContext, func(c *fiber.Ctx) error {
+		requestId = util.RequestIdFromContext(c.UserContext())
+		traceContext = util.TraceContextFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-1")
+	req.Header.Set(util.HeaderTraceparent, traceparent)
+	req.Header.Set(util.HeaderTracestate, "vendor=value")
+	_, _ = app.Test(req)
+
+	assert.Equal(t, "req-1", requestId)
+	assert.Equal(t, util.TraceContext{Traceparent: traceparent, Tracestate: "vendor=value"}, traceContext)
+
+	// an invalid traceparent starts a new trace and drops the tracestate
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(util.HeaderTraceparent, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	req.Header.Set(util.HeaderTracestate, "vendor=value")
+	_, _ = app.Test(req)
+
+	assert.NotEmpty(t, requestId)
+	assert.True(t, util.IsValidTraceparent(traceContext.Traceparent))
+	assert.NotEqual(t, traceparent, traceContext.Traceparent)
+	assert.Empty(t, traceContext.Tracestate)
+}
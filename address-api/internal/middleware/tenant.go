@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/internal/tenant"
+)
+
+// Tenant resolves the tenant from the X-Tenant-ID header or the configured default
+// Authentication replaces it with the tenant of the credentials
+func (mw Manager) Tenant() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		tenantId := ctx.Get(tenant.HeaderTenantId)
+		if tenantId == "" {
+			tenantId = mw.cfg.Tenant.Default
+		}
+
+		if !tenant.IsValid(tenantId) {
+			mw.logger.Infof("Invalid or missing tenant: %q", tenantId)
+
+			return Problem(ctx, fiber.StatusBadRequest, "Invalid or missing "+tenant.HeaderTenantId+" header")
+		}
+
+		ctx.SetUserContext(tenant.WithTenant(ctx.UserContext(), tenantId))
+
+		return ctx.Next()
+	}
+}
+
+// bindTenant makes the tenant of the credentials authoritative, credentials without a tenant act in the
+// default tenant and are rejected without one. The header can only narrow the tenant, never choose it
+func (mw Manager) bindTenant(ctx *fiber.Ctx, principal *auth.Principal) bool {
+	if principal.TenantId == "" {
+		principal.TenantId = mw.cfg.Tenant.Default
+	}
+	if principal.TenantId == "" {
+		mw.logger.Infof("Credentials without tenant rejected, UserId: %s", principal.UserId)
+		return false
+	}
+
+	if header := ctx.Get(tenant.HeaderTenantId); header != "" && header != principal.TenantId {
+		mw.logger.Infof("Tenant mismatch, UserId: %s, Header: %s, Claim: %s", principal.UserId, header, principal.TenantId)
+		return false
+	}
+
+	ctx.SetUserContext(tenant.WithTenant(ctx.UserContext(), principal.TenantId))
+	return true
+}
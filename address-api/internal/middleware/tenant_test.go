@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTenant(t *testing.T) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Info", mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+
+	manager := NewMiddlewareManager(&config.Config{
+		Auth:   config.AuthenticationConfig{JwtSecret: testJwtSecret},
+		Tenant: config.TenantConfig{Default: "default"},
+	}, mockLogger)
+
+	var capturedTenant string
+	var capturedPrincipal auth.Principal
+	app := fiber.New()
+	app.Use(manager.Tenant())
+	app.Get("/", manager.Authentication(), func(c *fiber.Ctx) error {
+		capturedTenant, _ = tenant.FromContext(c.UserContext())
+		capturedPrincipal, _ = auth.PrincipalFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	withTenant := signHS256(t, jwt.MapClaims{"user_id": "42", "tenant_id": "brand-a", "exp": time.Now().Add(time.Minute).Unix()})
+	withoutTenant := signHS256(t, jwt.MapClaims{"user_id": "42", "exp": time.Now().Add(time.Minute).Unix()})
+
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{name: "claim", token: withTenant, wantStatus: http.StatusOK, wantTenant: "brand-a"},
+		{name: "claim and matching header", token: withTenant, header: "brand-a", wantStatus: http.StatusOK, wantTenant: "brand-a"},
+		{name: "claim and other header", token: withTenant, header: "brand-b", wantStatus: http.StatusForbidden},
+		{name: "foreign header without claim", token: withoutTenant, header: "brand-b", wantStatus: http.StatusForbidden},
+		{name: "default header without claim", token: withoutTenant, header: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "default", token: withoutTenant, wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "invalid header", token: withoutTenant, header: "brand:b", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capturedTenant, capturedPrincipal = "", auth.Principal{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.header != "" {
+				req.Header.Set(tenant.HeaderTenantId, tt.header)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantTenant, capturedTenant)
+			assert.Equal(t, tt.wantTenant, capturedPrincipal.TenantId)
+		})
+	}
+}
+
+func TestTenant_CredentialsWithoutTenantCanNotChooseOne(t *testing.T) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	adminToken := signHS256(t, jwt.MapClaims{"user_id": "1", "scope": auth.ScopeAdmin, "exp": time.Now().Add(time.Minute).Unix()})
+	authenticator := stubApiKeyAuthenticator{principal: auth.Principal{UserId: "batch", Scopes: []string{auth.ScopeRead}}}
+
+	tests := []struct {
+		name          string
+		defaultTenant string
+		bearer        string
+		apiKey        string
+		header        string
+		wantStatus    int
+		wantTenant    string
+	}{
+		{name: "admin token with foreign header", defaultTenant: "default", bearer: adminToken, header: "brand-b", wantStatus: http.StatusForbidden},
+		{name: "api key with foreign header", defaultTenant: "default", apiKey: "ak_valid_secret", header: "brand-b", wantStatus: http.StatusForbidden},
+		{name: "admin token in the default tenant", defaultTenant: "default", bearer: adminToken, wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "no default tenant", bearer: adminToken, header: "brand-b", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewMiddlewareManager(&config.Config{
+				Auth:   config.AuthenticationConfig{JwtSecret: testJwtSecret},
+				Tenant: config.TenantConfig{Default: tt.defaultTenant},
+			}, mockLogger)
+
+			capturedTenant := ""
+			app := fiber.New()
+			app.Use(manager.Tenant())
+			app.Get("/", manager.JwtOrApiKeyAuthentication(authenticator), func(c *fiber.Ctx) error {
+				capturedTenant, _ = tenant.FromContext(c.UserContext())
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(HeaderApiKey, tt.apiKey)
+			}
+			if tt.header != "" {
+				req.Header.Set(tenant.HeaderTenantId, tt.header)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantTenant, capturedTenant)
+		})
+	}
+}
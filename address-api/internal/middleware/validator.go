@@ -3,30 +3,102 @@ package middleware
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/common"
+	"reflect"
 	"strings"
 )
 
 // Validator is a middleware to validate the request body against the provided struct
 func Validator(model interface{}) fiber.Handler {
+	modelType := reflect.TypeOf(model).Elem()
 	return func(c *fiber.Ctx) error {
+		// parse into a fresh value, a shared one is raced by parallel requests and keeps fields of previous bodies
+		model := reflect.New(modelType).Interface()
 		if err := c.BodyParser(model); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Cannot parse JSON",
-			})
+			return Problem(c, fiber.StatusBadRequest, "Cannot parse JSON")
 		}
 
-		validate := validator.New()
-		if err := validate.Struct(model); err != nil {
-			var validationErrors []string
-			for _, err := range err.(validator.ValidationErrors) {
-				validationErrors = append(validationErrors,
-					"Field: "+err.Field()+" failed on the '"+err.Tag()+"' tag")
-			}
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": strings.Join(validationErrors, ", "),
+		return validateStruct(c, model)
+	}
+}
+
+// QueryValidator is a middleware to validate the query string against the provided struct
+func QueryValidator(model interface{}) fiber.Handler {
+	modelType := reflect.TypeOf(model).Elem()
+	return func(c *fiber.Ctx) error {
+		// parse into a fresh value, otherwise parameters of previous requests would leak into this one
+		model := reflect.New(modelType).Interface()
+		if err := c.QueryParser(model); err != nil {
+			return Problem(c, fiber.StatusBadRequest, "Cannot parse query parameters")
+		}
+
+		return validateStruct(c, model)
+	}
+}
+
+func validateStruct(c *fiber.Ctx, model interface{}) error {
+	validate := newValidator()
+	if err := validate.Struct(model); err != nil {
+		problem := NewProblem(c, fiber.StatusBadRequest, "One or more fields are invalid")
+		problem.Type = common.ProblemTypeValidation
+		problem.Title = "Validation failed"
+		for _, err := range err.(validator.ValidationErrors) {
+			problem.Errors = append(problem.Errors, common.FieldError{
+				Field: err.Field(),
+				Rule:  err.Tag(),
+				Param: err.Param(),
 			})
 		}
+		return SendProblem(c, problem)
+	}
+
+	return c.Next()
+}
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	_ = validate.RegisterValidation("sortable", validateSortable)
+	// report fields by the name clients send, e.g. fullAddress instead of FullAddress
+	validate.RegisterTagNameFunc(fieldName)
 
-		return c.Next()
+	return validate
+}
+
+// fieldName returns the json or query name of a struct field
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// validateSortable checks a `field[:asc|desc],...` sort expression against the whitelist given as tag parameter
+func validateSortable(fl validator.FieldLevel) bool {
+	allowed := strings.Fields(fl.Param())
+	for _, part := range strings.Split(fl.Field().String(), ",") {
+		field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if direction != "" && direction != "asc" && direction != "desc" {
+			return false
+		}
+
+		found := false
+		for _, a := range allowed {
+			if a == field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
 	}
+
+	return true
 }
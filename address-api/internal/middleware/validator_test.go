@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/address/dto/request"
+	"github.com/sefikcan/address-api/internal/common"
+	webhookRequest "github.com/sefikcan/address-api/internal/webhook/dto/request"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidator_RespondsWithFieldErrors(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", Validator(&request.AddressCreateRequest{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"city":"Is","country":"Turkey","addressLine1":"Bagdat Cad.","countryCode":"XX"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, _ := app.Test(req)
+
+	var problem common.Problem
+	_ = json.NewDecoder(resp.Body).Decode(&problem)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, common.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, common.ProblemTypeValidation, problem.Type)
+	assert.Equal(t, []common.FieldError{
+		{Field: "city", Rule: "min", Param: "3"},
+		{Field: "countryCode", Rule: "iso3166_1_alpha2"},
+	}, problem.Errors)
+}
+
+func TestValidator_UpdateAcceptsStructuredOnly(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"city":"Istanbul","country":"Turkey","addressLine1":"Bagdat Cad."}`, http.StatusOK},
+		{`{"city":"Istanbul","country":"Turkey","fullAddress":"Bagdat Cad. No 12"}`, http.StatusOK},
+		{`{"city":"Istanbul","country":"Turkey"}`, http.StatusBadRequest},
+	}
+
+	app := fiber.New()
+	app.Put("/", Validator(&request.AddressUpdateRequest{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, tt.status, resp.StatusCode, tt.body)
+	}
+}
+
+func TestValidator_ParallelRequestsDoNotShareTheBody(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", Validator(&request.AddressCreateRequest{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	// the invalid body misses the fields of the valid one, it only passes if it sees a body of another request
+	valid := `{"city":"Istanbul","country":"Turkey","addressLine1":"Bagdat Cad."}`
+	invalid := `{"country":"Turkey"}`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		body, status := valid, http.StatusCreated
+		if i%2 == 1 {
+			body, status = invalid, http.StatusBadRequest
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, status, resp.StatusCode, body)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestQueryValidator_UsesQueryNames(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", QueryValidator(&request.AddressQueryRequest{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/?sort=unknown:asc", nil))
+
+	var problem common.Problem
+	_ = json.NewDecoder(resp.Body).Decode(&problem)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Len(t, problem.Errors, 1)
+	assert.Equal(t, "sort", problem.Errors[0].Field)
+	assert.Equal(t, "sortable", problem.Errors[0].Rule)
+}
+
+func TestValidator_WebhookUrlIsHttpOnly(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", Validator(&webhookRequest.WebhookCreateRequest{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"https://example.com/hooks/address", http.StatusCreated},
+		{"http://example.com:8080/hooks", http.StatusCreated},
+		{"file:///etc/passwd", http.StatusBadRequest},
+		{"gopher://example.com:70/_", http.StatusBadRequest},
+		{"ftp://example.com/hooks", http.StatusBadRequest},
+		{"https://", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		body := `{"url":"` + tt.url + `","eventTypes":["AddressCreated"]}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+	}
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// OutboxMessage is an event stored in the same transaction as the change it describes,
+// the relay publishes it to Kafka afterwards
+type OutboxMessage struct {
+	Id            int64      `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	AggregateType string     `gorm:"size:50" json:"aggregate_type"`
+	AggregateId   string     `gorm:"size:64;index" json:"aggregate_id"`
+	TenantId      string     `gorm:"size:64" json:"tenant_id"`
+	PartitionKey  string     `gorm:"size:255" json:"partition_key"`
+	Topic         string     `json:"topic"`
+	Payload       string     `gorm:"type:text" json:"payload"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at"`
+	// DeadAt is set when the relay gave up on the message after its last attempt
+	DeadAt *time.Time `gorm:"index" json:"dead_at"`
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/outbox/entity"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OutboxRepository interface {
+	Add(ctx context.Context, message entity.OutboxMessage) error
+	GetPending(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error)
+	Claim(ctx context.Context, ids []int64, until time.Time) error
+	MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id int64, lastError string, deadAt time.Time) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+	TryLock(ctx context.Context) (bool, error)
+}
+
+// relayLockKey is the postgres advisory lock key that allows a single relay at a time
+const relayLockKey = 7_316_402_551
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// Add joins the transaction of ctx, so the message is only stored together with the change
+func (o outboxRepository) Add(ctx context.Context, message entity.OutboxMessage) error {
+	if message.NextAttemptAt.IsZero() {
+		message.NextAttemptAt = time.Now()
+	}
+
+	if err := postgres.DB(ctx, o.db).Create(&message).Error; err != nil {
+		return common.DbError(err, "outboxRepository.Add")
+	}
+
+	return nil
+}
+
+// GetPending returns the oldest undelivered message of every partition key in insertion order, if it is due at now.
+// A key waiting for a retry or a claimed message contributes nothing, so its later messages stay behind it
+// and it does not take the place of other keys in the batch
+func (o outboxRepository) GetPending(ctx context.Context, now time.Time, limit int) ([]entity.OutboxMessage, error) {
+	db := postgres.DB(ctx, o.db)
+	oldest := db.Model(&entity.OutboxMessage{}).
+		Select("MIN(id)").
+		Where(`published_at IS NULL AND dead_at IS NULL`).
+		Group("COALESCE(NULLIF(partition_key, ''), aggregate_id)")
+
+	var messages []entity.OutboxMessage
+	err := db.Where(`id IN (?) AND next_attempt_at <= ?`, oldest, now).Order("id").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, common.DbError(err, "outboxRepository.GetPending")
+	}
+
+	return messages, nil
+}
+
+// Claim moves the next attempt of the messages to until, other relays skip them while they are published
+// and a relay that stops before marking them leaves them to be retried afterwards
+func (o outboxRepository) Claim(ctx context.Context, ids []int64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := postgres.DB(ctx, o.db).Model(&entity.OutboxMessage{}).Where(`id IN ?`, ids).Update("next_attempt_at", until).Error
+	if err != nil {
+		return common.DbError(err, "outboxRepository.Claim")
+	}
+
+	return nil
+}
+
+func (o outboxRepository) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	err := postgres.DB(ctx, o.db).Model(&entity.OutboxMessage{}).Where(`id = ?`, id).
+		Updates(map[string]interface{}{"published_at": publishedAt, "last_error": ""}).Error
+	if err != nil {
+		return common.DbError(err, "outboxRepository.MarkPublished")
+	}
+
+	return nil
+}
+
+func (o outboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	err := postgres.DB(ctx, o.db).Model(&entity.OutboxMessage{}).Where(`id = ?`, id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      lastError,
+			"next_attempt_at": nextAttemptAt,
+		}).Error
+	if err != nil {
+		return common.DbError(err, "outboxRepository.MarkFailed")
+	}
+
+	return nil
+}
+
+// MarkDead gives up on the message, it is kept for inspection and no longer holds back its partition key
+func (o outboxRepository) MarkDead(ctx context.Context, id int64, lastError string, deadAt time.Time) error {
+	err := postgres.DB(ctx, o.db).Model(&entity.OutboxMessage{}).Where(`id = ?`, id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": lastError,
+			"dead_at":    deadAt,
+		}).Error
+	if err != nil {
+		return common.DbError(err, "outboxRepository.MarkDead")
+	}
+
+	return nil
+}
+
+// DeletePublished removes delivered messages published before the given time
+func (o outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result := postgres.DB(ctx, o.db).Where(`published_at IS NOT NULL AND published_at < ?`, before).Delete(&entity.OutboxMessage{})
+	if result.Error != nil {
+		return 0, common.DbError(result.Error, "outboxRepository.DeletePublished")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// TryLock takes the relay lock for the transaction of ctx, so replicas do not claim the same
+// messages. Other databases, e.g. sqlite in tests, have a single writer anyway
+func (o outboxRepository) TryLock(ctx context.Context) (bool, error) {
+	db := postgres.DB(ctx, o.db)
+	if db.Dialector.Name() != "postgres" {
+		return true, nil
+	}
+
+	var locked bool
+	if err := db.Raw(`SELECT pg_try_advisory_xact_lock(?)`, relayLockKey).Scan(&locked).Error; err != nil {
+		return false, common.DbError(err, "outboxRepository.TryLock")
+	}
+
+	return locked, nil
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-api/internal/outbox/entity"
+	"github.com/sefikcan/address-api/internal/outbox/repository"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/sefikcan/address-api/pkg/kafka"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/sefikcan/address-events/serde"
+	"time"
+)
+
+const cleanupInterval = 10 * time.Minute
+
+// leaseMargin is left of the claim after a publish for marking the message and the clock drift between replicas
+const leaseMargin = 5 * time.Second
+
+// ErrClaimTimeout is returned for a claim shorter than the longest write of the producer, another relay could
+// claim and publish a message again while its first write is still retried
+var ErrClaimTimeout = errors.New("outbox claim timeout is shorter than the longest kafka write")
+
+// Relay publishes the pending outbox messages through the kafka producer
+// Messages of a partition key are published in insertion order, a failing message holds back
+// the later messages of its key until it is delivered or dead, so they reach their partition in order
+type Relay struct {
+	outboxRepository repository.OutboxRepository
+	transactor       postgres.Transactor
+	producer         kafka.Producer
+	logger           logger.Logger
+	pollInterval     time.Duration
+	batchSize        int
+	retryBackoff     time.Duration
+	maxRetryBackoff  time.Duration
+	maxAttempts      int
+	claimTimeout     time.Duration
+	maxPublishTime   time.Duration
+	retention        time.Duration
+	now              func() time.Time
+}
+
+// Run relays until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.pollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-poll.C:
+			if _, err := r.Drain(ctx); err != nil {
+				r.logger.Errorf("Outbox relay error: %v", err)
+			}
+		case <-cleanup.C:
+			if _, err := r.Cleanup(ctx); err != nil {
+				r.logger.Errorf("Outbox cleanup error: %v", err)
+			}
+		}
+	}
+}
+
+// ProcessBatch claims the due messages in a short transaction and publishes them outside of it,
+// it returns how many were delivered
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	_, published, err := r.processBatch(ctx)
+	return published, err
+}
+
+// Drain processes batches until none is due, every batch holds a single message of a partition key.
+// A claimed message is delivered, waits for its retry or is dead afterwards, so the loop ends
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		claimed, published, err := r.processBatch(ctx)
+		total += published
+		if err != nil || claimed == 0 {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (r *Relay) processBatch(ctx context.Context) (int, int, error) {
+	messages, claimedUntil, err := r.claim(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	published := 0
+	var errs []error
+	for i, message := range messages {
+		// a write started later could outlive the claim, the remaining messages wait for it to expire
+		// and are retried without counting an attempt
+		if r.now().Add(r.maxPublishTime + leaseMargin).After(claimedUntil) {
+			r.logger.Warnf("Outbox claim runs out, %d messages are left to the next claim", len(messages)-i)
+			break
+		}
+
+		if err := r.publish(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				// stopped while publishing, the claim expires and the message is retried without counting an attempt
+				break
+			}
+			errs = append(errs, r.fail(ctx, message, err))
+			continue
+		}
+
+		// a message that can not be marked is published again once its claim expires, consumers dedupe it by the event id
+		if err := r.outboxRepository.MarkPublished(ctx, message.Id, r.now()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		published++
+	}
+
+	return len(messages), published, errors.Join(errs...)
+}
+
+// claim takes the due messages for this relay until the returned time, the relay lock is only held while they are claimed
+func (r *Relay) claim(ctx context.Context) ([]entity.OutboxMessage, time.Time, error) {
+	var messages []entity.OutboxMessage
+	var claimedUntil time.Time
+
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		locked, err := r.outboxRepository.TryLock(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		now := r.now()
+		messages, err = r.outboxRepository.GetPending(ctx, now, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(messages))
+		for _, message := range messages {
+			ids = append(ids, message.Id)
+		}
+
+		claimedUntil = now.Add(r.claimTimeout)
+		return r.outboxRepository.Claim(ctx, ids, claimedUntil)
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return messages, claimedUntil, nil
+}
+
+// fail schedules the next attempt of the message, after the last attempt the message is dead so it stops
+// holding back the later messages of its partition key
+func (r *Relay) fail(ctx context.Context, message entity.OutboxMessage, cause error) error {
+	attempts := message.Attempts + 1
+	if attempts >= r.maxAttempts {
+		r.logger.Errorf("Outbox message dead after %d attempts, Id: %d, Topic: %s, Key: %s, Error: %v", attempts, message.Id, message.Topic, partitionKey(message), cause)
+		return r.outboxRepository.MarkDead(ctx, message.Id, cause.Error(), r.now())
+	}
+
+	r.logger.Warnf("Outbox publish failed, Id: %d, Topic: %s, Attempts: %d, Error: %v", message.Id, message.Topic, attempts, cause)
+	return r.outboxRepository.MarkFailed(ctx, message.Id, cause.Error(), r.now().Add(r.backoff(message)))
+}
+
+// publish sends the CloudEvent of the message, payloads stored before events were enveloped are sent unchanged
+func (r *Relay) publish(ctx context.Context, message entity.OutboxMessage) error {
+	key := partitionKey(message)
+
+	event, err := cloudevents.Decode(nil, []byte(message.Payload))
+	if err != nil {
+		return r.producer.SendMessage(ctx, message.Topic, key, message.Payload, map[string]string{
+			cloudevents.HeaderContentType: serde.ContentTypeJson,
+		})
+	}
+
+	return r.producer.SendEvent(ctx, message.Topic, key, event)
+}
+
+// partitionKey falls back to the aggregate for messages stored without a key
+func partitionKey(message entity.OutboxMessage) string {
+	if message.PartitionKey != "" {
+		return message.PartitionKey
+	}
+	return message.AggregateId
+}
+
+// Cleanup deletes messages published longer than the retention ago
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	deleted, err := r.outboxRepository.DeletePublished(ctx, r.now().Add(-r.retention))
+	if err == nil && deleted > 0 {
+		r.logger.Infof("Outbox cleanup deleted %d published messages", deleted)
+	}
+
+	return deleted, err
+}
+
+// backoff doubles the retry delay with every attempt of the message
+func (r *Relay) backoff(message entity.OutboxMessage) time.Duration {
+	backoff := r.retryBackoff
+	for i := 0; i < message.Attempts && backoff < r.maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > r.maxRetryBackoff {
+		return r.maxRetryBackoff
+	}
+	return backoff
+}
+
+func NewRelay(cfg *config.Config, outboxRepository repository.OutboxRepository, transactor postgres.Transactor, producer kafka.Producer, logger logger.Logger) (*Relay, error) {
+	relay := &Relay{
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		producer:         producer,
+		logger:           logger,
+		pollInterval:     cfg.Outbox.PollInterval * time.Millisecond,
+		batchSize:        cfg.Outbox.BatchSize,
+		retryBackoff:     cfg.Outbox.RetryBackoff * time.Second,
+		maxRetryBackoff:  cfg.Outbox.MaxRetryBackoff * time.Second,
+		maxAttempts:      cfg.Outbox.MaxAttempts,
+		claimTimeout:     cfg.Outbox.ClaimTimeout * time.Second,
+		maxPublishTime:   kafka.MaxWriteTime(cfg.Kafka.Producer),
+		retention:        cfg.Outbox.Retention * time.Hour,
+		now:              time.Now,
+	}
+
+	// defaults for configurations without an outbox section
+	if relay.pollInterval <= 0 {
+		relay.pollInterval = time.Second
+	}
+	if relay.batchSize <= 0 {
+		relay.batchSize = 100
+	}
+	if relay.retryBackoff <= 0 {
+		relay.retryBackoff = time.Second
+	}
+	if relay.maxRetryBackoff < relay.retryBackoff {
+		relay.maxRetryBackoff = 5 * time.Minute
+	}
+	if relay.maxAttempts <= 0 {
+		relay.maxAttempts = 20
+	}
+	// the first message of a batch is published right after the claim, so it always fits
+	minClaimTimeout := relay.maxPublishTime + 2*leaseMargin
+	if relay.claimTimeout <= 0 {
+		relay.claimTimeout = minClaimTimeout
+	}
+	if relay.claimTimeout < minClaimTimeout {
+		return nil, fmt.Errorf("%w, claim timeout: %s, required: %s", ErrClaimTimeout, relay.claimTimeout, minClaimTimeout)
+	}
+	if relay.retention <= 0 {
+		relay.retention = 24 * time.Hour
+	}
+
+	return relay, nil
+}
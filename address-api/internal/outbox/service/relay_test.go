@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/outbox/entity"
+	"github.com/sefikcan/address-api/internal/outbox/repository"
+	"github.com/sefikcan/address-api/pkg/config"
+	mocks2 "github.com/sefikcan/address-api/pkg/kafka/mocks"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+func setupRelay(t *testing.T) (*Relay, *gorm.DB, *mocks2.Producer) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	// every connection of an in-memory sqlite database is a separate database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, db.AutoMigrate(&entity.OutboxMessage{}))
+
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warnf", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Infof", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	producer := new(mocks2.Producer)
+	relay, err := NewRelay(&config.Config{}, repository.NewOutboxRepository(db), postgres.NewTransactor(db), producer, mockLogger)
+	assert.NoError(t, err)
+
+	return relay, db, producer
+}
+
+func addMessages(t *testing.T, db *gorm.DB, messages ...entity.OutboxMessage) {
+	repo := repository.NewOutboxRepository(db)
+	for _, message := range messages {
+		assert.NoError(t, repo.Add(context.Background(), message))
+	}
+}
+
+func TestRelay_PublishesInOrderAndMarksDelivered(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "first"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-updated", Payload: "second"},
+	)
+
+	var sent []string
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(3))
+	}).Return(nil)
+
+	// a batch holds one message of the aggregate, the next one is due once it is delivered
+	published, err := relay.Drain(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"first", "second"}, sent)
+
+	var pending int64
+	db.Model(&entity.OutboxMessage{}).Where("published_at IS NULL").Count(&pending)
+	assert.Equal(t, int64(0), pending)
+}
+
+func TestRelay_FailureHoldsBackLaterMessagesOfAggregate(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "a1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "2", Topic: "address-created", Payload: "b1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-updated", Payload: "a2"},
+	)
+
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "a1", mock.Anything).Return(errors.New("kafka unavailable")).Once()
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "b1", mock.Anything).Return(nil).Once()
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything, mock.Anything, "a2", mock.Anything)
+
+	var failed entity.OutboxMessage
+	db.Where("payload = ?", "a1").First(&failed)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, "kafka unavailable", failed.LastError)
+	assert.True(t, failed.NextAttemptAt.After(time.Now()))
+
+	// the message is retried once its backoff passed, followed by the held back message
+	relay.now = func() time.Time { return time.Now().Add(time.Minute) }
+	var sent []string
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(3))
+	}).Return(nil)
+
+	published, err = relay.Drain(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"a1", "a2"}, sent)
+}
+
+func TestRelay_CleanupDeletesOldPublishedMessages(t *testing.T) {
+	relay, db, _ := setupRelay(t)
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", PublishedAt: &old},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "2", PublishedAt: &recent},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "3"},
+	)
+
+	deleted, err := relay.Cleanup(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay, _, _ := setupRelay(t)
+
+	assert.Equal(t, time.Second, relay.backoff(entity.OutboxMessage{Attempts: 0}))
+	assert.Equal(t, 8*time.Second, relay.backoff(entity.OutboxMessage{Attempts: 3}))
+	assert.Equal(t, 5*time.Minute, relay.backoff(entity.OutboxMessage{Attempts: 20}))
+}
+
+func TestRelay_PublishesCloudEventsThroughTheProducer(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+
+	event, _ := cloudevents.New("/address-api", "com.sefikcan.address.created", "urn:test:v1", map[string]int{"addressId": 1})
+	payload, _ := json.Marshal(event)
+	addMessages(t, db, entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: string(payload)})
+
+	producer.On("SendEvent", mock.Anything, "address-created", "1", mock.MatchedBy(func(sent cloudevents.Event) bool {
+		return sent.Id == event.Id && string(sent.Data) == `{"addressId":1}`
+	})).Return(nil)
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertExpectations(t)
+}
+
+func TestRelay_KeysMessagesAndHoldsBackTheirPartitionKey(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	// both addresses belong to user 42, so a failure of one holds back the other
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", PartitionKey: "42", Topic: "address-created", Payload: "a1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "2", PartitionKey: "42", Topic: "address-created", Payload: "b1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "3", Topic: "address-created", Payload: "c1"},
+	)
+
+	producer.On("SendMessage", mock.Anything, mock.Anything, "42", "a1", mock.Anything).Return(errors.New("kafka unavailable")).Once()
+	producer.On("SendMessage", mock.Anything, mock.Anything, "3", "c1", mock.Anything).Return(nil).Once()
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything, mock.Anything, "b1", mock.Anything)
+	producer.AssertExpectations(t)
+}
+
+func TestRelay_KeysInBackoffDoNotFillTheBatch(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	relay.batchSize = 2
+	later := time.Now().Add(time.Hour)
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "a1", Attempts: 3, NextAttemptAt: later},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-updated", Payload: "a2"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-updated", Payload: "a3"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "2", Topic: "address-created", Payload: "b1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "3", Topic: "address-created", Payload: "c1"},
+	)
+
+	var sent []string
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(3))
+	}).Return(nil)
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"b1", "c1"}, sent)
+}
+
+func TestRelay_DeadMessageReleasesItsKey(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	relay.maxAttempts = 2
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "poison"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-updated", Payload: "a2"},
+	)
+
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "poison", mock.Anything).Return(errors.New("message too large"))
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "a2", mock.Anything).Return(nil).Once()
+
+	published, err := relay.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	relay.now = func() time.Time { return time.Now().Add(time.Hour) }
+	published, err = relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertExpectations(t)
+
+	var dead entity.OutboxMessage
+	db.Where("payload = ?", "poison").First(&dead)
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, "message too large", dead.LastError)
+	assert.NotNil(t, dead.DeadAt)
+	assert.Nil(t, dead.PublishedAt)
+}
+
+func TestRelay_PublishesClaimedMessagesOutsideTheTransaction(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	addMessages(t, db, entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "a1"})
+
+	// the test database has a single connection, a transaction held open while publishing would block these queries
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "a1", mock.Anything).Run(func(args mock.Arguments) {
+		pending, err := repository.NewOutboxRepository(db).GetPending(context.Background(), time.Now(), 10)
+		assert.NoError(t, err)
+		assert.Empty(t, pending, "claimed messages are not handed to other relays")
+	}).Return(nil).Once()
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertExpectations(t)
+}
+
+func TestRelay_ExpiredClaimIsRetried(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	addMessages(t, db, entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "a1"})
+
+	// a relay that stopped after claiming left the message claimed
+	messages, _, err := relay.claim(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	published, err := relay.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "a1", mock.Anything).Return(nil).Once()
+	relay.now = func() time.Time { return time.Now().Add(2 * relay.claimTimeout) }
+
+	published, err = relay.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertExpectations(t)
+}
+
+func TestNewRelay_ClaimOutlastsTheLongestWrite(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Kafka.Producer = config.KafkaProducerConfig{MaxAttempts: 3, WriteTimeout: 5000, RetryBackoffMax: 1000, BatchTimeout: 10}
+
+	relay, err := NewRelay(cfg, nil, nil, new(mocks2.Producer), new(mocks.Logger))
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Millisecond+3*5*time.Second+2*time.Second, relay.maxPublishTime)
+	assert.Equal(t, relay.maxPublishTime+2*leaseMargin, relay.claimTimeout)
+
+	cfg.Outbox.ClaimTimeout = 20
+	relay, err = NewRelay(cfg, nil, nil, new(mocks2.Producer), new(mocks.Logger))
+	assert.ErrorIs(t, err, ErrClaimTimeout)
+	assert.Nil(t, relay)
+}
+
+func TestRelay_StopsPublishingWhenTheClaimRunsOut(t *testing.T) {
+	relay, db, producer := setupRelay(t)
+	addMessages(t, db,
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "1", Topic: "address-created", Payload: "a1"},
+		entity.OutboxMessage{AggregateType: "address", AggregateId: "2", Topic: "address-created", Payload: "b1"},
+	)
+
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	// the first write takes so long that a second one could outlive the claim
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "a1", mock.Anything).Run(func(mock.Arguments) {
+		now = now.Add(relay.claimTimeout - relay.maxPublishTime)
+	}).Return(nil).Once()
+
+	published, err := relay.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	var left entity.OutboxMessage
+	assert.NoError(t, db.Where("payload = ?", "b1").Take(&left).Error)
+	assert.Nil(t, left.PublishedAt)
+	assert.Equal(t, 0, left.Attempts)
+
+	// the message is published once its claim expired
+	producer.On("SendMessage", mock.Anything, mock.Anything, mock.Anything, "b1", mock.Anything).Return(nil).Once()
+	now = now.Add(relay.claimTimeout)
+
+	published, err = relay.ProcessBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	producer.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/address-api/internal/tenant"
 	"time"
 )
 
@@ -23,7 +24,8 @@ func (dtb *DistributedTokenBucket) AllowRequest(ctx context.Context, key string,
 	// date convert nanotime
 	now := time.Now().UnixNano() / int64(time.Millisecond)
 
-	// create redis key
+	// create redis key, buckets are kept per tenant
+	key = tenant.Key(ctx, key)
 	bucketKey := fmt.Sprintf("%s:bucket", key)
 	lastRefillKey := fmt.Sprintf("%s:last_refill", key)
 
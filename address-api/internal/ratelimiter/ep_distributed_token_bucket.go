@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/sefikcan/address-api/internal/tenant"
 	"math"
 	"time"
 )
@@ -27,6 +28,8 @@ func NewEPDistributedTokenBucket(redisAddr string) *EPDistributedTokenBucket {
 
 // AllowRequest controls whether a request will be accepted or not
 func (rtb *EPDistributedTokenBucket) EPAllowRequest(ctx context.Context, key, endpoint string, tokens float64) bool {
+	// buckets are kept per tenant
+	key = tenant.Key(ctx, key)
 	bucketKey := fmt.Sprintf("%s:%s", key, endpoint)
 	lastRefillKey := fmt.Sprintf("%s:last_refill", key)
 
@@ -8,12 +8,23 @@ import (
 	"github.com/sefikcan/address-api/internal/address/handlers"
 	"github.com/sefikcan/address-api/internal/address/repository"
 	"github.com/sefikcan/address-api/internal/address/service"
+	apiKeyHandlers "github.com/sefikcan/address-api/internal/apikey/handlers"
+	apiKeyRepository "github.com/sefikcan/address-api/internal/apikey/repository"
+	apiKeyService "github.com/sefikcan/address-api/internal/apikey/service"
 	idempotency "github.com/sefikcan/address-api/internal/idempotency/service"
 	mw "github.com/sefikcan/address-api/internal/middleware"
+	outboxRepository "github.com/sefikcan/address-api/internal/outbox/repository"
+	outboxService "github.com/sefikcan/address-api/internal/outbox/service"
 	"github.com/sefikcan/address-api/internal/ratelimiter"
+	webhookHandlers "github.com/sefikcan/address-api/internal/webhook/handlers"
+	webhookRepository "github.com/sefikcan/address-api/internal/webhook/repository"
+	webhookService "github.com/sefikcan/address-api/internal/webhook/service"
 	"github.com/sefikcan/address-api/pkg/kafka"
 	"github.com/sefikcan/address-api/pkg/metric"
 	"github.com/sefikcan/address-api/pkg/redis"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/registry"
 )
 
 func (s *Server) MapHandlers(app *fiber.App) error {
@@ -32,35 +43,58 @@ func (s *Server) MapHandlers(app *fiber.App) error {
 		return err
 	}
 
-	kafkaProducer := kafka.NewKafkaProducer(s.cfg.Kafka.Brokers)
+	schemaRegistry, err := registry.NewFileRegistry(s.cfg.Kafka.SchemaRegistryPath)
+	if err != nil {
+		s.logger.Errorf("Error loading the schema registry: %v", err)
+		return err
+	}
+
+	serializer, err := events.NewSerializer(s.cfg.Kafka.Serializer, schemaRegistry)
+	if err != nil {
+		s.logger.Errorf("Error setting up Kafka producers: %v", err)
+		return err
+	}
+
+	producerMetrics, err := metric.CreateProducerMetrics(s.cfg.Metric.ServiceName)
+	if err != nil {
+		s.logger.Errorf("CreateProducerMetrics error: %s", err)
+		return err
+	}
+
+	kafkaProducer, err := kafka.NewKafkaProducer(s.cfg, serializer, producerMetrics, s.logger)
 	if err != nil {
 		s.logger.Errorf("Error setting up Kafka producers: %v", err)
 		return err
 	}
+	s.producer = kafkaProducer
 
 	// initialize repositories and service
+	transactor := postgres.NewTransactor(s.db)
 	addressRepository := repository.NewAddressRepository(s.db)
-	addressService := service.NewAddressService(s.cfg, addressRepository, s.logger, kafkaProducer)
+	outboxRepo := outboxRepository.NewOutboxRepository(s.db)
+	addressService := service.NewAddressService(s.cfg, addressRepository, outboxRepo, transactor, s.logger)
+	apiKeyRepo := apiKeyRepository.NewApiKeyRepository(s.db)
+	apiKeySvc := apiKeyService.NewApiKeyService(apiKeyRepo, s.logger)
+	webhookSvc := webhookService.NewWebhookService(webhookRepository.NewSubscriptionRepository(s.db), webhookRepository.NewDeliveryRepository(s.db), transactor, s.logger)
 
 	middlewareManager := mw.NewMiddlewareManager(s.cfg, s.logger)
+	go middlewareManager.RefreshKeys(s.ctx)
 
 	// set up middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, X-Request-ID",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Request-ID, X-API-Key, X-Tenant-ID, traceparent, tracestate",
 	}))
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
 	}))
 	app.Use(requestid.New())
+	app.Use(middlewareManager.RequestContext)
 	//tb := ratelimiter.NewTokenBucket()
 	//app.Use(middlewareManager.RateLimitMiddleware(tb))
 
-	idempotencyService := idempotency.NewIdempotencyService(redisClient)
-	app.Use(middlewareManager.IdempotencyMiddleware(idempotencyService))
-
-	distributedTb := ratelimiter.NewDistributedTokenBucket(redisClient)
-	app.Use(middlewareManager.DistributedRateLimitMiddleware(distributedTb))
+	// the tenant of the request is resolved first, authentication replaces it with the tenant of the credentials
+	app.Use(middlewareManager.Tenant())
 	app.Use(middlewareManager.RequestLogger)
 	app.Use(middlewareManager.ErrorLogger)
 	app.Use(middlewareManager.Metrics(metrics))
@@ -77,16 +111,37 @@ func (s *Server) MapHandlers(app *fiber.App) error {
 	})
 	app.Use(func(c *fiber.Ctx) error {
 		if c.Request().Header.ContentLength() > (2 * 1024 * 1024) { // 2MB limit
-			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Request body too large"})
+			return mw.Problem(c, fiber.StatusRequestEntityTooLarge, "Request body too large")
 		}
 		return c.Next()
 	})
 
+	// address events are published by the relay, it stops together with the server
+	relay, err := outboxService.NewRelay(s.cfg, outboxRepo, transactor, kafkaProducer, s.logger)
+	if err != nil {
+		s.logger.Errorf("Error setting up the outbox relay: %v", err)
+		return err
+	}
+	go relay.Run(s.ctx)
+
+	// idempotency keys and rate limit buckets are tenant prefixed, the routes mount them after the authentication
+	// so they use the tenant of the credentials instead of the one a client asks for
+	idempotencyService := idempotency.NewIdempotencyService(redisClient)
+	distributedTb := ratelimiter.NewDistributedTokenBucket(redisClient)
+	tenantScoped := []fiber.Handler{
+		middlewareManager.DistributedRateLimitMiddleware(distributedTb),
+		middlewareManager.IdempotencyMiddleware(idempotencyService),
+	}
+
 	// initialize handler
 	addressHandler := handlers.NewAddressHandler(addressService)
+	apiKeyHandler := apiKeyHandlers.NewApiKeyHandler(apiKeySvc)
+	webhookHandler := webhookHandlers.NewWebhookHandler(webhookSvc)
 
 	// initialize handler
-	handlers.MapAddressRotes(app, addressHandler, s.logger, middlewareManager, idempotencyService)
+	handlers.MapAddressRotes(app, addressHandler, s.logger, middlewareManager, apiKeySvc, tenantScoped)
+	apiKeyHandlers.MapApiKeyRoutes(app, apiKeyHandler, middlewareManager, tenantScoped)
+	webhookHandlers.MapWebhookRoutes(app, webhookHandler, middlewareManager, tenantScoped)
 
 	return nil
 }
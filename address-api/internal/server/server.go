@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/middleware"
 	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/sefikcan/address-api/pkg/kafka"
 	"github.com/sefikcan/address-api/pkg/logger"
 	"gorm.io/gorm"
 	"os"
@@ -18,6 +20,11 @@ type Server struct {
 	cfg    *config.Config
 	db     *gorm.DB
 	logger logger.Logger
+	// ctx is cancelled on shutdown to stop the background workers
+	ctx    context.Context
+	cancel context.CancelFunc
+	// producer is closed after the server, so the writes of the stopping relay complete
+	producer kafka.Producer
 }
 
 func (s *Server) Run() error {
@@ -42,6 +49,7 @@ func (s *Server) Run() error {
 	// Wait for an interrupt signal for graceful shutdown
 	<-quit
 	s.logger.Info("Shutting down server...")
+	s.cancel()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.CtxTimeout*time.Second)
@@ -52,6 +60,12 @@ func (s *Server) Run() error {
 		return err
 	}
 
+	if s.producer != nil {
+		if err := s.producer.Close(); err != nil {
+			return err
+		}
+	}
+
 	s.logger.Info("Server exited properly")
 	return nil
 }
@@ -60,11 +74,15 @@ func NewServer(cfg *config.Config, db *gorm.DB, logger logger.Logger) *Server {
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  time.Second * cfg.Server.ReadTimeout,
 		WriteTimeout: time.Second * cfg.Server.WriteTimeout,
+		ErrorHandler: middleware.ErrorHandler(logger),
 	})
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		app:    app,
 		cfg:    cfg,
 		db:     db,
 		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
@@ -0,0 +1,45 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// HeaderTenantId selects the tenant of requests whose credentials do not carry one
+const HeaderTenantId = "X-Tenant-ID"
+
+var (
+	ErrMissingTenant = errors.New("tenant is not resolved for the request")
+	ErrInvalidTenant = errors.New("invalid tenant id")
+)
+
+var tenantIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantKey struct{}
+
+// IsValid reports whether id can be used as tenant id, it ends up in Redis keys and event payloads
+func IsValid(id string) bool {
+	return tenantIdPattern.MatchString(id)
+}
+
+// WithTenant returns a copy of ctx carrying the tenant id
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant resolved by the tenant middleware
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Key prefixes a shared storage key, e.g. in Redis, with the tenant of ctx
+func Key(ctx context.Context, key string) string {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return key
+	}
+
+	return "tenant:" + id + ":" + key
+}
@@ -0,0 +1,19 @@
+package tenant
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "idempotency-key", Key(context.Background(), "idempotency-key"))
+	assert.Equal(t, "tenant:brand-a:idempotency-key", Key(WithTenant(context.Background(), "brand-a"), "idempotency-key"))
+}
+
+func TestIsValid(t *testing.T) {
+	assert.True(t, IsValid("brand_a-1"))
+	assert.False(t, IsValid(""))
+	assert.False(t, IsValid("brand:a"))
+	assert.False(t, IsValid("brand a"))
+}
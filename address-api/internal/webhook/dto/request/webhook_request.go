@@ -0,0 +1,17 @@
+package request
+
+type WebhookCreateRequest struct {
+	// Url is http or https only, the consumer refuses private and loopback addresses when it posts
+	Url        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1,dive,oneof=AddressCreated AddressUpdated AddressDeleted"`
+	// Secret signs the deliveries, a random secret is generated when it is empty
+	Secret string `json:"secret" validate:"omitempty,min=16,max=128"`
+}
+
+type WebhookUpdateRequest struct {
+	Id int `json:"-"`
+	// Url is http or https only, the consumer refuses private and loopback addresses when it posts
+	Url        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1,dive,oneof=AddressCreated AddressUpdated AddressDeleted"`
+	Active     bool     `json:"active"`
+}
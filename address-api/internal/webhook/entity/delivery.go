@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// delivery states, a pending delivery is posted by the address consumer once its next attempt is due
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is an address event posted to a subscription, it is the delivery log of the subscription
+type Delivery struct {
+	Id             int        `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	TenantId       string     `gorm:"index;size:64" json:"tenant_id"`
+	SubscriptionId int        `gorm:"uniqueIndex:idx_webhook_deliveries_event" json:"subscription_id"`
+	EventId        string     `gorm:"uniqueIndex:idx_webhook_deliveries_event;size:64" json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `gorm:"index;size:16" json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// Subscription is a partner endpoint notified about the address events of its tenant, the secret signs every delivery
+type Subscription struct {
+	Id         int       `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	TenantId   string    `gorm:"index;size:64" json:"tenant_id"`
+	Url        string    `gorm:"size:2048" json:"url"`
+	EventTypes string    `json:"event_types"`
+	Secret     string    `json:"-"`
+	Active     bool      `json:"active"`
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventTypeList returns the space separated event types as a slice
+func (s Subscription) EventTypeList() []string {
+	return strings.Fields(s.EventTypes)
+}
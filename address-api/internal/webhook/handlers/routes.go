@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/auth"
+	"github.com/sefikcan/address-api/internal/middleware"
+	"github.com/sefikcan/address-api/internal/webhook/dto/request"
+)
+
+// MapWebhookRoutes mounts the tenantScoped middlewares after the authentication bound the tenant
+func MapWebhookRoutes(app *fiber.App, webhookHandler WebhookHandler, manager *middleware.Manager, tenantScoped []fiber.Handler) {
+	// webhooks are managed by user tokens with the admin scope like the api keys
+	admin := app.Group("/api/v1/admin/webhooks", append([]fiber.Handler{manager.Authentication(), manager.RequireScopes(auth.ScopeAdmin)}, tenantScoped...)...)
+
+	admin.Post("/", middleware.Validator(&request.WebhookCreateRequest{}), webhookHandler.Create)
+	admin.Get("/", webhookHandler.GetAll)
+	admin.Get("/:id", webhookHandler.GetById)
+	admin.Put("/:id", middleware.Validator(&request.WebhookUpdateRequest{}), webhookHandler.Update)
+	admin.Delete("/:id", webhookHandler.Delete)
+	admin.Post("/:id/rotate", webhookHandler.RotateSecret)
+	admin.Get("/:id/deliveries", webhookHandler.GetDeliveries)
+	admin.Post("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+}
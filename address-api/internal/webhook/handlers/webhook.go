@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sefikcan/address-api/internal/webhook/dto/request"
+	"github.com/sefikcan/address-api/internal/webhook/service"
+	"strconv"
+)
+
+type WebhookHandler interface {
+	Create(c *fiber.Ctx) error
+	GetAll(c *fiber.Ctx) error
+	GetById(c *fiber.Ctx) error
+	Update(c *fiber.Ctx) error
+	Delete(c *fiber.Ctx) error
+	RotateSecret(c *fiber.Ctx) error
+	GetDeliveries(c *fiber.Ctx) error
+	Redeliver(c *fiber.Ctx) error
+}
+
+type webhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// Create godoc
+// @Summary Create a webhook
+// @Description Subscribe an endpoint to address events of the tenant, the signing secret is only returned once
+// @Tags webhooks
+// @Security BearerAuth
+// @Param webhook body request.WebhookCreateRequest true "Webhook creation payload"
+// @Success 201 {object} response.WebhookSecretResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks [post]
+func (w webhookHandler) Create(c *fiber.Ctx) error {
+	var webhook request.WebhookCreateRequest
+	if err := c.BodyParser(&webhook); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	response, err := w.webhookService.Create(c.UserContext(), webhook)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetAll godoc
+// @Summary Get all webhooks
+// @Description Get all webhooks of the tenant, secrets are never returned
+// @Tags webhooks
+// @Security BearerAuth
+// @Success 200 {array} response.WebhookResponse
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks [get]
+func (w webhookHandler) GetAll(c *fiber.Ctx) error {
+	webhooks, err := w.webhookService.GetAll(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(webhooks)
+}
+
+// GetById godoc
+// @Summary Get a webhook
+// @Description Get a webhook by its ID
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} response.WebhookResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id} [get]
+func (w webhookHandler) GetById(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	webhook, err := w.webhookService.GetById(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(webhook)
+}
+
+// Update godoc
+// @Summary Update a webhook
+// @Description Replace the endpoint and event types of a webhook or deactivate it
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param webhook body request.WebhookUpdateRequest true "Webhook update payload"
+// @Success 200 {object} response.WebhookResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id} [put]
+func (w webhookHandler) Update(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	webhook := request.WebhookUpdateRequest{}
+	if err := c.BodyParser(&webhook); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Cannot parse JSON")
+	}
+
+	webhook.Id = id
+	updated, err := w.webhookService.Update(c.UserContext(), webhook)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// Delete godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook together with its delivery log
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 204
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id} [delete]
+func (w webhookHandler) Delete(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	if err = w.webhookService.Delete(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RotateSecret godoc
+// @Summary Rotate a webhook secret
+// @Description Replace the signing secret of a webhook, the new secret is only returned once
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} response.WebhookSecretResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id}/rotate [post]
+func (w webhookHandler) RotateSecret(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	response, err := w.webhookService.RotateSecret(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetDeliveries godoc
+// @Summary Get the delivery log of a webhook
+// @Description Get the latest deliveries of a webhook first
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Filter by status" Enums(pending, delivered, failed)
+// @Param limit query int false "Maximum number of deliveries, 50 by default and at most 200"
+// @Success 200 {array} response.DeliveryResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id}/deliveries [get]
+func (w webhookHandler) GetDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	deliveries, err := w.webhookService.GetDeliveries(c.UserContext(), id, c.Query("status"), c.QueryInt("limit"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(deliveries)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Queue a delivery again with a fresh retry schedule, it is posted asynchronously
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 202 {object} response.DeliveryResponse
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /api/v1/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (w webhookHandler) Redeliver(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Id")
+	}
+
+	deliveryId, err := strconv.Atoi(c.Params("deliveryId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid delivery Id")
+	}
+
+	delivery, err := w.webhookService.Redeliver(c.UserContext(), id, deliveryId)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) WebhookHandler {
+	return &webhookHandler{
+		webhookService: webhookService,
+	}
+}
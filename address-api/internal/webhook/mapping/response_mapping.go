@@ -0,0 +1,40 @@
+package mapping
+
+import (
+	"github.com/sefikcan/address-api/internal/webhook/dto/response"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+)
+
+func MapDto(s entity.Subscription) *response.WebhookResponse {
+	return &response.WebhookResponse{
+		Id:         s.Id,
+		Url:        s.Url,
+		EventTypes: s.EventTypeList(),
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func MapSecretDto(s entity.Subscription) *response.WebhookSecretResponse {
+	return &response.WebhookSecretResponse{
+		WebhookResponse: *MapDto(s),
+		Secret:          s.Secret,
+	}
+}
+
+func MapDeliveryDto(d entity.Delivery) *response.DeliveryResponse {
+	return &response.DeliveryResponse{
+		Id:             d.Id,
+		SubscriptionId: d.SubscriptionId,
+		EventId:        d.EventId,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"gorm.io/gorm"
+	"time"
+)
+
+// DeliveryRepository reads the delivery log, the deliveries are written by the address consumer
+type DeliveryRepository interface {
+	GetAll(ctx context.Context, subscriptionId int, status string, limit int) ([]entity.Delivery, error)
+	GetById(ctx context.Context, subscriptionId, id int) (entity.Delivery, error)
+	Redeliver(ctx context.Context, subscriptionId, id int, now time.Time) (entity.Delivery, error)
+	DeleteBySubscription(ctx context.Context, subscriptionId int) error
+}
+
+type deliveryRepository struct {
+	db *gorm.DB
+}
+
+// GetAll returns the latest deliveries of the subscription first, an empty status returns every status
+func (d deliveryRepository) GetAll(ctx context.Context, subscriptionId int, status string, limit int) ([]entity.Delivery, error) {
+	db, err := d.tenantDB(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "deliveryRepository.GetAll.TenantError")
+	}
+
+	query := db.Model(&entity.Delivery{}).Where(`subscription_id = ?`, subscriptionId)
+	if status != "" {
+		query = query.Where(`status = ?`, status)
+	}
+
+	var deliveries []entity.Delivery
+	if err := query.Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, common.DbError(err, "deliveryRepository.GetAll")
+	}
+
+	return deliveries, nil
+}
+
+func (d deliveryRepository) GetById(ctx context.Context, subscriptionId, id int) (entity.Delivery, error) {
+	db, err := d.tenantDB(ctx)
+	if err != nil {
+		return entity.Delivery{}, errors.Wrap(err, "deliveryRepository.GetById.TenantError")
+	}
+
+	delivery := entity.Delivery{}
+	if err := db.Where(`subscription_id = ? AND id = ?`, subscriptionId, id).First(&delivery).Error; err != nil {
+		return entity.Delivery{}, common.DbError(err, "deliveryRepository.GetById")
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues the delivery again with a fresh retry schedule, the result of the last attempt is kept until the next one
+func (d deliveryRepository) Redeliver(ctx context.Context, subscriptionId, id int, now time.Time) (entity.Delivery, error) {
+	db, err := d.tenantDB(ctx)
+	if err != nil {
+		return entity.Delivery{}, errors.Wrap(err, "deliveryRepository.Redeliver.TenantError")
+	}
+
+	result := db.Model(&entity.Delivery{}).
+		Where(`subscription_id = ? AND id = ?`, subscriptionId, id).
+		Updates(map[string]interface{}{
+			"status":          entity.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"delivered_at":    nil,
+		})
+	if result.Error != nil {
+		return entity.Delivery{}, common.DbError(result.Error, "deliveryRepository.Redeliver")
+	}
+	if result.RowsAffected == 0 {
+		return entity.Delivery{}, common.DbError(gorm.ErrRecordNotFound, "deliveryRepository.Redeliver")
+	}
+
+	return d.GetById(ctx, subscriptionId, id)
+}
+
+func (d deliveryRepository) DeleteBySubscription(ctx context.Context, subscriptionId int) error {
+	db, err := d.tenantDB(ctx)
+	if err != nil {
+		return errors.Wrap(err, "deliveryRepository.DeleteBySubscription.TenantError")
+	}
+
+	if err := db.Where(`subscription_id = ?`, subscriptionId).Delete(&entity.Delivery{}).Error; err != nil {
+		return common.DbError(err, "deliveryRepository.DeleteBySubscription")
+	}
+
+	return nil
+}
+
+// tenantDB returns a session limited to the tenant of ctx, queries without a tenant are refused
+func (d deliveryRepository) tenantDB(ctx context.Context) (*gorm.DB, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	return postgres.DB(ctx, d.db).Where(`tenant_id = ?`, tenantId).Session(&gorm.Session{}), nil
+}
+
+func NewDeliveryRepository(db *gorm.DB) DeliveryRepository {
+	return &deliveryRepository{
+		db: db,
+	}
+}
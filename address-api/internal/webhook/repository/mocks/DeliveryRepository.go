@@ -0,0 +1,135 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/sefikcan/address-api/internal/webhook/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// DeliveryRepository is an autogenerated mock type for the DeliveryRepository type
+type DeliveryRepository struct {
+	mock.Mock
+}
+
+// DeleteBySubscription provides a mock function with given fields: ctx, subscriptionId
+func (_m *DeliveryRepository) DeleteBySubscription(ctx context.Context, subscriptionId int) error {
+	ret := _m.Called(ctx, subscriptionId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBySubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, subscriptionId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAll provides a mock function with given fields: ctx, subscriptionId, status, limit
+func (_m *DeliveryRepository) GetAll(ctx context.Context, subscriptionId int, status string, limit int) ([]entity.Delivery, error) {
+	ret := _m.Called(ctx, subscriptionId, status, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []entity.Delivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) ([]entity.Delivery, error)); ok {
+		return rf(ctx, subscriptionId, status, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) []entity.Delivery); ok {
+		r0 = rf(ctx, subscriptionId, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Delivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int) error); ok {
+		r1 = rf(ctx, subscriptionId, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetById provides a mock function with given fields: ctx, subscriptionId, id
+func (_m *DeliveryRepository) GetById(ctx context.Context, subscriptionId int, id int) (entity.Delivery, error) {
+	ret := _m.Called(ctx, subscriptionId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 entity.Delivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (entity.Delivery, error)); ok {
+		return rf(ctx, subscriptionId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) entity.Delivery); ok {
+		r0 = rf(ctx, subscriptionId, id)
+	} else {
+		r0 = ret.Get(0).(entity.Delivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, subscriptionId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Redeliver provides a mock function with given fields: ctx, subscriptionId, id, now
+func (_m *DeliveryRepository) Redeliver(ctx context.Context, subscriptionId int, id int, now time.Time) (entity.Delivery, error) {
+	ret := _m.Called(ctx, subscriptionId, id, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 entity.Delivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, time.Time) (entity.Delivery, error)); ok {
+		return rf(ctx, subscriptionId, id, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, time.Time) entity.Delivery); ok {
+		r0 = rf(ctx, subscriptionId, id, now)
+	} else {
+		r0 = ret.Get(0).(entity.Delivery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, time.Time) error); ok {
+		r1 = rf(ctx, subscriptionId, id, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewDeliveryRepository creates a new instance of DeliveryRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeliveryRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeliveryRepository {
+	mock := &DeliveryRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
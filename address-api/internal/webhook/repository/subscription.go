@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"gorm.io/gorm"
+)
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error)
+	Update(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error)
+	Delete(ctx context.Context, id int) error
+	GetById(ctx context.Context, id int) (entity.Subscription, error)
+	GetAll(ctx context.Context) ([]entity.Subscription, error)
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func (s subscriptionRepository) Create(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return entity.Subscription{}, errors.Wrap(tenant.ErrMissingTenant, "subscriptionRepository.Create.TenantError")
+	}
+
+	subscription.TenantId = tenantId
+	if result := postgres.DB(ctx, s.db).Create(&subscription); result.Error != nil {
+		return entity.Subscription{}, common.DbError(result.Error, "subscriptionRepository.Create")
+	}
+
+	return subscription, nil
+}
+
+func (s subscriptionRepository) Update(ctx context.Context, subscription entity.Subscription) (entity.Subscription, error) {
+	db, err := s.tenantDB(ctx)
+	if err != nil {
+		return entity.Subscription{}, errors.Wrap(err, "subscriptionRepository.Update.TenantError")
+	}
+
+	result := db.Model(&entity.Subscription{}).Where(`id = ?`, subscription.Id).Select("*").Omit("created_at", "tenant_id").Updates(&subscription)
+	if result.Error != nil {
+		return entity.Subscription{}, common.DbError(result.Error, "subscriptionRepository.Update")
+	}
+	if result.RowsAffected == 0 {
+		return entity.Subscription{}, common.DbError(gorm.ErrRecordNotFound, "subscriptionRepository.Update")
+	}
+
+	return subscription, nil
+}
+
+func (s subscriptionRepository) Delete(ctx context.Context, id int) error {
+	db, err := s.tenantDB(ctx)
+	if err != nil {
+		return errors.Wrap(err, "subscriptionRepository.Delete.TenantError")
+	}
+
+	result := db.Where(`id = ?`, id).Delete(&entity.Subscription{})
+	if result.Error != nil {
+		return common.DbError(result.Error, "subscriptionRepository.Delete")
+	}
+	if result.RowsAffected == 0 {
+		return common.DbError(gorm.ErrRecordNotFound, "subscriptionRepository.Delete")
+	}
+
+	return nil
+}
+
+func (s subscriptionRepository) GetById(ctx context.Context, id int) (entity.Subscription, error) {
+	db, err := s.tenantDB(ctx)
+	if err != nil {
+		return entity.Subscription{}, errors.Wrap(err, "subscriptionRepository.GetById.TenantError")
+	}
+
+	subscription := entity.Subscription{}
+	if err := db.Where(`id = ?`, id).First(&subscription).Error; err != nil {
+		return entity.Subscription{}, common.DbError(err, "subscriptionRepository.GetById")
+	}
+
+	return subscription, nil
+}
+
+func (s subscriptionRepository) GetAll(ctx context.Context) ([]entity.Subscription, error) {
+	db, err := s.tenantDB(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "subscriptionRepository.GetAll.TenantError")
+	}
+
+	var subscriptions []entity.Subscription
+	if err := db.Model(&entity.Subscription{}).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, common.DbError(err, "subscriptionRepository.GetAll")
+	}
+
+	return subscriptions, nil
+}
+
+// tenantDB returns a session limited to the tenant of ctx, queries without a tenant are refused
+func (s subscriptionRepository) tenantDB(ctx context.Context) (*gorm.DB, error) {
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+
+	return postgres.DB(ctx, s.db).Where(`tenant_id = ?`, tenantId).Session(&gorm.Session{}), nil
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{
+		db: db,
+	}
+}
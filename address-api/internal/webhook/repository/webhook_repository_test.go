@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"github.com/sefikcan/address-api/internal/tenant"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+func tenantContext(tenantId string) context.Context {
+	return tenant.WithTenant(context.Background(), tenantId)
+}
+
+func SetupTestDB() (*gorm.DB, func()) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	err = db.AutoMigrate(&entity.Subscription{}, &entity.Delivery{})
+	if err != nil {
+		return nil, nil
+	}
+
+	return db, func() {
+		db.Exec(`DROP TABLE webhook_deliveries`)
+		db.Exec(`DROP TABLE webhook_subscriptions`)
+	}
+}
+
+func TestSubscriptionRepository_IsTenantScoped(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	repo := NewSubscriptionRepository(db)
+	created, err := repo.Create(tenantContext("brand-a"), entity.Subscription{Url: "https://partner.example/hooks", EventTypes: "AddressCreated AddressUpdated", Secret: "secret", Active: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-a", created.TenantId)
+	_, _ = repo.Create(tenantContext("brand-b"), entity.Subscription{Url: "https://other.example/hooks", EventTypes: "AddressDeleted", Active: true})
+
+	all, err := repo.GetAll(tenantContext("brand-a"))
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.Equal(t, []string{"AddressCreated", "AddressUpdated"}, all[0].EventTypeList())
+
+	_, err = repo.GetById(tenantContext("brand-b"), created.Id)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	created.Active = false
+	_, err = repo.Update(tenantContext("brand-b"), created)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Delete(tenantContext("brand-b"), created.Id), gorm.ErrRecordNotFound)
+
+	updated, err := repo.Update(tenantContext("brand-a"), created)
+	assert.NoError(t, err)
+	assert.False(t, updated.Active)
+	assert.NoError(t, repo.Delete(tenantContext("brand-a"), created.Id))
+}
+
+func TestDeliveryRepository_GetAllAndRedeliver(t *testing.T) {
+	db, teardown := SetupTestDB()
+	defer teardown()
+
+	deliveredAt := time.Now()
+	deliveries := []entity.Delivery{
+		{TenantId: "brand-a", SubscriptionId: 1, EventId: "e1", Status: entity.DeliveryDelivered, Attempts: 1, DeliveredAt: &deliveredAt},
+		{TenantId: "brand-a", SubscriptionId: 1, EventId: "e2", Status: entity.DeliveryFailed, Attempts: 6, LastStatusCode: 500},
+		{TenantId: "brand-a", SubscriptionId: 2, EventId: "e1", Status: entity.DeliveryPending},
+		{TenantId: "brand-b", SubscriptionId: 1, EventId: "e3", Status: entity.DeliveryFailed},
+	}
+	for _, delivery := range deliveries {
+		assert.NoError(t, db.Create(&delivery).Error)
+	}
+
+	repo := NewDeliveryRepository(db)
+
+	all, err := repo.GetAll(tenantContext("brand-a"), 1, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, "e2", all[0].EventId, "latest deliveries come first")
+
+	failed, err := repo.GetAll(tenantContext("brand-a"), 1, entity.DeliveryFailed, 10)
+	assert.NoError(t, err)
+	assert.Len(t, failed, 1)
+
+	now := time.Now()
+	redelivered, err := repo.Redeliver(tenantContext("brand-a"), 1, failed[0].Id, now)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.DeliveryPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	assert.Equal(t, 500, redelivered.LastStatusCode, "the last attempt stays in the log")
+
+	// deliveries of other tenants or subscriptions are not found
+	_, err = repo.Redeliver(tenantContext("brand-b"), 1, failed[0].Id, now)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.Redeliver(tenantContext("brand-a"), 2, failed[0].Id, now)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
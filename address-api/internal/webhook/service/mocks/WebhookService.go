@@ -0,0 +1,259 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	request "github.com/sefikcan/address-api/internal/webhook/dto/request"
+	mock "github.com/stretchr/testify/mock"
+
+	response "github.com/sefikcan/address-api/internal/webhook/dto/response"
+)
+
+// WebhookService is an autogenerated mock type for the WebhookService type
+type WebhookService struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, _a1
+func (_m *WebhookService) Create(ctx context.Context, _a1 request.WebhookCreateRequest) (*response.WebhookSecretResponse, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *response.WebhookSecretResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, request.WebhookCreateRequest) (*response.WebhookSecretResponse, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, request.WebhookCreateRequest) *response.WebhookSecretResponse); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.WebhookSecretResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, request.WebhookCreateRequest) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *WebhookService) Delete(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAll provides a mock function with given fields: ctx
+func (_m *WebhookService) GetAll(ctx context.Context) ([]response.WebhookResponse, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []response.WebhookResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]response.WebhookResponse, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []response.WebhookResponse); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]response.WebhookResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetById provides a mock function with given fields: ctx, id
+func (_m *WebhookService) GetById(ctx context.Context, id int) (*response.WebhookResponse, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 *response.WebhookResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*response.WebhookResponse, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *response.WebhookResponse); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.WebhookResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDeliveries provides a mock function with given fields: ctx, id, status, limit
+func (_m *WebhookService) GetDeliveries(ctx context.Context, id int, status string, limit int) ([]response.DeliveryResponse, error) {
+	ret := _m.Called(ctx, id, status, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeliveries")
+	}
+
+	var r0 []response.DeliveryResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) ([]response.DeliveryResponse, error)); ok {
+		return rf(ctx, id, status, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, int) []response.DeliveryResponse); ok {
+		r0 = rf(ctx, id, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]response.DeliveryResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, int) error); ok {
+		r1 = rf(ctx, id, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Redeliver provides a mock function with given fields: ctx, id, deliveryId
+func (_m *WebhookService) Redeliver(ctx context.Context, id int, deliveryId int) (*response.DeliveryResponse, error) {
+	ret := _m.Called(ctx, id, deliveryId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 *response.DeliveryResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*response.DeliveryResponse, error)); ok {
+		return rf(ctx, id, deliveryId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *response.DeliveryResponse); ok {
+		r0 = rf(ctx, id, deliveryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.DeliveryResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, id, deliveryId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotateSecret provides a mock function with given fields: ctx, id
+func (_m *WebhookService) RotateSecret(ctx context.Context, id int) (*response.WebhookSecretResponse, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateSecret")
+	}
+
+	var r0 *response.WebhookSecretResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*response.WebhookSecretResponse, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *response.WebhookSecretResponse); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.WebhookSecretResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, _a1
+func (_m *WebhookService) Update(ctx context.Context, _a1 request.WebhookUpdateRequest) (*response.WebhookResponse, error) {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *response.WebhookResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, request.WebhookUpdateRequest) (*response.WebhookResponse, error)); ok {
+		return rf(ctx, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, request.WebhookUpdateRequest) *response.WebhookResponse); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*response.WebhookResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, request.WebhookUpdateRequest) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWebhookService creates a new instance of WebhookService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhookService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhookService {
+	mock := &WebhookService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/sefikcan/address-api/internal/common"
+	"github.com/sefikcan/address-api/internal/webhook/dto/request"
+	"github.com/sefikcan/address-api/internal/webhook/dto/response"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+	"github.com/sefikcan/address-api/internal/webhook/mapping"
+	"github.com/sefikcan/address-api/internal/webhook/repository"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"github.com/sefikcan/address-api/pkg/storage/postgres"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	// secretPrefix marks generated webhook secrets, e.g. for secret scanners
+	secretPrefix = "whsec_"
+
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+var (
+	ErrWebhookNotFound       = common.NewError(common.KindNotFound, "webhook not found")
+	ErrDeliveryNotFound      = common.NewError(common.KindNotFound, "webhook delivery not found")
+	ErrInvalidDeliveryStatus = common.NewError(common.KindValidation, "delivery status must be pending, delivered or failed")
+)
+
+type WebhookService interface {
+	Create(ctx context.Context, request request.WebhookCreateRequest) (*response.WebhookSecretResponse, error)
+	GetAll(ctx context.Context) ([]response.WebhookResponse, error)
+	GetById(ctx context.Context, id int) (*response.WebhookResponse, error)
+	Update(ctx context.Context, request request.WebhookUpdateRequest) (*response.WebhookResponse, error)
+	Delete(ctx context.Context, id int) error
+	RotateSecret(ctx context.Context, id int) (*response.WebhookSecretResponse, error)
+	GetDeliveries(ctx context.Context, id int, status string, limit int) ([]response.DeliveryResponse, error)
+	Redeliver(ctx context.Context, id, deliveryId int) (*response.DeliveryResponse, error)
+}
+
+type webhookService struct {
+	subscriptionRepository repository.SubscriptionRepository
+	deliveryRepository     repository.DeliveryRepository
+	transactor             postgres.Transactor
+	logger                 logger.Logger
+	now                    func() time.Time
+}
+
+func (w webhookService) Create(ctx context.Context, request request.WebhookCreateRequest) (*response.WebhookSecretResponse, error) {
+	secret := request.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription, err := w.subscriptionRepository.Create(ctx, entity.Subscription{
+		Url:        request.Url,
+		EventTypes: strings.Join(request.EventTypes, " "),
+		Secret:     secret,
+		Active:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapSecretDto(subscription), nil
+}
+
+func (w webhookService) GetAll(ctx context.Context) ([]response.WebhookResponse, error) {
+	subscriptions, err := w.subscriptionRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]response.WebhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		dtos = append(dtos, *mapping.MapDto(subscription))
+	}
+
+	return dtos, nil
+}
+
+func (w webhookService) GetById(ctx context.Context, id int) (*response.WebhookResponse, error) {
+	subscription, err := w.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(subscription), nil
+}
+
+// Update replaces the endpoint and its event types, an inactive webhook keeps its log but gets no new deliveries
+func (w webhookService) Update(ctx context.Context, request request.WebhookUpdateRequest) (*response.WebhookResponse, error) {
+	subscription, err := w.get(ctx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Url = request.Url
+	subscription.EventTypes = strings.Join(request.EventTypes, " ")
+	subscription.Active = request.Active
+
+	updated, err := w.subscriptionRepository.Update(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapDto(updated), nil
+}
+
+// Delete removes the webhook together with its delivery log, pending deliveries are not posted anymore
+func (w webhookService) Delete(ctx context.Context, id int) error {
+	if _, err := w.get(ctx, id); err != nil {
+		return err
+	}
+
+	return w.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := w.deliveryRepository.DeleteBySubscription(ctx, id); err != nil {
+			return err
+		}
+
+		return w.subscriptionRepository.Delete(ctx, id)
+	})
+}
+
+// RotateSecret replaces the secret in place, deliveries are signed with the new secret from their next attempt on
+func (w webhookService) RotateSecret(ctx context.Context, id int) (*response.WebhookSecretResponse, error) {
+	subscription, err := w.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Secret, err = generateSecret(); err != nil {
+		return nil, err
+	}
+
+	updated, err := w.subscriptionRepository.Update(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.MapSecretDto(updated), nil
+}
+
+func (w webhookService) GetDeliveries(ctx context.Context, id int, status string, limit int) ([]response.DeliveryResponse, error) {
+	switch status {
+	case "", entity.DeliveryPending, entity.DeliveryDelivered, entity.DeliveryFailed:
+	default:
+		return nil, ErrInvalidDeliveryStatus
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	limit = min(limit, maxDeliveryLimit)
+
+	if _, err := w.get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := w.deliveryRepository.GetAll(ctx, id, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]response.DeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		dtos = append(dtos, *mapping.MapDeliveryDto(delivery))
+	}
+
+	return dtos, nil
+}
+
+// Redeliver queues the delivery again, the address consumer posts it with its next poll
+func (w webhookService) Redeliver(ctx context.Context, id, deliveryId int) (*response.DeliveryResponse, error) {
+	delivery, err := w.deliveryRepository.Redeliver(ctx, id, deliveryId, w.now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w.logger.Infof("Webhook delivery queued again, WebhookId: %d, DeliveryId: %d, EventId: %s", id, deliveryId, delivery.EventId)
+
+	return mapping.MapDeliveryDto(delivery), nil
+}
+
+func (w webhookService) get(ctx context.Context, id int) (entity.Subscription, error) {
+	subscription, err := w.subscriptionRepository.GetById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.Subscription{}, ErrWebhookNotFound
+	}
+
+	return subscription, err
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func NewWebhookService(subscriptionRepository repository.SubscriptionRepository, deliveryRepository repository.DeliveryRepository, transactor postgres.Transactor, logger logger.Logger) WebhookService {
+	return &webhookService{
+		subscriptionRepository: subscriptionRepository,
+		deliveryRepository:     deliveryRepository,
+		transactor:             transactor,
+		logger:                 logger,
+		now:                    time.Now,
+	}
+}
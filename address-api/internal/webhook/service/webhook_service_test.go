@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	mocks2 "github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/internal/webhook/dto/request"
+	"github.com/sefikcan/address-api/internal/webhook/entity"
+	"github.com/sefikcan/address-api/internal/webhook/repository/mocks"
+	mocks3 "github.com/sefikcan/address-api/pkg/storage/postgres/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+func inlineTransactor() *mocks3.Transactor {
+	transactor := new(mocks3.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	}).Maybe()
+	return transactor
+}
+
+func TestWebhookService_CreateGeneratesSecret(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	svc := NewWebhookService(mockSubscriptions, new(mocks.DeliveryRepository), inlineTransactor(), new(mocks2.Logger))
+
+	var stored entity.Subscription
+	mockSubscriptions.On("Create", mock.Anything, mock.Anything).Return(func(_ context.Context, s entity.Subscription) (entity.Subscription, error) {
+		s.Id = 1
+		stored = s
+		return s, nil
+	})
+
+	resp, err := svc.Create(context.Background(), request.WebhookCreateRequest{Url: "https://partner.example/hooks", EventTypes: []string{"AddressCreated", "AddressDeleted"}})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.Secret, secretPrefix))
+	assert.Equal(t, stored.Secret, resp.Secret)
+	assert.Equal(t, "AddressCreated AddressDeleted", stored.EventTypes)
+	assert.True(t, stored.Active)
+	assert.Equal(t, []string{"AddressCreated", "AddressDeleted"}, resp.EventTypes)
+}
+
+func TestWebhookService_CreateKeepsGivenSecret(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	svc := NewWebhookService(mockSubscriptions, new(mocks.DeliveryRepository), inlineTransactor(), new(mocks2.Logger))
+	mockSubscriptions.On("Create", mock.Anything, mock.MatchedBy(func(s entity.Subscription) bool {
+		return s.Secret == "partner-chosen-secret"
+	})).Return(entity.Subscription{Id: 1, Secret: "partner-chosen-secret"}, nil)
+
+	resp, err := svc.Create(context.Background(), request.WebhookCreateRequest{Url: "https://partner.example/hooks", EventTypes: []string{"AddressCreated"}, Secret: "partner-chosen-secret"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "partner-chosen-secret", resp.Secret)
+}
+
+func TestWebhookService_RotateSecret(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	svc := NewWebhookService(mockSubscriptions, new(mocks.DeliveryRepository), inlineTransactor(), new(mocks2.Logger))
+	mockSubscriptions.On("GetById", mock.Anything, 1).Return(entity.Subscription{Id: 1, Secret: "old"}, nil)
+	mockSubscriptions.On("Update", mock.Anything, mock.Anything).Return(func(_ context.Context, s entity.Subscription) (entity.Subscription, error) {
+		return s, nil
+	})
+
+	resp, err := svc.RotateSecret(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, "old", resp.Secret)
+	assert.True(t, strings.HasPrefix(resp.Secret, secretPrefix))
+}
+
+func TestWebhookService_DeleteRemovesDeliveries(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	mockDeliveries := new(mocks.DeliveryRepository)
+	transactor := inlineTransactor()
+	svc := NewWebhookService(mockSubscriptions, mockDeliveries, transactor, new(mocks2.Logger))
+	mockSubscriptions.On("GetById", mock.Anything, 1).Return(entity.Subscription{Id: 1}, nil)
+	mockDeliveries.On("DeleteBySubscription", mock.Anything, 1).Return(nil)
+	mockSubscriptions.On("Delete", mock.Anything, 1).Return(nil)
+
+	assert.NoError(t, svc.Delete(context.Background(), 1))
+	mockDeliveries.AssertExpectations(t)
+	mockSubscriptions.AssertExpectations(t)
+	transactor.AssertCalled(t, "WithinTransaction", mock.Anything, mock.Anything)
+}
+
+func TestWebhookService_NotFound(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	mockDeliveries := new(mocks.DeliveryRepository)
+	svc := NewWebhookService(mockSubscriptions, mockDeliveries, inlineTransactor(), new(mocks2.Logger))
+	mockSubscriptions.On("GetById", mock.Anything, 9).Return(entity.Subscription{}, gorm.ErrRecordNotFound)
+	mockDeliveries.On("Redeliver", mock.Anything, 1, 9, mock.Anything).Return(entity.Delivery{}, gorm.ErrRecordNotFound)
+
+	_, err := svc.GetById(context.Background(), 9)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	_, err = svc.GetDeliveries(context.Background(), 9, "", 0)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	_, err = svc.Redeliver(context.Background(), 1, 9)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestWebhookService_GetDeliveries(t *testing.T) {
+	mockSubscriptions := new(mocks.SubscriptionRepository)
+	mockDeliveries := new(mocks.DeliveryRepository)
+	svc := NewWebhookService(mockSubscriptions, mockDeliveries, inlineTransactor(), new(mocks2.Logger))
+	mockSubscriptions.On("GetById", mock.Anything, 1).Return(entity.Subscription{Id: 1}, nil)
+	mockDeliveries.On("GetAll", mock.Anything, 1, entity.DeliveryFailed, maxDeliveryLimit).Return([]entity.Delivery{
+		{Id: 3, SubscriptionId: 1, EventId: "e1", Status: entity.DeliveryFailed, Attempts: 6, LastStatusCode: 503},
+	}, nil)
+
+	deliveries, err := svc.GetDeliveries(context.Background(), 1, entity.DeliveryFailed, 1000)
+
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 503, deliveries[0].LastStatusCode)
+
+	_, err = svc.GetDeliveries(context.Background(), 1, "unknown", 0)
+	assert.ErrorIs(t, err, ErrInvalidDeliveryStatus)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	mockDeliveries := new(mocks.DeliveryRepository)
+	mockLogger := new(mocks2.Logger)
+	svc := NewWebhookService(new(mocks.SubscriptionRepository), mockDeliveries, inlineTransactor(), mockLogger)
+	mockDeliveries.On("Redeliver", mock.Anything, 1, 3, mock.Anything).Return(entity.Delivery{Id: 3, SubscriptionId: 1, EventId: "e1", Status: entity.DeliveryPending}, nil)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	delivery, err := svc.Redeliver(context.Background(), 1, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, entity.DeliveryPending, delivery.Status)
+	mockDeliveries.AssertExpectations(t)
+}
@@ -19,13 +19,16 @@ const (
 )
 
 type Config struct {
-	Server   ServerConfig         `mapstructure:"server"`
-	Postgres PostgresConfig       `mapstructure:"postgres"`
-	Logger   LoggerConfig         `mapstructure:"logger"`
-	Auth     AuthenticationConfig `mapstructure:"auth"`
-	Metric   MetricConfig         `mapstructure:"metric"`
-	Kafka    KafkaConfig          `mapstructure:"kafka"`
-	Redis    RedisConfig          `mapstructure:"redis"`
+	Server     ServerConfig         `mapstructure:"server"`
+	Postgres   PostgresConfig       `mapstructure:"postgres"`
+	Logger     LoggerConfig         `mapstructure:"logger"`
+	Auth       AuthenticationConfig `mapstructure:"auth"`
+	Metric     MetricConfig         `mapstructure:"metric"`
+	Kafka      KafkaConfig          `mapstructure:"kafka"`
+	Redis      RedisConfig          `mapstructure:"redis"`
+	Pagination PaginationConfig     `mapstructure:"pagination"`
+	Tenant     TenantConfig         `mapstructure:"tenant"`
+	Outbox     OutboxConfig         `mapstructure:"outbox"`
 }
 
 type ServerConfig struct {
@@ -40,6 +43,32 @@ type ServerConfig struct {
 	CtxTimeout     time.Duration `mapstructure:"ctxTimeout"`
 }
 
+type PaginationConfig struct {
+	CursorSecret string `mapstructure:"cursorSecret"`
+}
+
+type TenantConfig struct {
+	// Default is the tenant of requests without X-Tenant-ID header and of credentials without tenant claim,
+	// empty rejects them
+	Default string `mapstructure:"default"`
+}
+
+type OutboxConfig struct {
+	// PollInterval in milliseconds between two relay runs
+	PollInterval time.Duration `mapstructure:"pollInterval"`
+	BatchSize    int           `mapstructure:"batchSize"`
+	// RetryBackoff in seconds, doubled after every failed attempt up to MaxRetryBackoff seconds
+	RetryBackoff    time.Duration `mapstructure:"retryBackoff"`
+	MaxRetryBackoff time.Duration `mapstructure:"maxRetryBackoff"`
+	// MaxAttempts a message is published before the relay gives up on it
+	MaxAttempts int `mapstructure:"maxAttempts"`
+	// ClaimTimeout in seconds a claimed message is left to its relay before another one may retry it,
+	// the relay refuses to start when a failing kafka write takes longer and derives it when it is empty
+	ClaimTimeout time.Duration `mapstructure:"claimTimeout"`
+	// Retention in hours published messages are kept before cleanup
+	Retention time.Duration `mapstructure:"retention"`
+}
+
 type RedisConfig struct {
 	Addr string `mapstructure:"addr"`
 }
@@ -68,6 +97,16 @@ type PostgresConfig struct {
 
 type AuthenticationConfig struct {
 	JwtSecret string `mapstructure:"jwtSecret"`
+	// Algorithms lists the accepted signing algorithms, e.g. HS256, RS256, ES256
+	Algorithms []string `mapstructure:"algorithms"`
+	// JwksSource is a file path or http(s) url of the identity provider JWKS document
+	JwksSource string `mapstructure:"jwksSource"`
+	// JwksRefreshInterval in seconds
+	JwksRefreshInterval time.Duration `mapstructure:"jwksRefreshInterval"`
+	Issuer              string        `mapstructure:"issuer"`
+	Audience            string        `mapstructure:"audience"`
+	// ClockSkew in seconds tolerated on exp, nbf and iat
+	ClockSkew time.Duration `mapstructure:"clockSkew"`
 }
 
 type MetricConfig struct {
@@ -82,8 +121,56 @@ type KafkaConfig struct {
 	GroupID        string   `mapstructure:"groupId"`
 	AutoCommit     bool     `mapstructure:"autoCommit"`
 	FetchMaxWaitMs int      `mapstructure:"fetchMaxWaitMs"`
+	// EventMode is the CloudEvents content mode of published events, structured or binary
+	EventMode string `mapstructure:"eventMode"`
+	// Serializer encodes event data as json, protobuf or avro, protobuf and avro are always sent in binary mode
+	Serializer string `mapstructure:"serializer"`
+	// SchemaRegistryPath is the file of the local schema registry shared with the consumers
+	SchemaRegistryPath string `mapstructure:"schemaRegistryPath"`
+	// PartitionKey keys address events by addressId or userId, events of a key keep their order
+	PartitionKey string              `mapstructure:"partitionKey"`
+	Producer     KafkaProducerConfig `mapstructure:"producer"`
+	TLS          KafkaTLSConfig      `mapstructure:"tls"`
+	SASL         KafkaSASLConfig     `mapstructure:"sasl"`
+}
+
+type KafkaProducerConfig struct {
+	// RequiredAcks is all, one or none, kafka-go has no idempotent producer so retried writes
+	// may be duplicated and consumers dedupe by the event id
+	RequiredAcks string `mapstructure:"requiredAcks"`
+	MaxAttempts  int    `mapstructure:"maxAttempts"`
+	// RetryBackoffMin and RetryBackoffMax in milliseconds between two write attempts
+	RetryBackoffMin time.Duration `mapstructure:"retryBackoffMin"`
+	RetryBackoffMax time.Duration `mapstructure:"retryBackoffMax"`
+	BatchSize       int           `mapstructure:"batchSize"`
+	// BatchTimeout in milliseconds an incomplete batch waits before it is sent
+	BatchTimeout time.Duration `mapstructure:"batchTimeout"`
+	// WriteTimeout in milliseconds of a single write attempt
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"`
+	// Compression is none, gzip, snappy, lz4 or zstd
+	Compression string `mapstructure:"compression"`
 }
 
+type KafkaTLSConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CaFile             string `mapstructure:"caFile"`
+	CertFile           string `mapstructure:"certFile"`
+	KeyFile            string `mapstructure:"keyFile"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+}
+
+type KafkaSASLConfig struct {
+	// Mechanism is plain, scram-sha-256 or scram-sha-512, empty disables SASL
+	Mechanism string `mapstructure:"mechanism"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+}
+
+const (
+	PartitionKeyAddressId = "addressId"
+	PartitionKeyUserId    = "userId"
+)
+
 func NewConfig() *Config {
 	env, _ := os.LookupEnv(environmentKey)
 	fmt.Println("Environment: [" + env + "] was successfully read from runtime arguments [" + environmentKey + "].")
@@ -0,0 +1,157 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AcksAll  = "all"
+	AcksOne  = "one"
+	AcksNone = "none"
+
+	SASLPlain       = "plain"
+	SASLScramSha256 = "scram-sha-256"
+	SASLScramSha512 = "scram-sha-512"
+)
+
+// kafka-go defaults of the writer settings left empty
+const (
+	defaultMaxAttempts     = 10
+	defaultWriteTimeout    = 10 * time.Second
+	defaultWriteBackoffMax = time.Second
+	defaultBatchTimeout    = time.Second
+)
+
+// MaxWriteTime is the longest a write of the producer takes before it fails, the batch waits for its timeout
+// and every attempt may take the write timeout followed by the longest backoff
+func MaxWriteTime(producerCfg config.KafkaProducerConfig) time.Duration {
+	attempts := producerCfg.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+
+	writeTimeout := orDefault(producerCfg.WriteTimeout*time.Millisecond, defaultWriteTimeout)
+	backoff := orDefault(producerCfg.RetryBackoffMax*time.Millisecond, defaultWriteBackoffMax)
+	batchTimeout := orDefault(producerCfg.BatchTimeout*time.Millisecond, defaultBatchTimeout)
+
+	return batchTimeout + time.Duration(attempts)*writeTimeout + time.Duration(attempts-1)*backoff
+}
+
+func orDefault(value, defaultValue time.Duration) time.Duration {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// parseRequiredAcks defaults to all, so a write is acknowledged by every in-sync replica
+func parseRequiredAcks(acks string) (kafka.RequiredAcks, error) {
+	switch strings.ToLower(acks) {
+	case "", AcksAll:
+		return kafka.RequireAll, nil
+	case AcksOne:
+		return kafka.RequireOne, nil
+	case AcksNone:
+		return kafka.RequireNone, nil
+	default:
+		return 0, errors.Errorf("kafka.parseRequiredAcks: unsupported required acks %q", acks)
+	}
+}
+
+func parseCompression(codec string) (kafka.Compression, error) {
+	switch strings.ToLower(codec) {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, errors.Errorf("kafka.parseCompression: unsupported compression %q", codec)
+	}
+}
+
+// newTransport returns nil when neither TLS nor SASL is configured
+func newTransport(tlsCfg config.KafkaTLSConfig, saslCfg config.KafkaSASLConfig) (*kafka.Transport, error) {
+	if !tlsCfg.Enabled && saslCfg.Mechanism == "" {
+		return nil, nil
+	}
+
+	transport := &kafka.Transport{}
+
+	if tlsCfg.Enabled {
+		tlsConfig, err := newTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLS = tlsConfig
+	}
+
+	if saslCfg.Mechanism != "" {
+		mechanism, err := newSASLMechanism(saslCfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+
+	return transport, nil
+}
+
+func newTLSConfig(cfg config.KafkaTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CaFile != "" {
+		ca, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "kafka.newTLSConfig.ReadCaFile")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("kafka.newTLSConfig: no certificate found in the ca file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// the client certificate is only needed by brokers requiring mutual TLS
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "kafka.newTLSConfig.LoadX509KeyPair")
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func newSASLMechanism(cfg config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	switch strings.ToLower(cfg.Mechanism) {
+	case SASLPlain:
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case SASLScramSha256:
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case SASLScramSha512:
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, errors.Errorf("kafka.newSASLMechanism: unsupported mechanism %q", cfg.Mechanism)
+	}
+}
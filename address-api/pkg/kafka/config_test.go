@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseRequiredAcks(t *testing.T) {
+	tests := []struct {
+		acks    string
+		want    kafka.RequiredAcks
+		wantErr bool
+	}{
+		{acks: "", want: kafka.RequireAll},
+		{acks: "all", want: kafka.RequireAll},
+		{acks: "ONE", want: kafka.RequireOne},
+		{acks: "none", want: kafka.RequireNone},
+		{acks: "two", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		acks, err := parseRequiredAcks(tt.acks)
+		if tt.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, acks)
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	tests := []struct {
+		codec   string
+		want    kafka.Compression
+		wantErr bool
+	}{
+		{codec: "", want: 0},
+		{codec: "none", want: 0},
+		{codec: "gzip", want: kafka.Gzip},
+		{codec: "snappy", want: kafka.Snappy},
+		{codec: "lz4", want: kafka.Lz4},
+		{codec: "zstd", want: kafka.Zstd},
+		{codec: "brotli", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		compression, err := parseCompression(tt.codec)
+		if tt.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, compression)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := newTransport(config.KafkaTLSConfig{}, config.KafkaSASLConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, transport)
+
+	transport, err = newTransport(config.KafkaTLSConfig{Enabled: true}, config.KafkaSASLConfig{Mechanism: SASLScramSha512, Username: "user", Password: "secret"})
+	assert.NoError(t, err)
+	assert.NotNil(t, transport.TLS)
+	assert.Equal(t, "SCRAM-SHA-512", transport.SASL.Name())
+
+	_, err = newTransport(config.KafkaTLSConfig{Enabled: true, CaFile: "missing.pem"}, config.KafkaSASLConfig{})
+	assert.Error(t, err)
+
+	_, err = newTransport(config.KafkaTLSConfig{}, config.KafkaSASLConfig{Mechanism: "gssapi"})
+	assert.Error(t, err)
+}
+
+func TestMaxWriteTime(t *testing.T) {
+	// an empty config uses the writer defaults, 1 s + 10 * 10 s + 9 * 1 s
+	assert.Equal(t, 110*time.Second, MaxWriteTime(config.KafkaProducerConfig{}))
+	assert.Equal(t, 10*time.Millisecond+2*5*time.Second+500*time.Millisecond, MaxWriteTime(config.KafkaProducerConfig{
+		MaxAttempts:     2,
+		WriteTimeout:    5000,
+		RetryBackoffMax: 500,
+		BatchTimeout:    10,
+	}))
+}
@@ -0,0 +1,84 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	cloudevents "github.com/sefikcan/address-events/cloudevents"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Producer is an autogenerated mock type for the Producer type
+type Producer struct {
+	mock.Mock
+}
+
+// Close provides a mock function with no fields
+func (_m *Producer) Close() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendEvent provides a mock function with given fields: ctx, topic, key, event
+func (_m *Producer) SendEvent(ctx context.Context, topic string, key string, event cloudevents.Event) error {
+	ret := _m.Called(ctx, topic, key, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, cloudevents.Event) error); ok {
+		r0 = rf(ctx, topic, key, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendMessage provides a mock function with given fields: ctx, topic, key, message, headers
+func (_m *Producer) SendMessage(ctx context.Context, topic string, key string, message string, headers map[string]string) error {
+	ret := _m.Called(ctx, topic, key, message, headers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendMessage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, map[string]string) error); ok {
+		r0 = rf(ctx, topic, key, message, headers)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewProducer creates a new instance of Producer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProducer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Producer {
+	mock := &Producer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
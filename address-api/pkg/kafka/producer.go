@@ -2,53 +2,172 @@ package kafka
 
 import (
 	"context"
-	"fmt"
+	"github.com/sefikcan/address-api/pkg/config"
+	"github.com/sefikcan/address-api/pkg/logger"
+	"github.com/sefikcan/address-api/pkg/metric"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/sefikcan/address-events/serde"
 	"github.com/segmentio/kafka-go"
-	"log"
+	"time"
 )
 
+// Producer publishes keyed messages, messages with the same key are written to the same partition
 type Producer interface {
-	SendMessage(ctx context.Context, topic, message string) error
+	SendMessage(ctx context.Context, topic, key, message string, headers map[string]string) error
+	// SendEvent publishes the event with the configured serializer and CloudEvents content mode,
+	// the request id and trace context are added as plain headers in every mode
+	SendEvent(ctx context.Context, topic, key string, event cloudevents.Event) error
 	Close() error
 }
 
 type KafkaProducer struct {
-	writer *kafka.Writer
+	writer     *kafka.Writer
+	eventMode  string
+	serializer serde.Serializer
+	metrics    metric.ProducerMetrics
+	logger     logger.Logger
 }
 
-func (k *KafkaProducer) SendMessage(ctx context.Context, topic, message string) error {
-	fmt.Printf("Sending message to Kafka: %s", message) // Mesajı logla
+// SendMessage returns once kafka acknowledged the message, the outbox relay marks a message published only then
+func (k *KafkaProducer) SendMessage(ctx context.Context, topic, key, message string, headers map[string]string) error {
 
-	err := k.writer.WriteMessages(ctx, kafka.Message{
-		Topic: topic,
-		Value: []byte(message),
-	})
+	kafkaHeaders := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	msg := kafka.Message{
+		Topic:   topic,
+		Value:   []byte(message),
+		Headers: kafkaHeaders,
+		// kept by the writer until the completion callback, which observes the latency from it
+		Time: time.Now(),
+	}
+	// messages without a key are spread over the partitions
+	if key != "" {
+		msg.Key = []byte(key)
+	}
+
+	err := k.writer.WriteMessages(ctx, msg)
 	if err != nil {
-		log.Printf("Failed to send message to Kafka: %s", err)
+		k.logger.Errorf("Failed to send message to Kafka, topic: %s, key: %s, error: %v", topic, key, err)
 		return err
 	}
 
-	log.Printf("Message sent to Kafka: %s", message)
+	k.logger.Debugf("Message sent to Kafka, topic: %s, key: %s", topic, key)
 	return nil
 }
 
+// complete is called by the writer for every produce request, messages of a call share a partition
+func (k *KafkaProducer) complete(messages []kafka.Message, err error) {
+	if len(messages) == 0 {
+		return
+	}
+
+	topic := messages[0].Topic
+	k.metrics.ObserveBatchSize(topic, len(messages))
+
+	if err != nil {
+		// the error is returned and logged by SendMessage
+		k.metrics.IncreaseFailed(topic, len(messages))
+		return
+	}
+
+	k.metrics.IncreaseSent(topic, len(messages))
+	for _, message := range messages {
+		k.metrics.ObserveLatency(topic, time.Since(message.Time).Seconds())
+	}
+}
+
+func (k *KafkaProducer) SendEvent(ctx context.Context, topic, key string, event cloudevents.Event) error {
+	headers, value, err := k.encode(topic, event)
+	if err != nil {
+		return err
+	}
+
+	return k.SendMessage(ctx, topic, key, string(value), headers)
+}
+
+// encode serializes the json data of the event, protobuf and avro data cannot be embedded in the
+// json envelope so those events are always sent in binary mode
+func (k *KafkaProducer) encode(topic string, event cloudevents.Event) (map[string]string, []byte, error) {
+	var headers map[string]string
+	var value []byte
+	var err error
+
+	if k.serializer.ContentType() == serde.ContentTypeJson {
+		headers, value, err = event.Encode(k.eventMode)
+	} else {
+		value, err = k.serializer.Serialize(serde.ValueSubject(topic), event.Data)
+		event.DataContentType = k.serializer.ContentType()
+		headers, _ = event.Binary()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, header := range event.CorrelationHeaders() {
+		headers[name] = header
+	}
+
+	return headers, value, nil
+}
+
+// Close waits for the writes in flight
 func (k *KafkaProducer) Close() error {
 	if err := k.writer.Close(); err != nil {
-		log.Printf("Failed to close Kafka writer: %v", err)
+		k.logger.Errorf("Failed to close Kafka writer: %v", err)
 		return err
 	}
 
-	log.Println("Kafka producer closed successfully")
+	k.logger.Info("Kafka producer closed successfully")
 	return nil
 }
 
-func NewKafkaProducer(brokers []string) Producer {
-	writer := &kafka.Writer{
-		Addr:     kafka.TCP(brokers...),
-		Balancer: &kafka.LeastBytes{},
+func NewKafkaProducer(cfg *config.Config, serializer serde.Serializer, metrics metric.ProducerMetrics, logger logger.Logger) (Producer, error) {
+	producerCfg := cfg.Kafka.Producer
+
+	requiredAcks, err := parseRequiredAcks(producerCfg.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	compression, err := parseCompression(producerCfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(cfg.Kafka.TLS, cfg.Kafka.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	producer := &KafkaProducer{
+		eventMode:  cfg.Kafka.EventMode,
+		serializer: serializer,
+		metrics:    metrics,
+		logger:     logger,
 	}
 
-	return &KafkaProducer{
-		writer: writer,
+	producer.writer = &kafka.Writer{
+		Addr: kafka.TCP(cfg.Kafka.Brokers...),
+		// the hash of the key selects the partition, so the events of an address stay ordered
+		Balancer:        &kafka.Hash{},
+		RequiredAcks:    requiredAcks,
+		MaxAttempts:     producerCfg.MaxAttempts,
+		WriteBackoffMin: producerCfg.RetryBackoffMin * time.Millisecond,
+		WriteBackoffMax: producerCfg.RetryBackoffMax * time.Millisecond,
+		BatchSize:       producerCfg.BatchSize,
+		BatchTimeout:    producerCfg.BatchTimeout * time.Millisecond,
+		WriteTimeout:    producerCfg.WriteTimeout * time.Millisecond,
+		Compression:     compression,
+		Completion:      producer.complete,
+		ErrorLogger:     kafka.LoggerFunc(logger.Errorf),
 	}
+	// the default transport is kept for plaintext brokers
+	if transport != nil {
+		producer.writer.Transport = transport
+	}
+
+	return producer, nil
 }
@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"errors"
+	"github.com/sefikcan/address-api/internal/address/service/mocks"
+	"github.com/sefikcan/address-api/pkg/config"
+	mocks2 "github.com/sefikcan/address-api/pkg/metric/mocks"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/sefikcan/address-events/registry"
+	"github.com/sefikcan/address-events/serde"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testAvroSchema = `{"type":"record","name":"Address","fields":[{"name":"addressId","type":"long"}]}`
+
+func TestKafkaProducer_Encode(t *testing.T) {
+	schemaRegistry, err := registry.NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	assert.NoError(t, err)
+
+	jsonSerializer, _ := serde.NewSerializer(serde.FormatJson, schemaRegistry, "")
+	avroSerializer, err := serde.NewSerializer(serde.FormatAvro, schemaRegistry, testAvroSchema)
+	assert.NoError(t, err)
+
+	event, _ := cloudevents.New("/address-api", "com.sefikcan.address.created", "urn:test:v1", map[string]int{"addressId": 1})
+	event.RequestId = "req-1"
+	event.Traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name            string
+		eventMode       string
+		serializer      serde.Serializer
+		wantContentType string
+		wantCeHeaders   bool
+	}{
+		{name: "json structured", eventMode: cloudevents.ModeStructured, serializer: jsonSerializer, wantContentType: cloudevents.ContentTypeStructured},
+		{name: "json binary", eventMode: cloudevents.ModeBinary, serializer: jsonSerializer, wantContentType: serde.ContentTypeJson, wantCeHeaders: true},
+		{name: "avro is always binary", eventMode: cloudevents.ModeStructured, serializer: avroSerializer, wantContentType: serde.ContentTypeAvro, wantCeHeaders: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &KafkaProducer{eventMode: tt.eventMode, serializer: tt.serializer}
+
+			headers, value, err := producer.encode("address-created", event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantContentType, headers[cloudevents.HeaderContentType])
+			assert.Equal(t, tt.wantCeHeaders, headers["ce_id"] == event.Id)
+			assert.Equal(t, "req-1", headers[cloudevents.HeaderRequestId])
+			assert.Equal(t, event.Traceparent, headers[cloudevents.HeaderTraceparent])
+
+			// consumers decode every combination back into the same event
+			decoded, err := cloudevents.Decode(headers, value)
+			assert.NoError(t, err)
+			assert.Equal(t, event.Id, decoded.Id)
+
+			deserializer, err := serde.ForContentType(decoded.DataContentType, schemaRegistry)
+			assert.NoError(t, err)
+			data, err := deserializer.Deserialize(decoded.Data)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"addressId":1}`, string(data))
+		})
+	}
+}
+
+func TestNewKafkaProducer_AppliesProducerConfig(t *testing.T) {
+	cfg := &config.Config{Kafka: config.KafkaConfig{
+		Brokers: []string{"localhost:9092"},
+		Producer: config.KafkaProducerConfig{
+			RequiredAcks:    "one",
+			MaxAttempts:     5,
+			RetryBackoffMin: 100,
+			RetryBackoffMax: 1000,
+			BatchSize:       50,
+			BatchTimeout:    20,
+			WriteTimeout:    3000,
+			Compression:     "zstd",
+		},
+	}}
+
+	producer, err := NewKafkaProducer(cfg, nil, new(mocks2.ProducerMetrics), new(mocks.Logger))
+	assert.NoError(t, err)
+
+	writer := producer.(*KafkaProducer).writer
+	assert.Equal(t, kafka.RequireOne, writer.RequiredAcks)
+	assert.Equal(t, 5, writer.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, writer.WriteBackoffMin)
+	assert.Equal(t, time.Second, writer.WriteBackoffMax)
+	assert.Equal(t, 50, writer.BatchSize)
+	assert.Equal(t, 20*time.Millisecond, writer.BatchTimeout)
+	assert.Equal(t, 3*time.Second, writer.WriteTimeout)
+	assert.False(t, writer.Async)
+	assert.Equal(t, kafka.Zstd, writer.Compression)
+	assert.Nil(t, writer.Transport)
+
+	cfg.Kafka.Producer.Compression = "brotli"
+	_, err = NewKafkaProducer(cfg, nil, new(mocks2.ProducerMetrics), new(mocks.Logger))
+	assert.Error(t, err)
+}
+
+func TestKafkaProducer_CompleteRecordsMetrics(t *testing.T) {
+	messages := []kafka.Message{
+		{Topic: "address-created", Key: []byte("1"), Time: time.Now()},
+		{Topic: "address-created", Key: []byte("1"), Time: time.Now()},
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		metrics := new(mocks2.ProducerMetrics)
+		metrics.On("ObserveBatchSize", "address-created", 2).Once()
+		metrics.On("IncreaseSent", "address-created", 2).Once()
+		metrics.On("ObserveLatency", "address-created", mock.AnythingOfType("float64")).Twice()
+
+		producer := &KafkaProducer{writer: &kafka.Writer{}, metrics: metrics, logger: new(mocks.Logger)}
+		producer.complete(messages, nil)
+
+		metrics.AssertExpectations(t)
+	})
+
+	t.Run("failed delivery", func(t *testing.T) {
+		metrics := new(mocks2.ProducerMetrics)
+		metrics.On("ObserveBatchSize", "address-created", 2).Once()
+		metrics.On("IncreaseFailed", "address-created", 2).Once()
+
+		producer := &KafkaProducer{writer: &kafka.Writer{}, metrics: metrics, logger: new(mocks.Logger)}
+		producer.complete(messages, errors.New("broker unavailable"))
+
+		metrics.AssertExpectations(t)
+	})
+}
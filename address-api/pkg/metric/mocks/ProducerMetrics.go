@@ -0,0 +1,44 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// ProducerMetrics is an autogenerated mock type for the ProducerMetrics type
+type ProducerMetrics struct {
+	mock.Mock
+}
+
+// IncreaseFailed provides a mock function with given fields: topic, count
+func (_m *ProducerMetrics) IncreaseFailed(topic string, count int) {
+	_m.Called(topic, count)
+}
+
+// IncreaseSent provides a mock function with given fields: topic, count
+func (_m *ProducerMetrics) IncreaseSent(topic string, count int) {
+	_m.Called(topic, count)
+}
+
+// ObserveBatchSize provides a mock function with given fields: topic, size
+func (_m *ProducerMetrics) ObserveBatchSize(topic string, size int) {
+	_m.Called(topic, size)
+}
+
+// ObserveLatency provides a mock function with given fields: topic, seconds
+func (_m *ProducerMetrics) ObserveLatency(topic string, seconds float64) {
+	_m.Called(topic, seconds)
+}
+
+// NewProducerMetrics creates a new instance of ProducerMetrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProducerMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ProducerMetrics {
+	mock := &ProducerMetrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
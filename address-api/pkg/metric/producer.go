@@ -0,0 +1,81 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"log"
+)
+
+// ProducerMetrics records the delivery of kafka messages per topic
+type ProducerMetrics interface {
+	IncreaseSent(topic string, count int)
+	IncreaseFailed(topic string, count int)
+	ObserveLatency(topic string, seconds float64)
+	ObserveBatchSize(topic string, size int)
+}
+
+type producerMetrics struct {
+	Sent      *prometheus.CounterVec
+	Failed    *prometheus.CounterVec
+	Latency   *prometheus.HistogramVec
+	BatchSize *prometheus.HistogramVec
+}
+
+func (metric *producerMetrics) IncreaseSent(topic string, count int) {
+	metric.Sent.WithLabelValues(topic).Add(float64(count))
+}
+
+func (metric *producerMetrics) IncreaseFailed(topic string, count int) {
+	metric.Failed.WithLabelValues(topic).Add(float64(count))
+}
+
+func (metric *producerMetrics) ObserveLatency(topic string, seconds float64) {
+	metric.Latency.WithLabelValues(topic).Observe(seconds)
+}
+
+func (metric *producerMetrics) ObserveBatchSize(topic string, size int) {
+	metric.BatchSize.WithLabelValues(topic).Observe(float64(size))
+}
+
+// CreateProducerMetrics registers the producer metrics, they are served by the metrics server of CreateMetrics
+func CreateProducerMetrics(name string) (ProducerMetrics, error) {
+	var metric producerMetrics
+	metric.Sent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_kafka_messages_sent_total",
+		Help: "Messages acknowledged by kafka.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Sent); err != nil {
+		log.Printf("Error registering Sent: %v", err)
+		return nil, err
+	}
+
+	metric.Failed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_kafka_messages_failed_total",
+		Help: "Messages kafka failed to acknowledge after every attempt.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Failed); err != nil {
+		log.Printf("Error registering Failed: %v", err)
+		return nil, err
+	}
+
+	metric.Latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name + "_kafka_send_latency_seconds",
+		Help:    "Time from handing a message to the producer until kafka acknowledged it.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Latency); err != nil {
+		log.Printf("Error registering Latency: %v", err)
+		return nil, err
+	}
+
+	metric.BatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name + "_kafka_batch_size",
+		Help:    "Messages written in a single produce request.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.BatchSize); err != nil {
+		log.Printf("Error registering BatchSize: %v", err)
+		return nil, err
+	}
+
+	return &metric, nil
+}
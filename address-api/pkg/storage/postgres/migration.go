@@ -0,0 +1,336 @@
+package postgres
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// Migration is a single versioned schema change
+// Up runs inside a transaction and every migration is applied only once
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *gorm.DB) error
+}
+
+// schemaMigration keeps the applied migration versions
+type schemaMigration struct {
+	Version     int `gorm:"primaryKey;autoIncrement:false"`
+	Description string
+	AppliedAt   time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// addressV1 is the addresses table as it was shipped in the first version
+type addressV1 struct {
+	Id          int `gorm:"primary_key"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	City        string
+	Country     string
+	FullAddress string
+	UserId      string
+}
+
+func (addressV1) TableName() string {
+	return "addresses"
+}
+
+// addressV2 adds the structured address components
+type addressV2 struct {
+	AddressLine1 string
+	AddressLine2 string
+	HouseNumber  string
+	District     string
+	Region       string
+	PostalCode   string `gorm:"index"`
+	CountryCode  string `gorm:"size:2"`
+	Latitude     *float64
+	Longitude    *float64
+}
+
+func (addressV2) TableName() string {
+	return "addresses"
+}
+
+// apiKeyV3 is the api_keys table used for server-to-server authentication
+type apiKeyV3 struct {
+	Id         int `gorm:"primary_key"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Name       string
+	Owner      string `gorm:"index"`
+	Prefix     string `gorm:"uniqueIndex;size:16"`
+	KeyHash    string `gorm:"size:64"`
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiKeyV3) TableName() string {
+	return "api_keys"
+}
+
+// addressV4 and apiKeyV4 add the tenant of every row
+type addressV4 struct {
+	TenantId string `gorm:"index;size:64"`
+}
+
+func (addressV4) TableName() string {
+	return "addresses"
+}
+
+type apiKeyV4 struct {
+	TenantId string `gorm:"index;size:64"`
+}
+
+func (apiKeyV4) TableName() string {
+	return "api_keys"
+}
+
+// outboxMessageV5 stores the events written in the transaction of an address change
+type outboxMessageV5 struct {
+	Id            int64 `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	AggregateType string `gorm:"size:50"`
+	AggregateId   string `gorm:"size:64;index"`
+	TenantId      string `gorm:"size:64"`
+	Topic         string
+	Payload       string `gorm:"type:text"`
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	PublishedAt   *time.Time `gorm:"index"`
+}
+
+func (outboxMessageV5) TableName() string {
+	return "outbox_messages"
+}
+
+// outboxMessageV6 adds the kafka message key of the event
+type outboxMessageV6 struct {
+	PartitionKey string `gorm:"size:255"`
+}
+
+func (outboxMessageV6) TableName() string {
+	return "outbox_messages"
+}
+
+// outboxMessageV9 adds the time the relay gave up on a message
+type outboxMessageV9 struct {
+	DeadAt *time.Time `gorm:"index"`
+}
+
+func (outboxMessageV9) TableName() string {
+	return "outbox_messages"
+}
+
+// addressV7 adds the version of every address
+type addressV7 struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+func (addressV7) TableName() string {
+	return "addresses"
+}
+
+// webhookSubscriptionV8 is a partner endpoint notified about address events
+type webhookSubscriptionV8 struct {
+	Id         int `gorm:"primary_key"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	TenantId   string `gorm:"index;size:64"`
+	Url        string `gorm:"size:2048"`
+	EventTypes string
+	Secret     string
+	Active     bool
+}
+
+func (webhookSubscriptionV8) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// webhookDeliveryV8 is an event posted to a subscription, the address consumer delivers the pending rows
+type webhookDeliveryV8 struct {
+	Id             int `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	TenantId       string `gorm:"index;size:64"`
+	SubscriptionId int    `gorm:"uniqueIndex:idx_webhook_deliveries_event"`
+	EventId        string `gorm:"uniqueIndex:idx_webhook_deliveries_event;size:64"`
+	EventType      string
+	Payload        string `gorm:"type:text"`
+	Status         string `gorm:"index;size:16"`
+	Attempts       int
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+func (webhookDeliveryV8) TableName() string {
+	return "webhook_deliveries"
+}
+
+// DefaultTenantId owns the rows created before multi-tenancy
+const DefaultTenantId = "default"
+
+// Migrations returns every known migration ordered by version
+func Migrations() []Migration {
+	migrations := []Migration{
+		{
+			Version:     1,
+			Description: "create addresses table",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&addressV1{})
+			},
+		},
+		{
+			Version:     2,
+			Description: "add structured address components",
+			Up: func(tx *gorm.DB) error {
+				// existing rows only have the free-text address, their address lines stay empty
+				return tx.AutoMigrate(&addressV2{})
+			},
+		},
+		{
+			Version:     3,
+			Description: "create api_keys table",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&apiKeyV3{})
+			},
+		},
+		{
+			Version:     4,
+			Description: "add tenant to addresses and api_keys",
+			Up: func(tx *gorm.DB) error {
+				for _, model := range []interface{}{&addressV4{}, &apiKeyV4{}} {
+					if err := tx.AutoMigrate(model); err != nil {
+						return err
+					}
+
+					err := tx.Model(model).
+						Where("tenant_id IS NULL OR tenant_id = ''").
+						Update("tenant_id", DefaultTenantId).Error
+					if err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Version:     5,
+			Description: "create outbox_messages table",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&outboxMessageV5{})
+			},
+		},
+		{
+			Version:     6,
+			Description: "add partition key to outbox_messages",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&outboxMessageV6{}); err != nil {
+					return err
+				}
+
+				// pending events were keyed by their aggregate
+				return tx.Model(&outboxMessageV6{}).
+					Where("partition_key IS NULL OR partition_key = ''").
+					Update("partition_key", gorm.Expr("aggregate_id")).Error
+			},
+		},
+		{
+			Version:     7,
+			Description: "add version to addresses",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&addressV7{}); err != nil {
+					return err
+				}
+
+				// existing addresses start their history now
+				return tx.Model(&addressV7{}).
+					Where("version IS NULL OR version = 0").
+					Update("version", 1).Error
+			},
+		},
+		{
+			Version:     8,
+			Description: "create webhook tables",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&webhookSubscriptionV8{}, &webhookDeliveryV8{})
+			},
+		},
+		{
+			Version:     9,
+			Description: "add dead_at to outbox_messages",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&outboxMessageV9{})
+			},
+		},
+		{
+			Version:     10,
+			Description: "clear address lines copied from the full address",
+			Up: func(tx *gorm.DB) error {
+				// version 2 used to copy the free-text address, which already holds the city and country, into
+				// the first line, composing the full address from it repeated them
+				return tx.Model(&addressV2{}).
+					Where("address_line1 = full_address").
+					Update("address_line1", "").Error
+			},
+		},
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations
+}
+
+// Migrate applies the pending migrations in version order
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return errors.Wrap(err, "postgres.Migrate.SchemaMigrationsTable")
+	}
+
+	var applied []int
+	if err := db.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return errors.Wrap(err, "postgres.Migrate.AppliedVersions")
+	}
+
+	appliedVersions := make(map[int]bool, len(applied))
+	for _, version := range applied {
+		appliedVersions[version] = true
+	}
+
+	for _, migration := range Migrations() {
+		if appliedVersions[migration.Version] {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&schemaMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return errors.Wrapf(err, "postgres.Migrate.Version%d", migration.Version)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+func setupMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+
+	return db
+}
+
+func TestMigrate_AppliesAllVersionsOnce(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	assert.NoError(t, Migrate(db))
+	assert.NoError(t, Migrate(db))
+
+	var count int64
+	db.Model(&schemaMigration{}).Count(&count)
+	assert.Equal(t, int64(len(Migrations())), count)
+	assert.True(t, db.Migrator().HasColumn(&addressV2{}, "postal_code"))
+	assert.True(t, db.Migrator().HasTable(&apiKeyV3{}))
+	assert.True(t, db.Migrator().HasTable(&outboxMessageV5{}))
+	assert.True(t, db.Migrator().HasColumn(&outboxMessageV6{}, "partition_key"))
+	assert.True(t, db.Migrator().HasColumn(&addressV7{}, "version"))
+	assert.True(t, db.Migrator().HasTable(&webhookSubscriptionV8{}))
+	assert.True(t, db.Migrator().HasTable(&webhookDeliveryV8{}))
+	assert.True(t, db.Migrator().HasColumn(&outboxMessageV9{}, "dead_at"))
+}
+
+func TestMigrate_BackfillsLegacyRows(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	// simulate a database created by the first release
+	assert.NoError(t, db.AutoMigrate(&schemaMigration{}))
+	assert.NoError(t, db.AutoMigrate(&addressV1{}))
+	assert.NoError(t, db.Create(&schemaMigration{Version: 1, Description: "create addresses table"}).Error)
+	assert.NoError(t, db.Create(&addressV1{Id: 1, City: "Istanbul", Country: "Turkey", FullAddress: "Bagdat Cad. No 1", UserId: "1"}).Error)
+
+	assert.NoError(t, Migrate(db))
+
+	// the free-text address is not split, composing it into a first line would repeat the city and country
+	var line1 string
+	db.Table("addresses").Where("id = ?", 1).Select("address_line1").Scan(&line1)
+	assert.Empty(t, line1)
+
+	var tenantId string
+	db.Table("addresses").Where("id = ?", 1).Select("tenant_id").Scan(&tenantId)
+	assert.Equal(t, DefaultTenantId, tenantId)
+
+	var version int64
+	db.Table("addresses").Where("id = ?", 1).Select("version").Scan(&version)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestMigrate_ClearsAddressLinesCopiedFromTheFullAddress(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	assert.NoError(t, Migrate(db))
+
+	// rows backfilled by an earlier version 2 and a structured row
+	assert.NoError(t, db.Table("addresses").Create(map[string]interface{}{"id": 1, "full_address": "Bagdat Cad. No 1, Istanbul, Turkey", "address_line1": "Bagdat Cad. No 1, Istanbul, Turkey"}).Error)
+	assert.NoError(t, db.Table("addresses").Create(map[string]interface{}{"id": 2, "full_address": "Bagdat Cad. 12, Istanbul, Turkey", "address_line1": "Bagdat Cad."}).Error)
+	assert.NoError(t, db.Where("version = ?", 10).Delete(&schemaMigration{}).Error)
+
+	assert.NoError(t, Migrate(db))
+
+	var lines []string
+	db.Table("addresses").Order("id").Pluck("address_line1", &lines)
+	assert.Equal(t, []string{"", "Bagdat Cad."}, lines)
+}
@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"fmt"
-	"github.com/sefikcan/address-api/internal/address/entity"
 	"github.com/sefikcan/address-api/pkg/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -21,7 +20,10 @@ func NewPsqlDb(c *config.Config) (*gorm.DB, error) {
 		c.Postgres.DbName)
 
 	// open postgresql connection
-	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{
+		// dialect errors such as unique violations are translated to gorm errors, e.g. gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -42,10 +44,8 @@ func NewPsqlDb(c *config.Config) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// Automatically migrate schema for all specified models
-	if err := db.AutoMigrate(
-		&entity.Address{},
-	); err != nil {
+	// Apply versioned schema migrations
+	if err := Migrate(db); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTransactor_CommitsAndRollsBack(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&addressV1{}))
+	transactor := NewTransactor(db)
+
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		return DB(ctx, db).Create(&addressV1{City: "Istanbul"}).Error
+	})
+	assert.NoError(t, err)
+
+	err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := DB(ctx, db).Create(&addressV1{City: "Ankara"}).Error; err != nil {
+			return err
+		}
+		// nested calls join the outer transaction and are rolled back with it
+		_ = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return DB(ctx, db).Create(&addressV1{City: "Izmir"}).Error
+		})
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	var cities []string
+	db.Model(&addressV1{}).Pluck("city", &cities)
+	assert.Equal(t, []string{"Istanbul"}, cities)
+}
@@ -6,16 +6,34 @@ import (
 	"github.com/sefikcan/address-api/pkg/logger"
 )
 
+// GetRequestId returns the request id sent by the client or generated by the requestid middleware
 func GetRequestId(c *fiber.Ctx) string {
-	return c.Get(fiber.HeaderXRequestID)
+	if requestId := c.Get(fiber.HeaderXRequestID); requestId != "" {
+		return requestId
+	}
+	return c.GetRespHeader(fiber.HeaderXRequestID)
 }
 
 func GetIPAddress(c *fiber.Ctx) string {
 	return c.IP()
 }
 
+type requestIdKey struct{}
+
+// GetRequestCtx returns the user context of the request carrying its request id
 func GetRequestCtx(c *fiber.Ctx) context.Context {
-	return context.WithValue(c.UserContext(), "RequestCtx", GetRequestId(c))
+	return WithRequestId(c.UserContext(), GetRequestId(c))
+}
+
+// WithRequestId returns a copy of ctx carrying the request id
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestIdFromContext returns the request id stored by GetRequestCtx
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
 }
 
 func PrepareLogging(c *fiber.Ctx, logger logger.Logger, err error) {
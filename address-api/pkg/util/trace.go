@@ -0,0 +1,58 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// traceparentPattern is the version 00 format of the W3C trace context header
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// TraceContext is the W3C trace context a request belongs to
+type TraceContext struct {
+	Traceparent string
+	Tracestate  string
+}
+
+type traceContextKey struct{}
+
+// WithTraceContext returns a copy of ctx carrying the trace context
+func WithTraceContext(ctx context.Context, traceContext TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext)
+}
+
+// TraceContextFromContext returns the trace context stored by WithTraceContext
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	traceContext, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return traceContext
+}
+
+// IsValidTraceparent reports whether the header is a version 00 traceparent with non-zero ids
+func IsValidTraceparent(traceparent string) bool {
+	match := traceparentPattern.FindStringSubmatch(traceparent)
+	return match != nil && !isZero(match[1]) && !isZero(match[2])
+}
+
+// NewTraceparent starts a new sampled trace
+func NewTraceparent() string {
+	ids := make([]byte, 24)
+	_, _ = rand.Read(ids)
+
+	return "00-" + hex.EncodeToString(ids[:16]) + "-" + hex.EncodeToString(ids[16:]) + "-01"
+}
+
+func isZero(id string) bool {
+	for _, c := range id {
+		if c != '0' {
+			return false
+		}
+	}
+	return true
+}
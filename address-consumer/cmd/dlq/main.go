@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/sefikcan/address-consumer/internal/consumer"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const usage = `usage:
+  dlq list -topic <source topic> [-limit 20] [-payload]
+  dlq redrive -topic <source topic> [-limit 0] [-idle 5s]
+
+list prints the dead letters of the source topic without committing any offset,
+redrive publishes them back to the source topic, a limit of 0 re-drives every dead letter`
+
+func main() {
+	if len(os.Args) < 2 {
+		exit(usage)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	topic := flags.String("topic", "", "source topic of the dead-letter topic, e.g. address-created")
+	limit := flags.Int("limit", 0, "maximum number of messages")
+	payload := flags.Bool("payload", false, "print the message value")
+	idle := flags.Duration("idle", 5*time.Second, "redrive stops when no dead letter arrives for this duration")
+	_ = flags.Parse(os.Args[2:])
+
+	if *topic == "" {
+		exit(usage)
+	}
+
+	cfg := config.NewConfig()
+	admin, err := consumer.NewDeadLetterAdmin(cfg.Kafka)
+	if err != nil {
+		exit(err.Error())
+	}
+	defer admin.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "list":
+		if *limit == 0 {
+			*limit = 20
+		}
+		err = admin.Inspect(ctx, *topic, *limit, func(deadLetter consumer.DeadLetter) {
+			fmt.Printf("%s/%d@%d key=%s original=%s/%d@%d attempts=%d first-failed=%s last-failed=%s error=%q\n",
+				deadLetter.Topic, deadLetter.Partition, deadLetter.Offset, deadLetter.Key,
+				deadLetter.OriginalTopic, deadLetter.OriginalPartition, deadLetter.OriginalOffset, deadLetter.Attempts,
+				deadLetter.FirstFailedAt.Format(time.RFC3339), deadLetter.LastFailedAt.Format(time.RFC3339), deadLetter.Error)
+			if *payload {
+				fmt.Printf("  %s\n", deadLetter.Value)
+			}
+		})
+	case "redrive":
+		var redriven int
+		redriven, err = admin.Redrive(ctx, *topic, *limit, *idle)
+		fmt.Printf("%d dead letters re-driven to %s\n", redriven, *topic)
+	default:
+		exit(usage)
+	}
+
+	if err != nil {
+		exit(err.Error())
+	}
+}
+
+func exit(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/sefikcan/address-consumer/internal/consumer"
+	"github.com/sefikcan/address-consumer/internal/projection"
+	"github.com/sefikcan/address-consumer/internal/service"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/registry"
+	"github.com/segmentio/kafka-go"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage:
+  rebuild [-keep]
+
+rebuild deletes the address read model and replays the address topics from the beginning into it,
+-keep replays into the existing read model, events it already has are skipped by their version`
+
+func main() {
+	flags := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	keep := flags.Bool("keep", false, "do not delete the read model before the replay")
+	_ = flags.Parse(os.Args[1:])
+
+	cfg := config.NewConfig()
+	log := logger.NewLogger(cfg)
+	log.InitLogger()
+
+	schemaRegistry, err := registry.NewFileRegistry(cfg.Kafka.SchemaRegistryPath)
+	if err != nil {
+		exit(err.Error())
+	}
+	decoder := service.NewAddressEventDecoder(schemaRegistry)
+
+	db, err := projection.NewDb(cfg)
+	if err != nil {
+		exit(err.Error())
+	}
+	projector := projection.NewProjector(db, log)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if !*keep {
+		if err := projector.Reset(ctx); err != nil {
+			exit(err.Error())
+		}
+	}
+
+	// events are ordered by their address version, so the topics can be replayed one after another,
+	// webhooks were notified when the events were consumed so the replay does not notify them again
+	services := []struct {
+		topic         string
+		businessLogic consumer.BusinessLogic
+	}{
+		{events.KafkaTopics.AddressCreated, service.NewAddressCreatedService(log, decoder, projector, nil)},
+		{events.KafkaTopics.AddressUpdated, service.NewAddressUpdatedService(log, decoder, projector, nil)},
+		{events.KafkaTopics.AddressDeleted, service.NewAddressDeletedService(log, decoder, projector, nil)},
+	}
+
+	for _, s := range services {
+		replayed, skipped := 0, 0
+		err := consumer.Replay(ctx, cfg.Kafka, s.topic, func(msg kafka.Message) error {
+			err := s.businessLogic.ProcessMessage(ctx, msg)
+			// messages the consumer could never process are in the dead-letter topic, the replay skips them too
+			if consumer.IsPermanent(err) {
+				log.Warnf("Message skipped (%s): partition %d, offset %d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+				skipped++
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s/%d@%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+			}
+
+			replayed++
+			return nil
+		})
+		if err != nil {
+			exit(err.Error())
+		}
+
+		fmt.Printf("%d events of %s replayed, %d skipped\n", replayed, s.topic, skipped)
+	}
+
+	_ = log.Sync()
+}
+
+func exit(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -2,12 +2,22 @@ package main
 
 import (
 	"context"
-	"github.com/sefikcan/address-consumer/internal/constants"
+	"github.com/sefikcan/address-consumer/internal/admin"
 	"github.com/sefikcan/address-consumer/internal/consumer"
+	"github.com/sefikcan/address-consumer/internal/dedup"
+	"github.com/sefikcan/address-consumer/internal/projection"
 	"github.com/sefikcan/address-consumer/internal/service"
+	"github.com/sefikcan/address-consumer/internal/webhook"
 	"github.com/sefikcan/address-consumer/pkg/config"
 	"github.com/sefikcan/address-consumer/pkg/logger"
-	"sync"
+	"github.com/sefikcan/address-consumer/pkg/metric"
+	"github.com/sefikcan/address-consumer/pkg/storage/postgres"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/registry"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -15,28 +25,128 @@ func main() {
 	log := logger.NewLogger(cfg)
 	log.InitLogger()
 
+	schemaRegistry, err := registry.NewFileRegistry(cfg.Kafka.SchemaRegistryPath)
+	if err != nil {
+		log.Fatalf("Schema registry could not be loaded: %v", err)
+	}
+	decoder := service.NewAddressEventDecoder(schemaRegistry)
+
+	var projector projection.Projector
+	if cfg.Projection.Enabled {
+		db, err := projection.NewDb(cfg)
+		if err != nil {
+			log.Fatalf("Projection store could not be configured: %v", err)
+		}
+		projector = projection.NewProjector(db, log)
+	}
+
+	var notifier webhook.Notifier
+	var dispatcher *webhook.Dispatcher
+	if cfg.Webhook.Enabled {
+		// the webhook subscriptions and deliveries are kept in the database of the address api
+		db, err := postgres.NewPsqlDb(cfg)
+		if err != nil {
+			log.Fatalf("Webhook store could not be configured: %v", err)
+		}
+		notifier = webhook.NewNotifier(db, log)
+		dispatcher, err = webhook.NewDispatcher(cfg.Webhook, db, log)
+		if err != nil {
+			log.Fatalf("Webhook dispatcher could not be configured: %v", err)
+		}
+	}
+
 	services := map[string]consumer.BusinessLogic{
-		constants.KafkaTopics.AddressCreated: service.NewAddressCreatedService(log),
-		constants.KafkaTopics.AddressDeleted: service.NewAddressDeletedService(log),
-		constants.KafkaTopics.AddressUpdated: service.NewAddressUpdatedService(log),
+		events.KafkaTopics.AddressCreated: service.NewAddressCreatedService(log, decoder, projector, notifier),
+		events.KafkaTopics.AddressDeleted: service.NewAddressDeletedService(log, decoder, projector, notifier),
+		events.KafkaTopics.AddressUpdated: service.NewAddressUpdatedService(log, decoder, projector, notifier),
+	}
+
+	metrics, err := metric.CreateMetrics(cfg.Metric.ServiceName)
+	if err != nil {
+		log.Fatalf("CreateMetrics error: %v", err)
+	}
+
+	// redelivered events are skipped before they reach the services
+	if cfg.Dedup.Enabled {
+		store, err := dedup.NewStore(cfg)
+		if err != nil {
+			log.Fatalf("Dedup store could not be configured: %v", err)
+		}
+
+		ttl := time.Duration(cfg.Dedup.TtlHours) * time.Hour
+		for topic, businessLogic := range services {
+			services[topic] = dedup.NewHandler(businessLogic, store, ttl, cfg.Dedup.KeyPrefix, metrics, log)
+		}
+	}
+
+	var deadLetter *consumer.DeadLetterRouter
+	if cfg.Kafka.DeadLetter.Enabled {
+		deadLetter, err = consumer.NewDeadLetterRouter(cfg.Kafka, log)
+		if err != nil {
+			log.Fatalf("Dead-letter router could not be configured: %v", err)
+		}
 	}
 
-	var wg sync.WaitGroup
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// every consumer is validated before any of them starts reading
+	supervisor := consumer.NewSupervisor(cfg.Consumer, log)
 	for topic, businessLogic := range services {
-		wg.Add(1)
-		go func(topic string, logic consumer.BusinessLogic) {
-			defer wg.Done()
-			kafkaConsumer, err := consumer.NewKafkaConsumer(cfg, log, topic, logic)
+		topic, businessLogic := topic, businessLogic
+		err := supervisor.Add(topic, func() (*consumer.KafkaConsumer, error) {
+			return consumer.NewKafkaConsumer(cfg, log, topic, businessLogic, deadLetter, metrics)
+		})
+		if err != nil {
+			log.Fatalf("Consumer could not be configured (%s): %v", topic, err)
+		}
+
+		if deadLetter == nil {
+			continue
+		}
+
+		for _, retryTopic := range deadLetter.RetryTopics(topic) {
+			retryTopic := retryTopic
+			err := supervisor.Add(retryTopic.Name, func() (*consumer.KafkaConsumer, error) {
+				return consumer.NewRetryConsumer(cfg, log, retryTopic, businessLogic, deadLetter, metrics)
+			})
 			if err != nil {
-				log.Errorf("Consumer could not be started (%s): %v", topic, err)
-				return
+				log.Fatalf("Retry consumer could not be configured (%s): %v", retryTopic.Name, err)
 			}
+		}
+	}
 
-			if err := kafkaConsumer.Start(context.Background()); err != nil {
-				log.Errorf("An error occurred while running Consumer (%s): %v", topic, err)
-			}
-		}(topic, businessLogic)
+	// the admin server outlives the consumers, so the readiness probe reports them draining
+	adminServer := admin.NewServer(cfg.Metric.Url, supervisor, log)
+	go adminServer.Start()
+
+	dispatcherDone := make(chan struct{})
+	if dispatcher != nil {
+		go func() {
+			defer close(dispatcherDone)
+			dispatcher.Run(ctx)
+		}()
+	} else {
+		close(dispatcherDone)
+	}
+
+	// blocks until a shutdown signal stopped every consumer
+	supervisor.Run(ctx)
+	<-dispatcherDone
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Admin server could not be shut down: %v", err)
+	}
+
+	// the dead-letter writer is closed after the consumers, so their last messages are flushed
+	if deadLetter != nil {
+		if err := deadLetter.Close(); err != nil {
+			log.Errorf("Dead-letter router could not be closed: %v", err)
+		}
 	}
 
-	wg.Wait()
+	log.Info("Consumers stopped, shutting down")
+	_ = log.Sync()
 }
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sefikcan/address-consumer/internal/consumer"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	"net/http"
+	"time"
+)
+
+const (
+	statusUp   = "UP"
+	statusDown = "DOWN"
+)
+
+// HealthResponse is the body of the health endpoints
+type HealthResponse struct {
+	Status    string                    `json:"status"`
+	Consumers []consumer.ConsumerStatus `json:"consumers,omitempty"`
+}
+
+// Server serves the metrics and the health of the consumers
+type Server struct {
+	server     *http.Server
+	supervisor *consumer.Supervisor
+	logger     logger.Logger
+}
+
+func NewServer(address string, supervisor *consumer.Supervisor, logger logger.Logger) *Server {
+	s := &Server{
+		supervisor: supervisor,
+		logger:     logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/health/live", s.live)
+	mux.HandleFunc("/health/ready", s.ready)
+
+	s.server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+// Start blocks until the server is shut down
+func (s *Server) Start() {
+	s.logger.Infof("Admin server is running on: %s", s.server.Addr)
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Errorf("Admin server stopped: %v", err)
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// live only reports that the process serves requests, crashed consumers are restarted by the supervisor
+func (s *Server) live(w http.ResponseWriter, r *http.Request) {
+	s.write(w, http.StatusOK, HealthResponse{Status: statusUp})
+}
+
+// ready is down while any consumer is not running, can not reach the broker or its group rebalances
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	statuses := s.supervisor.Status()
+
+	ready := len(statuses) > 0
+	for _, status := range statuses {
+		if !status.Ready() {
+			ready = false
+		}
+	}
+
+	if !ready {
+		s.write(w, http.StatusServiceUnavailable, HealthResponse{Status: statusDown, Consumers: statuses})
+		return
+	}
+	s.write(w, http.StatusOK, HealthResponse{Status: statusUp, Consumers: statuses})
+}
+
+func (s *Server) write(w http.ResponseWriter, statusCode int, response HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Errorf("Health response could not be written: %v", err)
+	}
+}
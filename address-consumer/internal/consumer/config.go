@@ -0,0 +1,174 @@
+package consumer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	StartOffsetEarliest = "earliest"
+	StartOffsetLatest   = "latest"
+
+	SASLPlain       = "plain"
+	SASLScramSha256 = "scram-sha-256"
+	SASLScramSha512 = "scram-sha-512"
+)
+
+// NewReaderConfig builds the reader settings of the topic, it fails on settings kafka-go would reject
+// or silently ignore so a misconfigured consumer does not start
+func NewReaderConfig(cfg config.KafkaConfig, topic string) (kafka.ReaderConfig, error) {
+	topicCfg := cfg.ForTopic(topic)
+
+	if len(topicCfg.Brokers) == 0 {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): no brokers configured", topic)
+	}
+	if topicCfg.GroupID == "" {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): no group id configured", topic)
+	}
+	if topicCfg.MinBytes > 0 && topicCfg.MaxBytes > 0 && topicCfg.MinBytes > topicCfg.MaxBytes {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): minBytes %d is greater than maxBytes %d", topic, topicCfg.MinBytes, topicCfg.MaxBytes)
+	}
+
+	startOffset, err := parseStartOffset(topicCfg.StartOffset)
+	if err != nil {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): %w", topic, err)
+	}
+
+	dialer, err := newDialer(topicCfg.TLS, topicCfg.SASL)
+	if err != nil {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): %w", topic, err)
+	}
+
+	readerConfig := kafka.ReaderConfig{
+		Brokers:           topicCfg.Brokers,
+		GroupID:           topicCfg.GroupID,
+		Topic:             topic,
+		QueueCapacity:     topicCfg.MaxPollRecords,
+		MinBytes:          topicCfg.MinBytes,
+		MaxBytes:          topicCfg.MaxBytes,
+		MaxWait:           time.Duration(topicCfg.FetchMaxWaitMs) * time.Millisecond,
+		StartOffset:       startOffset,
+		SessionTimeout:    time.Duration(topicCfg.SessionTimeoutMs) * time.Millisecond,
+		HeartbeatInterval: time.Duration(topicCfg.HeartbeatIntervalMs) * time.Millisecond,
+		Dialer:            dialer,
+	}
+	// without auto commit every message is committed synchronously after it is processed
+	if topicCfg.AutoCommit {
+		readerConfig.CommitInterval = time.Duration(topicCfg.CommitIntervalMs) * time.Millisecond
+	}
+
+	if err := readerConfig.Validate(); err != nil {
+		return kafka.ReaderConfig{}, fmt.Errorf("consumer.NewReaderConfig(%s): %w", topic, err)
+	}
+
+	return readerConfig, nil
+}
+
+// parseStartOffset defaults to earliest, so a new group does not skip the events already published
+func parseStartOffset(offset string) (int64, error) {
+	switch strings.ToLower(offset) {
+	case "", StartOffsetEarliest:
+		return kafka.FirstOffset, nil
+	case StartOffsetLatest:
+		return kafka.LastOffset, nil
+	default:
+		return 0, fmt.Errorf("unsupported start offset %q", offset)
+	}
+}
+
+// newDialer returns nil when neither TLS nor SASL is configured, the reader then uses the default dialer
+func newDialer(tlsCfg config.KafkaTLSConfig, saslCfg config.KafkaSASLConfig) (*kafka.Dialer, error) {
+	if !tlsCfg.Enabled && saslCfg.Mechanism == "" {
+		return nil, nil
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+	}
+
+	if tlsCfg.Enabled {
+		tlsConfig, err := newTLSConfig(tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+		dialer.TLS = tlsConfig
+	}
+
+	if saslCfg.Mechanism != "" {
+		mechanism, err := newSASLMechanism(saslCfg)
+		if err != nil {
+			return nil, err
+		}
+		dialer.SASLMechanism = mechanism
+	}
+
+	return dialer, nil
+}
+
+// newTransport is the writer counterpart of newDialer, it returns nil for the default transport
+func newTransport(tlsCfg config.KafkaTLSConfig, saslCfg config.KafkaSASLConfig) (*kafka.Transport, error) {
+	dialer, err := newDialer(tlsCfg, saslCfg)
+	if err != nil || dialer == nil {
+		return nil, err
+	}
+
+	return &kafka.Transport{
+		TLS:  dialer.TLS,
+		SASL: dialer.SASLMechanism,
+	}, nil
+}
+
+func newTLSConfig(cfg config.KafkaTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CaFile != "" {
+		ca, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("consumer.newTLSConfig.ReadCaFile: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("consumer.newTLSConfig: no certificate found in the ca file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// the client certificate is only needed by brokers requiring mutual TLS
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("consumer.newTLSConfig.LoadX509KeyPair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func newSASLMechanism(cfg config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	switch strings.ToLower(cfg.Mechanism) {
+	case SASLPlain:
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case SASLScramSha256:
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case SASLScramSha512:
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", cfg.Mechanism)
+	}
+}
@@ -0,0 +1,194 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headers added to a message moved to a retry or dead-letter topic
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	HeaderFirstFailedAt     = "x-first-failed-at"
+	HeaderLastFailedAt      = "x-last-failed-at"
+)
+
+const deadLetterSuffix = ".dlq"
+
+var deadLetterHeaders = []string{
+	HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset,
+	HeaderError, HeaderAttempts, HeaderFirstFailedAt, HeaderLastFailedAt,
+}
+
+// RetryTopic is a topic whose messages are processed again once their delay passed
+type RetryTopic struct {
+	Name  string
+	Delay time.Duration
+}
+
+func RetryTopicName(topic, delay string) string {
+	return topic + ".retry." + delay
+}
+
+func DeadLetterTopicName(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+func IsDeadLetterTopic(topic string) bool {
+	return strings.HasSuffix(topic, deadLetterSuffix)
+}
+
+// messageWriter is the part of the kafka writer the router publishes with
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// DeadLetterRouter publishes the messages a consumer gave up on to the next retry topic, messages
+// failing permanently or in the last retry topic go to the dead-letter topic of their source topic
+type DeadLetterRouter struct {
+	writer      messageWriter
+	retryDelays []string
+	delays      []time.Duration
+	logger      logger.Logger
+}
+
+func NewDeadLetterRouter(cfg config.KafkaConfig, logger logger.Logger) (*DeadLetterRouter, error) {
+	delays := make([]time.Duration, 0, len(cfg.DeadLetter.RetryDelays))
+	for _, retryDelay := range cfg.DeadLetter.RetryDelays {
+		delay, err := time.ParseDuration(retryDelay)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("consumer.NewDeadLetterRouter: invalid retry delay %q", retryDelay)
+		}
+		delays = append(delays, delay)
+	}
+
+	writer, err := newDeadLetterWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterRouter{
+		writer:      writer,
+		retryDelays: cfg.DeadLetter.RetryDelays,
+		delays:      delays,
+		logger:      logger,
+	}, nil
+}
+
+// RetryTopics returns the retry topics of the source topic in the order messages pass them
+func (r *DeadLetterRouter) RetryTopics(topic string) []RetryTopic {
+	retryTopics := make([]RetryTopic, 0, len(r.delays))
+	for i, delay := range r.delays {
+		retryTopics = append(retryTopics, RetryTopic{Name: RetryTopicName(topic, r.retryDelays[i]), Delay: delay})
+	}
+
+	return retryTopics
+}
+
+// Route publishes the message to its next topic and returns it, attempts are the failed attempts of the current consumer
+func (r *DeadLetterRouter) Route(ctx context.Context, msg kafka.Message, cause error, attempts int) (string, error) {
+	headers := headerMap(msg.Headers)
+	now := time.Now().UTC()
+
+	originalTopic := headers[HeaderOriginalTopic]
+	if originalTopic == "" {
+		originalTopic = msg.Topic
+		headers[HeaderOriginalTopic] = msg.Topic
+		headers[HeaderOriginalPartition] = strconv.Itoa(msg.Partition)
+		headers[HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+		headers[HeaderFirstFailedAt] = now.Format(time.RFC3339Nano)
+	}
+
+	previousAttempts, _ := strconv.Atoi(headers[HeaderAttempts])
+	headers[HeaderAttempts] = strconv.Itoa(previousAttempts + attempts)
+	headers[HeaderError] = cause.Error()
+	headers[HeaderLastFailedAt] = now.Format(time.RFC3339Nano)
+
+	next := r.nextTopic(originalTopic, msg.Topic, cause)
+	err := r.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   next,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: kafkaHeaders(headers),
+		// the retry consumer waits from this time on
+		Time: now,
+	})
+	if err != nil {
+		return "", fmt.Errorf("consumer.DeadLetterRouter.Route(%s): %w", next, err)
+	}
+
+	r.logger.Warnf("Message moved to %s: original topic %s, partition %s, offset %s, attempts %s: %v",
+		next, originalTopic, headers[HeaderOriginalPartition], headers[HeaderOriginalOffset], headers[HeaderAttempts], cause)
+	return next, nil
+}
+
+func (r *DeadLetterRouter) nextTopic(originalTopic, currentTopic string, cause error) string {
+	if IsPermanent(cause) {
+		return DeadLetterTopicName(originalTopic)
+	}
+
+	retryTopics := r.RetryTopics(originalTopic)
+	next := 0
+	for i, retryTopic := range retryTopics {
+		if retryTopic.Name == currentTopic {
+			next = i + 1
+		}
+	}
+
+	if next < len(retryTopics) {
+		return retryTopics[next].Name
+	}
+	return DeadLetterTopicName(originalTopic)
+}
+
+func (r *DeadLetterRouter) Close() error {
+	return r.writer.Close()
+}
+
+// newDeadLetterWriter keys messages like the source topics, so the retries of a key stay on one partition
+func newDeadLetterWriter(cfg config.KafkaConfig) (*kafka.Writer, error) {
+	transport, err := newTransport(cfg.TLS, cfg.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	if transport != nil {
+		writer.Transport = transport
+	}
+
+	return writer, nil
+}
+
+func headerMap(headers []kafka.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for _, header := range headers {
+		result[strings.ToLower(header.Key)] = string(header.Value)
+	}
+
+	return result
+}
+
+func kafkaHeaders(headers map[string]string) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		result = append(result, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	return result
+}
@@ -0,0 +1,156 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"time"
+)
+
+// DeadLetter is a message of a dead-letter topic with the failure recorded in its headers
+type DeadLetter struct {
+	Topic             string
+	Partition         int
+	Offset            int64
+	Key               string
+	OriginalTopic     string
+	OriginalPartition int
+	OriginalOffset    int64
+	Error             string
+	Attempts          int
+	FirstFailedAt     time.Time
+	LastFailedAt      time.Time
+	Value             []byte
+}
+
+func ParseDeadLetter(msg kafka.Message) DeadLetter {
+	headers := headerMap(msg.Headers)
+
+	deadLetter := DeadLetter{
+		Topic:         msg.Topic,
+		Partition:     msg.Partition,
+		Offset:        msg.Offset,
+		Key:           string(msg.Key),
+		OriginalTopic: headers[HeaderOriginalTopic],
+		Error:         headers[HeaderError],
+		Value:         msg.Value,
+	}
+	deadLetter.OriginalPartition, _ = strconv.Atoi(headers[HeaderOriginalPartition])
+	deadLetter.OriginalOffset, _ = strconv.ParseInt(headers[HeaderOriginalOffset], 10, 64)
+	deadLetter.Attempts, _ = strconv.Atoi(headers[HeaderAttempts])
+	deadLetter.FirstFailedAt, _ = time.Parse(time.RFC3339Nano, headers[HeaderFirstFailedAt])
+	deadLetter.LastFailedAt, _ = time.Parse(time.RFC3339Nano, headers[HeaderLastFailedAt])
+
+	return deadLetter
+}
+
+// DeadLetterAdmin inspects the dead-letter topics and re-drives their messages to the source topics
+type DeadLetterAdmin struct {
+	cfg    config.KafkaConfig
+	dialer *kafka.Dialer
+	writer *kafka.Writer
+}
+
+func NewDeadLetterAdmin(cfg config.KafkaConfig) (*DeadLetterAdmin, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("consumer.NewDeadLetterAdmin: no brokers configured")
+	}
+
+	dialer, err := newDialer(cfg.TLS, cfg.SASL)
+	if err != nil {
+		return nil, err
+	}
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+
+	writer, err := newDeadLetterWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterAdmin{
+		cfg:    cfg,
+		dialer: dialer,
+		writer: writer,
+	}, nil
+}
+
+// Inspect calls fn with up to limit messages of every partition of the dead-letter topic, it does not commit
+// any offset so inspecting never changes what is re-driven
+func (a *DeadLetterAdmin) Inspect(ctx context.Context, topic string, limit int, fn func(DeadLetter)) error {
+	return readTopic(ctx, a.dialer, a.cfg.Brokers, DeadLetterTopicName(topic), limit, func(msg kafka.Message) error {
+		fn(ParseDeadLetter(msg))
+		return nil
+	})
+}
+
+// Redrive publishes up to limit dead letters back to their original topic, it stops when the dead-letter
+// topic has no message for the idle duration, the redrive group commits every re-driven message
+func (a *DeadLetterAdmin) Redrive(ctx context.Context, topic string, limit int, idle time.Duration) (int, error) {
+	groupId := a.cfg.DeadLetter.RedriveGroupId
+	if groupId == "" {
+		return 0, errors.New("consumer.DeadLetterAdmin.Redrive: no redrive group id configured")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     a.cfg.Brokers,
+		Topic:       DeadLetterTopicName(topic),
+		GroupID:     groupId,
+		StartOffset: kafka.FirstOffset,
+		Dialer:      a.dialer,
+	})
+	defer reader.Close()
+
+	redriven := 0
+	for limit <= 0 || redriven < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return redriven, nil
+			}
+			return redriven, fmt.Errorf("consumer.DeadLetterAdmin.Redrive.FetchMessage: %w", err)
+		}
+
+		if err := a.writer.WriteMessages(ctx, redriveMessage(msg, topic)); err != nil {
+			return redriven, fmt.Errorf("consumer.DeadLetterAdmin.Redrive.WriteMessages: %w", err)
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			return redriven, fmt.Errorf("consumer.DeadLetterAdmin.Redrive.CommitMessages: %w", err)
+		}
+		redriven++
+	}
+
+	return redriven, nil
+}
+
+func (a *DeadLetterAdmin) Close() error {
+	return a.writer.Close()
+}
+
+// redriveMessage is the dead letter as it was published to the original topic, without the failure headers
+func redriveMessage(msg kafka.Message, topic string) kafka.Message {
+	headers := headerMap(msg.Headers)
+
+	originalTopic := headers[HeaderOriginalTopic]
+	if originalTopic == "" {
+		originalTopic = topic
+	}
+
+	for _, header := range deadLetterHeaders {
+		delete(headers, header)
+	}
+
+	return kafka.Message{
+		Topic:   originalTopic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: kafkaHeaders(headers),
+	}
+}
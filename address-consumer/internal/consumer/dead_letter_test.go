@@ -0,0 +1,172 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger/mocks"
+	mocks2 "github.com/sefikcan/address-consumer/pkg/metric/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingWriter keeps the messages the router publishes instead of sending them to kafka
+type recordingWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+}
+
+func (w *recordingWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *recordingWriter) Close() error {
+	return nil
+}
+
+func newTestLogger() *mocks.Logger {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	return mockLogger
+}
+
+func newTestRouter(t *testing.T, retryDelays ...string) (*DeadLetterRouter, *recordingWriter) {
+	router, err := NewDeadLetterRouter(config.KafkaConfig{
+		DeadLetter: config.KafkaDeadLetterConfig{Enabled: true, RetryDelays: retryDelays},
+	}, newTestLogger())
+	assert.NoError(t, err)
+
+	writer := &recordingWriter{}
+	router.writer = writer
+
+	return router, writer
+}
+
+func TestNewDeadLetterRouter_RejectsInvalidDelay(t *testing.T) {
+	_, err := NewDeadLetterRouter(config.KafkaConfig{
+		DeadLetter: config.KafkaDeadLetterConfig{RetryDelays: []string{"1m", "soon"}},
+	}, newTestLogger())
+
+	assert.Error(t, err)
+}
+
+func TestDeadLetterRouter_NextTopic(t *testing.T) {
+	router, _ := newTestRouter(t, "1m", "10m")
+	retryable := errors.New("database unavailable")
+	permanent := Permanent(errors.New("invalid payload"))
+
+	tests := []struct {
+		name    string
+		current string
+		cause   error
+		want    string
+	}{
+		{name: "source to first retry", current: "address-created", cause: retryable, want: "address-created.retry.1m"},
+		{name: "first retry to second retry", current: "address-created.retry.1m", cause: retryable, want: "address-created.retry.10m"},
+		{name: "last retry to dead letter", current: "address-created.retry.10m", cause: retryable, want: "address-created.dlq"},
+		{name: "permanent from source", current: "address-created", cause: permanent, want: "address-created.dlq"},
+		{name: "permanent from retry", current: "address-created.retry.1m", cause: permanent, want: "address-created.dlq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, router.nextTopic("address-created", tt.current, tt.cause))
+		})
+	}
+
+	withoutRetries, _ := newTestRouter(t)
+	assert.Equal(t, "address-created.dlq", withoutRetries.nextTopic("address-created", "address-created", retryable))
+}
+
+func TestDeadLetterRouter_RouteKeepsTheOriginOfTheMessage(t *testing.T) {
+	router, writer := newTestRouter(t, "1m", "10m")
+
+	source := kafka.Message{
+		Topic:     "address-created",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("42"),
+		Value:     []byte(`{"addressId":1}`),
+		Headers:   []kafka.Header{{Key: "ce_id", Value: []byte("event-1")}},
+	}
+
+	next, err := router.Route(context.Background(), source, errors.New("database unavailable"), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "address-created.retry.1m", next)
+
+	retried := writer.messages[0]
+	headers := headerMap(retried.Headers)
+	assert.Equal(t, next, retried.Topic)
+	assert.Equal(t, source.Key, retried.Key)
+	assert.Equal(t, source.Value, retried.Value)
+	assert.Equal(t, "event-1", headers["ce_id"])
+	assert.Equal(t, "address-created", headers[HeaderOriginalTopic])
+	assert.Equal(t, "3", headers[HeaderOriginalPartition])
+	assert.Equal(t, "42", headers[HeaderOriginalOffset])
+	assert.Equal(t, "3", headers[HeaderAttempts])
+	assert.Equal(t, "database unavailable", headers[HeaderError])
+	assert.NotEmpty(t, headers[HeaderFirstFailedAt])
+	firstFailedAt := headers[HeaderFirstFailedAt]
+
+	// the retry consumer fails again, the origin is kept and the attempts add up
+	retried.Partition, retried.Offset = 0, 7
+	next, err = router.Route(context.Background(), retried, Permanent(errors.New("invalid payload")), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "address-created.dlq", next)
+
+	headers = headerMap(writer.messages[1].Headers)
+	assert.Equal(t, "event-1", headers["ce_id"])
+	assert.Equal(t, "address-created", headers[HeaderOriginalTopic])
+	assert.Equal(t, "3", headers[HeaderOriginalPartition])
+	assert.Equal(t, "42", headers[HeaderOriginalOffset])
+	assert.Equal(t, "4", headers[HeaderAttempts])
+	assert.Equal(t, "invalid payload", headers[HeaderError])
+	assert.Equal(t, firstFailedAt, headers[HeaderFirstFailedAt])
+}
+
+type failingHandler struct {
+	err   error
+	calls int
+}
+
+func (h *failingHandler) ProcessMessage(context.Context, kafka.Message) error {
+	h.calls++
+	return h.err
+}
+
+func TestKafkaConsumer_PermanentErrorGoesStraightToDeadLetter(t *testing.T) {
+	router, writer := newTestRouter(t, "1m", "10m")
+	handler := &failingHandler{err: Permanent(errors.New("invalid payload"))}
+
+	metrics := mocks2.NewMetrics(t)
+	metrics.On("IncreaseFailed", "address-created").Once()
+	metrics.On("IncreaseDeadLetters", "address-created").Once()
+
+	kc := &KafkaConsumer{
+		logger:      newTestLogger(),
+		topic:       "address-created",
+		retryPolicy: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour, Multiplier: 2},
+		handler:     handler,
+		deadLetter:  router,
+		metrics:     metrics,
+	}
+
+	handled := kc.process(context.Background(), context.Background(), kafka.Message{Topic: "address-created", Offset: 1})
+
+	assert.True(t, handled)
+	assert.Equal(t, 1, handler.calls)
+	assert.Len(t, writer.messages, 1)
+	assert.Equal(t, "address-created.dlq", writer.messages[0].Topic)
+	assert.Equal(t, "1", headerMap(writer.messages[0].Headers)[HeaderAttempts])
+}
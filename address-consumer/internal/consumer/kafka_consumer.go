@@ -2,51 +2,338 @@ package consumer
 
 import (
 	"context"
+	"fmt"
 	"github.com/sefikcan/address-consumer/pkg/config"
 	"github.com/sefikcan/address-consumer/pkg/logger"
+	"github.com/sefikcan/address-consumer/pkg/metric"
 	"github.com/segmentio/kafka-go"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// BusinessLogic processes a message, an error wrapped by Permanent skips the retries
 type BusinessLogic interface {
 	ProcessMessage(ctx context.Context, msg kafka.Message) error
 }
 
 type KafkaConsumer struct {
-	reader  *kafka.Reader
-	logger  logger.Logger
-	topic   string
-	handler BusinessLogic
+	reader      *kafka.Reader
+	logger      logger.Logger
+	topic       string
+	retryPolicy RetryPolicy
+	handler     BusinessLogic
+	// deadLetter is nil when failed messages are only logged
+	deadLetter *DeadLetterRouter
+	// delay is the time a message of a retry topic waits before it is processed again
+	delay time.Duration
+	// drainTimeout is the time the in-flight messages get to finish after the consumer is stopped
+	drainTimeout    time.Duration
+	workers         int
+	workerQueueSize int
+	metrics         metric.Metrics
+	// statsInterval is how often the reader stats are collected
+	statsInterval time.Duration
+	// member is set while the consumer holds a generation of its group, it is unset while the group rebalances
+	member atomic.Bool
+	// readerFailing is set while the reader stats report errors, e.g. failed group joins or broker connections
+	readerFailing atomic.Bool
 }
 
-func NewKafkaConsumer(cfg *config.Config, logger logger.Logger, topic string, handler BusinessLogic) (*KafkaConsumer, error) {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{"localhost:9092"},
-		Topic:   topic,
-		GroupID: "1",
-	})
+// Health is the connection state of a consumer, a consumer joining its group counts as rebalancing
+type Health struct {
+	Connected   bool
+	Rebalancing bool
+}
+
+func NewKafkaConsumer(cfg *config.Config, logger logger.Logger, topic string, handler BusinessLogic, deadLetter *DeadLetterRouter, metrics metric.Metrics) (*KafkaConsumer, error) {
+	readerConfig, err := NewReaderConfig(cfg.Kafka, topic)
+	if err != nil {
+		return nil, err
+	}
+	topicCfg := cfg.Kafka.ForTopic(topic)
+
+	kc := &KafkaConsumer{
+		logger:          logger,
+		topic:           topic,
+		retryPolicy:     NewRetryPolicy(cfg.Kafka.Retry),
+		handler:         handler,
+		deadLetter:      deadLetter,
+		drainTimeout:    time.Duration(cfg.Consumer.DrainTimeoutMs) * time.Millisecond,
+		workers:         max(topicCfg.Workers, 1),
+		workerQueueSize: max(topicCfg.WorkerQueueSize, 1),
+		metrics:         metrics,
+		statsInterval:   time.Duration(max(cfg.Consumer.StatsIntervalMs, 1000)) * time.Millisecond,
+	}
+	readerConfig.Logger = kafka.LoggerFunc(kc.watchGeneration)
+	kc.reader = kafka.NewReader(readerConfig)
+
+	return kc, nil
+}
+
+// NewRetryConsumer returns the consumer of a retry topic, it processes the messages with the handler
+// of the source topic once their delay passed
+func NewRetryConsumer(cfg *config.Config, logger logger.Logger, retryTopic RetryTopic, handler BusinessLogic, deadLetter *DeadLetterRouter, metrics metric.Metrics) (*KafkaConsumer, error) {
+	retryConsumer, err := NewKafkaConsumer(cfg, logger, retryTopic.Name, handler, deadLetter, metrics)
+	if err != nil {
+		return nil, err
+	}
+	retryConsumer.delay = retryTopic.Delay
 
-	return &KafkaConsumer{
-		reader:  reader,
-		logger:  logger,
-		topic:   topic,
-		handler: handler,
-	}, nil
+	return retryConsumer, nil
 }
 
+// Start commits a message only after it is processed, a message read again after a crash or
+// rebalance is processed twice so the business logic has to be idempotent
+// messages are processed by the workers concurrently, the messages of a key by one worker in order
+// once ctx is done no message is fetched anymore, the in-flight messages are processed and committed
+// within the drain timeout and the reader is closed
 func (kc *KafkaConsumer) Start(ctx context.Context) error {
-	kc.logger.Info("Kafka Consumer started. Topic: %s", kc.topic)
+	kc.logger.Infof("Kafka Consumer started. Topic: %s, GroupId: %s, Workers: %d", kc.topic, kc.reader.Config().GroupID, kc.workers)
+	defer kc.close()
+
+	collected := make(chan struct{})
+	defer func() { <-collected }()
+
+	// a panicking worker stops the consumer, the supervisor restarts it
+	run, crash := context.WithCancelCause(ctx)
+	defer crash(nil)
+
+	go func() {
+		defer close(collected)
+		kc.collectStats(run)
+	}()
+
+	work, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	stopDrain := context.AfterFunc(run, func() {
+		time.AfterFunc(kc.drainTimeout, cancelWork)
+	})
+	defer stopDrain()
+
+	// fetched and completed messages share the channel, a message is always fetched before it completes
+	offsets := make(chan offsetEvent, kc.workers*kc.workerQueueSize)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		kc.commit(work, offsets)
+	}()
+
+	queues := make([]chan kafka.Message, kc.workers)
+	var workers sync.WaitGroup
+	for i := range queues {
+		// the bounded queues stop the fetching while the workers are busy
+		queues[i] = make(chan kafka.Message, kc.workerQueueSize)
+		workers.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer workers.Done()
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					crash(fmt.Errorf("panic: %v", recovered))
+				}
+			}()
+
+			for msg := range queue {
+				// only the messages already being processed finish after the stop, queued ones are read again
+				if run.Err() != nil {
+					continue
+				}
+
+				// messages of a retry topic share the delay, so waiting for one never delays an earlier due message
+				if kc.delay > 0 && !wait(run, time.Until(msg.Time.Add(kc.delay))) {
+					continue
+				}
 
+				// a message interrupted by the shutdown stays uncommitted, the group reads it again
+				started := time.Now()
+				if kc.process(run, work, msg) {
+					kc.metrics.ObserveProcessingTime(kc.topic, time.Since(started).Seconds())
+					offsets <- offsetEvent{msg: msg, completed: true}
+				}
+			}
+		}(queues[i])
+	}
+
+	kc.fetch(run, queues, offsets)
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	workers.Wait()
+	close(offsets)
+	<-committed
+
+	if ctx.Err() == nil {
+		return context.Cause(run)
+	}
+	return nil
+}
+
+// fetch hands the messages to the workers until the context is done
+func (kc *KafkaConsumer) fetch(ctx context.Context, queues []chan kafka.Message, offsets chan<- offsetEvent) {
+	readFailures := 0
 	for {
-		msg, err := kc.reader.ReadMessage(ctx)
+		msg, err := kc.reader.FetchMessage(ctx)
 		if err != nil {
-			kc.logger.Errorf("Message could not be read (%s): %v", kc.topic, string(msg.Value))
+			if ctx.Err() != nil {
+				return
+			}
+
+			// the broker may be unreachable, back off instead of spinning on the error
+			readFailures++
+			kc.logger.Errorf("Message could not be read (%s): %v", kc.topic, err)
+			if !wait(ctx, kc.retryPolicy.Backoff(readFailures)) {
+				return
+			}
+			continue
+		}
+		readFailures = 0
+
+		kc.logger.Infof("Message received (%s): partition %d, offset %d", kc.topic, msg.Partition, msg.Offset)
+		kc.metrics.IncreaseConsumed(kc.topic)
+
+		offsets <- offsetEvent{msg: msg}
+		select {
+		case queues[worker(msg, len(queues))] <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type offsetEvent struct {
+	msg       kafka.Message
+	completed bool
+}
+
+// commit advances the offset of a partition past the contiguous completed messages, it is the only
+// goroutine committing so the committed offsets never go back
+func (kc *KafkaConsumer) commit(ctx context.Context, offsets <-chan offsetEvent) {
+	tracker := newOffsetTracker()
+	for event := range offsets {
+		if !event.completed {
+			tracker.fetched(event.msg)
 			continue
 		}
 
-		kc.logger.Infof("Message received (%s): %s", kc.topic, string(msg.Value))
+		msg, ok := tracker.complete(event.msg)
+		if !ok {
+			continue
+		}
+
+		if err := kc.reader.CommitMessages(ctx, msg); err != nil {
+			kc.logger.Errorf("Message could not be committed (%s): partition %d, offset %d: %v", kc.topic, msg.Partition, msg.Offset, err)
+		}
+	}
+}
+
+// Health reports whether the consumer reaches the broker and holds a generation of its group
+func (kc *KafkaConsumer) Health() Health {
+	return Health{
+		Connected:   !kc.readerFailing.Load(),
+		Rebalancing: !kc.member.Load(),
+	}
+}
+
+// watchGeneration follows the generations of the group through the reader log, kafka-go has no rebalance
+// callback but runs a heartbeat for exactly the lifetime of a generation
+func (kc *KafkaConsumer) watchGeneration(msg string, args ...interface{}) {
+	switch {
+	case strings.HasPrefix(msg, "started heartbeat"):
+		kc.member.Store(true)
+	case strings.HasPrefix(msg, "stopped heartbeat"):
+		kc.member.Store(false)
+		// the partitions may be assigned to another consumer of the group now
+		kc.metrics.ResetLag(kc.topic)
+	}
+}
+
+// collectStats collects the reader stats, they count since the last collection
+func (kc *KafkaConsumer) collectStats(ctx context.Context) {
+	ticker := time.NewTicker(kc.statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := kc.reader.Stats()
+			kc.readerFailing.Store(stats.Errors > 0)
+			// the lag of a consumer that left its generation was reset, its partitions may be read by another one now
+			if kc.member.Load() {
+				kc.metrics.SetLag(kc.topic, stats.Lag)
+			}
+		}
+	}
+}
+
+// close flushes the offsets committed on an interval
+func (kc *KafkaConsumer) close() {
+	kc.member.Store(false)
+	kc.metrics.ResetLag(kc.topic)
+
+	if err := kc.reader.Close(); err != nil {
+		kc.logger.Errorf("Kafka reader could not be closed (%s): %v", kc.topic, err)
+		return
+	}
+
+	kc.logger.Infof("Kafka Consumer stopped. Topic: %s", kc.topic)
+}
+
+// process retries the message with the retry policy, messages failing permanently or on every attempt
+// are dead-lettered, the handler runs with the work context which outlives ctx by the drain timeout
+// it returns false when the message is not handled because the consumer stopped
+func (kc *KafkaConsumer) process(ctx, work context.Context, msg kafka.Message) bool {
+	for attempt := 1; ; attempt++ {
+		err := kc.handler.ProcessMessage(work, msg)
+		if err == nil {
+			kc.metrics.IncreaseProcessed(kc.topic)
+			return true
+		}
+		// a stopping consumer does not retry, the message is read again after the restart
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if IsPermanent(err) || attempt >= kc.retryPolicy.MaxAttempts {
+			kc.metrics.IncreaseFailed(kc.topic)
+			return kc.giveUp(ctx, work, msg, err, attempt)
+		}
+
+		kc.metrics.IncreaseRetries(kc.topic)
+
+		backoff := kc.retryPolicy.Backoff(attempt)
+		kc.logger.Warnf("Message could not be processed, retrying in %s (%s): partition %d, offset %d, attempt %d: %v", backoff, kc.topic, msg.Partition, msg.Offset, attempt, err)
+		if !wait(ctx, backoff) {
+			return false
+		}
+	}
+}
+
+// giveUp moves the message to its retry or dead-letter topic, the message is only committed once it is
+// published there, so routing is retried until it succeeds or the context is done
+func (kc *KafkaConsumer) giveUp(ctx, work context.Context, msg kafka.Message, cause error, attempts int) bool {
+	if kc.deadLetter == nil {
+		kc.logger.Errorf("Message could not be processed after %d attempts and is skipped (%s): partition %d, offset %d: %v", attempts, kc.topic, msg.Partition, msg.Offset, cause)
+		return true
+	}
+
+	for routeAttempt := 1; ; routeAttempt++ {
+		next, err := kc.deadLetter.Route(work, msg, cause, attempts)
+		if err == nil {
+			if IsDeadLetterTopic(next) {
+				kc.metrics.IncreaseDeadLetters(kc.topic)
+			} else {
+				kc.metrics.IncreaseRetries(kc.topic)
+			}
+			return true
+		}
 
-		if err := kc.handler.ProcessMessage(ctx, msg); err != nil {
-			kc.logger.Errorf("Message could not be processed (%s): %v", kc.topic, err)
+		kc.logger.Errorf("Message could not be dead-lettered (%s): partition %d, offset %d: %v", kc.topic, msg.Partition, msg.Offset, err)
+		if !wait(ctx, kc.retryPolicy.Backoff(routeAttempt)) {
+			return false
 		}
 	}
 }
@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/segmentio/kafka-go"
+)
+
+// Replay calls fn with every message of the topic, from the first message of each partition up to the last
+// message when the replay started, it reads without a consumer group so no offset is committed
+func Replay(ctx context.Context, cfg config.KafkaConfig, topic string, fn func(kafka.Message) error) error {
+	if len(cfg.Brokers) == 0 {
+		return errors.New("consumer.Replay: no brokers configured")
+	}
+
+	dialer, err := newDialer(cfg.TLS, cfg.SASL)
+	if err != nil {
+		return err
+	}
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+
+	return readTopic(ctx, dialer, cfg.Brokers, topic, 0, fn)
+}
+
+// readTopic reads up to limit messages of every partition of the topic, a limit of 0 reads every message
+func readTopic(ctx context.Context, dialer *kafka.Dialer, brokers []string, topic string, limit int, fn func(kafka.Message) error) error {
+	conn, err := dialer.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return fmt.Errorf("consumer.readTopic.Dial: %w", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return fmt.Errorf("consumer.readTopic.ReadPartitions(%s): %w", topic, err)
+	}
+
+	for _, partition := range partitions {
+		if err := readPartition(ctx, dialer, brokers, topic, partition.ID, limit, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readPartition(ctx context.Context, dialer *kafka.Dialer, brokers []string, topic string, partition, limit int, fn func(kafka.Message) error) error {
+	leader, err := dialer.DialLeader(ctx, "tcp", brokers[0], topic, partition)
+	if err != nil {
+		return fmt.Errorf("consumer.readPartition.DialLeader(%s/%d): %w", topic, partition, err)
+	}
+	first, last, err := leader.ReadOffsets()
+	leader.Close()
+	if err != nil {
+		return fmt.Errorf("consumer.readPartition.ReadOffsets(%s/%d): %w", topic, partition, err)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+		Dialer:    dialer,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(first); err != nil {
+		return err
+	}
+
+	// last is the offset of the next message, reading stops there instead of waiting for new messages
+	for offset, read := first, 0; offset < last && (limit <= 0 || read < limit); read++ {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("consumer.readPartition.ReadMessage(%s/%d): %w", topic, partition, err)
+		}
+
+		if err := fn(msg); err != nil {
+			return err
+		}
+		offset = msg.Offset + 1
+	}
+
+	return nil
+}
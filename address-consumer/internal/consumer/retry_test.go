@@ -0,0 +1,75 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestIsPermanent(t *testing.T) {
+	cause := errors.New("invalid payload")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "retryable", err: cause, want: false},
+		{name: "permanent", err: Permanent(cause), want: true},
+		{name: "wrapped permanent", err: fmt.Errorf("createdService.ProcessMessage: %w", Permanent(cause)), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPermanent(tt.err))
+		})
+	}
+
+	assert.Nil(t, Permanent(nil))
+	assert.ErrorIs(t, Permanent(cause), cause)
+}
+
+func TestNewRetryPolicy_Defaults(t *testing.T) {
+	policy := NewRetryPolicy(config.KafkaRetryConfig{Jitter: 3})
+
+	assert.Equal(t, RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         1,
+	}, policy)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := NewRetryPolicy(config.KafkaRetryConfig{
+		MaxAttempts:      5,
+		InitialBackoffMs: 100,
+		MaxBackoffMs:     1000,
+		Multiplier:       3,
+	})
+
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 900*time.Millisecond, policy.Backoff(3))
+	assert.Equal(t, time.Second, policy.Backoff(4))
+	assert.Equal(t, time.Second, policy.Backoff(10))
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	policy := NewRetryPolicy(config.KafkaRetryConfig{
+		InitialBackoffMs: 1000,
+		MaxBackoffMs:     1000,
+		Jitter:           0.5,
+	})
+
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(1)
+		assert.GreaterOrEqual(t, backoff, 500*time.Millisecond)
+		assert.LessOrEqual(t, backoff, time.Second)
+	}
+}
@@ -0,0 +1,208 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	"sort"
+	"sync"
+)
+
+// State is the lifecycle state of a supervised consumer
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	StateDraining State = "draining"
+	StateStopped  State = "stopped"
+	StateFailed   State = "failed"
+)
+
+// Factory creates a consumer, the supervisor calls it again to replace a crashed consumer
+type Factory func() (*KafkaConsumer, error)
+
+type supervised struct {
+	name     string
+	factory  Factory
+	consumer *KafkaConsumer
+	state    State
+	restarts int
+	lastErr  error
+}
+
+// ConsumerStatus is the state of a supervised consumer
+type ConsumerStatus struct {
+	Name        string `json:"name"`
+	State       State  `json:"state"`
+	Restarts    int    `json:"restarts"`
+	LastError   string `json:"lastError,omitempty"`
+	Connected   bool   `json:"connected"`
+	Rebalancing bool   `json:"rebalancing"`
+}
+
+// Ready reports whether the consumer is consuming its topic
+func (s ConsumerStatus) Ready() bool {
+	return s.State == StateRunning && s.Connected && !s.Rebalancing
+}
+
+// Supervisor runs the consumers until the context is done and restarts crashed consumers, the restart
+// backoff grows with the failures of a consumer since the process started
+type Supervisor struct {
+	mu        sync.Mutex
+	consumers []*supervised
+	backoff   RetryPolicy
+	logger    logger.Logger
+}
+
+func NewSupervisor(cfg config.ConsumerConfig, logger logger.Logger) *Supervisor {
+	return &Supervisor{
+		backoff: NewRetryPolicy(config.KafkaRetryConfig{
+			InitialBackoffMs: cfg.RestartBackoffMs,
+			MaxBackoffMs:     cfg.MaxRestartBackoffMs,
+			Jitter:           0.2,
+		}),
+		logger: logger,
+	}
+}
+
+// Add creates the first consumer right away, so a misconfigured consumer fails before any consumer runs
+func (s *Supervisor) Add(name string, factory Factory) error {
+	kafkaConsumer, err := factory()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consumers = append(s.consumers, &supervised{
+		name:     name,
+		factory:  factory,
+		consumer: kafkaConsumer,
+		state:    StateStarting,
+	})
+
+	return nil
+}
+
+// Run blocks until the context is done and every consumer stopped
+func (s *Supervisor) Run(ctx context.Context) {
+	s.mu.Lock()
+	consumers := append([]*supervised(nil), s.consumers...)
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, consumer := range consumers {
+		wg.Add(1)
+		go func(consumer *supervised) {
+			defer wg.Done()
+			s.supervise(ctx, consumer)
+		}(consumer)
+	}
+
+	<-ctx.Done()
+	s.mu.Lock()
+	for _, consumer := range consumers {
+		if consumer.state == StateRunning {
+			consumer.state = StateDraining
+		}
+	}
+	s.mu.Unlock()
+
+	wg.Wait()
+}
+
+func (s *Supervisor) supervise(ctx context.Context, consumer *supervised) {
+	failures := 0
+	for {
+		s.setState(consumer, StateRunning, nil)
+		err := s.start(ctx, consumer.consumer)
+
+		if ctx.Err() != nil {
+			s.setState(consumer, StateStopped, err)
+			return
+		}
+
+		// Start only returns before the shutdown when the consumer crashed
+		if err == nil {
+			err = errors.New("consumer stopped unexpectedly")
+		}
+
+		for {
+			failures++
+			s.setState(consumer, StateFailed, err)
+
+			backoff := s.backoff.Backoff(failures)
+			s.logger.Errorf("Consumer %s failed, restarting in %s: %v", consumer.name, backoff, err)
+			if !wait(ctx, backoff) {
+				s.setState(consumer, StateStopped, nil)
+				return
+			}
+
+			s.setState(consumer, StateStarting, nil)
+			var kafkaConsumer *KafkaConsumer
+			kafkaConsumer, err = consumer.factory()
+			if err == nil {
+				s.mu.Lock()
+				consumer.consumer = kafkaConsumer
+				consumer.restarts++
+				s.mu.Unlock()
+				break
+			}
+		}
+	}
+}
+
+// start turns a panic of the consumer or its business logic into an error
+func (s *Supervisor) start(ctx context.Context, kafkaConsumer *KafkaConsumer) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	return kafkaConsumer.Start(ctx)
+}
+
+func (s *Supervisor) setState(consumer *supervised, state State, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if consumer.state != state {
+		s.logger.Infof("Consumer %s is %s", consumer.name, state)
+	}
+	consumer.state = state
+	if err != nil {
+		consumer.lastErr = err
+	}
+}
+
+// Status returns the state of every consumer ordered by name
+func (s *Supervisor) Status() []ConsumerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ConsumerStatus, 0, len(s.consumers))
+	for _, consumer := range s.consumers {
+		health := consumer.consumer.Health()
+		status := ConsumerStatus{
+			Name:        consumer.name,
+			State:       consumer.state,
+			Restarts:    consumer.restarts,
+			Connected:   health.Connected,
+			Rebalancing: health.Rebalancing,
+		}
+		if consumer.lastErr != nil {
+			status.LastError = consumer.lastErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
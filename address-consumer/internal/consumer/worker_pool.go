@@ -0,0 +1,97 @@
+package consumer
+
+import (
+	"github.com/segmentio/kafka-go"
+	"hash/fnv"
+	"strconv"
+)
+
+// offsetTracker finds the offset a partition can be committed up to, messages complete out of order
+// across keys but an offset is only committed once every earlier fetched message of its partition completed
+type offsetTracker struct {
+	pending   map[int][]int64
+	completed map[int]map[int64]kafka.Message
+	// committed is the highest offset returned per partition, the tracker never returns a lower one
+	committed map[int]int64
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		pending:   make(map[int][]int64),
+		completed: make(map[int]map[int64]kafka.Message),
+		committed: make(map[int]int64),
+	}
+}
+
+// fetched records the message before it is handed to a worker, messages of a partition are fetched in offset order.
+// Messages fetched again after a rebalance are not recorded again, they are at or below an offset already tracked
+func (t *offsetTracker) fetched(msg kafka.Message) {
+	if t.tracked(msg) {
+		return
+	}
+
+	t.pending[msg.Partition] = append(t.pending[msg.Partition], msg.Offset)
+}
+
+// tracked reports whether the offset is committed or waits behind a pending message of its partition
+func (t *offsetTracker) tracked(msg kafka.Message) bool {
+	if committed, ok := t.committed[msg.Partition]; ok && msg.Offset <= committed {
+		return true
+	}
+
+	pending := t.pending[msg.Partition]
+	return len(pending) > 0 && msg.Offset <= pending[len(pending)-1]
+}
+
+// complete returns the last message of the contiguous completed messages at the start of the partition
+func (t *offsetTracker) complete(msg kafka.Message) (kafka.Message, bool) {
+	// the other copy of a message fetched twice completed first
+	if committed, ok := t.committed[msg.Partition]; ok && msg.Offset <= committed {
+		return kafka.Message{}, false
+	}
+
+	completed, ok := t.completed[msg.Partition]
+	if !ok {
+		completed = make(map[int64]kafka.Message)
+		t.completed[msg.Partition] = completed
+	}
+	completed[msg.Offset] = msg
+
+	var last kafka.Message
+	advanced := false
+	pending := t.pending[msg.Partition]
+	for len(pending) > 0 {
+		next, ok := completed[pending[0]]
+		if !ok {
+			break
+		}
+
+		delete(completed, pending[0])
+		pending = pending[1:]
+		last = next
+		advanced = true
+	}
+	t.pending[msg.Partition] = pending
+	if advanced {
+		t.committed[msg.Partition] = last.Offset
+	}
+
+	return last, advanced
+}
+
+// worker returns the worker of the message, messages with the same key are processed by one worker in order
+func worker(msg kafka.Message, workers int) int {
+	if workers <= 1 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	if len(msg.Key) > 0 {
+		_, _ = hash.Write(msg.Key)
+	} else {
+		// messages without a key have no order to keep
+		_, _ = hash.Write([]byte(strconv.FormatInt(msg.Offset, 10)))
+	}
+
+	return int(hash.Sum32() % uint32(workers))
+}
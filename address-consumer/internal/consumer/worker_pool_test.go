@@ -0,0 +1,156 @@
+package consumer
+
+import (
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type trackedMessage struct {
+	partition int
+	offset    int64
+}
+
+func TestOffsetTracker_Complete(t *testing.T) {
+	type completion struct {
+		// refetched are fetched again before the message completes, e.g. after a rebalance
+		refetched  []trackedMessage
+		msg        trackedMessage
+		wantCommit bool
+		wantOffset int64
+	}
+
+	tests := []struct {
+		name        string
+		fetched     []trackedMessage
+		completions []completion
+	}{
+		{
+			name:    "in order",
+			fetched: []trackedMessage{{0, 10}, {0, 11}, {0, 12}},
+			completions: []completion{
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 10},
+				{msg: trackedMessage{0, 11}, wantCommit: true, wantOffset: 11},
+				{msg: trackedMessage{0, 12}, wantCommit: true, wantOffset: 12},
+			},
+		},
+		{
+			name:    "later offsets wait for the first one",
+			fetched: []trackedMessage{{0, 10}, {0, 11}, {0, 12}},
+			completions: []completion{
+				{msg: trackedMessage{0, 12}},
+				{msg: trackedMessage{0, 11}},
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 12},
+			},
+		},
+		{
+			name:    "gap in the middle",
+			fetched: []trackedMessage{{0, 10}, {0, 11}, {0, 12}, {0, 13}},
+			completions: []completion{
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 10},
+				{msg: trackedMessage{0, 12}},
+				{msg: trackedMessage{0, 13}},
+				{msg: trackedMessage{0, 11}, wantCommit: true, wantOffset: 13},
+			},
+		},
+		{
+			name:    "offsets are not contiguous after compaction",
+			fetched: []trackedMessage{{0, 10}, {0, 15}, {0, 20}},
+			completions: []completion{
+				{msg: trackedMessage{0, 20}},
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 10},
+				{msg: trackedMessage{0, 15}, wantCommit: true, wantOffset: 20},
+			},
+		},
+		{
+			name:    "partitions are tracked separately",
+			fetched: []trackedMessage{{0, 10}, {1, 10}, {0, 11}, {1, 11}},
+			completions: []completion{
+				{msg: trackedMessage{1, 11}},
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 10},
+				{msg: trackedMessage{0, 11}, wantCommit: true, wantOffset: 11},
+				{msg: trackedMessage{1, 10}, wantCommit: true, wantOffset: 11},
+			},
+		},
+		{
+			name:    "offsets fetched again after they were committed",
+			fetched: []trackedMessage{{0, 10}, {0, 11}, {0, 12}},
+			completions: []completion{
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 10},
+				{msg: trackedMessage{0, 11}, wantCommit: true, wantOffset: 11},
+				{refetched: []trackedMessage{{0, 10}, {0, 11}, {0, 12}}, msg: trackedMessage{0, 10}},
+				{msg: trackedMessage{0, 11}},
+				{msg: trackedMessage{0, 12}, wantCommit: true, wantOffset: 12},
+				{msg: trackedMessage{0, 12}},
+			},
+		},
+		{
+			name:    "offsets fetched twice before they completed",
+			fetched: []trackedMessage{{0, 10}, {0, 11}, {0, 10}, {0, 11}, {0, 12}},
+			completions: []completion{
+				{msg: trackedMessage{0, 11}},
+				{msg: trackedMessage{0, 10}, wantCommit: true, wantOffset: 11},
+				{msg: trackedMessage{0, 10}},
+				{msg: trackedMessage{0, 11}},
+				{msg: trackedMessage{0, 12}, wantCommit: true, wantOffset: 12},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			for _, msg := range tt.fetched {
+				tracker.fetched(kafka.Message{Partition: msg.partition, Offset: msg.offset})
+			}
+
+			for _, c := range tt.completions {
+				for _, msg := range c.refetched {
+					tracker.fetched(kafka.Message{Partition: msg.partition, Offset: msg.offset})
+				}
+				last, ok := tracker.complete(kafka.Message{Partition: c.msg.partition, Offset: c.msg.offset})
+
+				assert.Equal(t, c.wantCommit, ok, "complete %v", c.msg)
+				if c.wantCommit {
+					assert.Equal(t, c.msg.partition, last.Partition)
+					assert.Equal(t, c.wantOffset, last.Offset, "complete %v", c.msg)
+				}
+			}
+
+			for partition, pending := range tracker.pending {
+				assert.Empty(t, pending, "partition %d", partition)
+			}
+			for partition, completed := range tracker.completed {
+				assert.Empty(t, completed, "partition %d", partition)
+			}
+		})
+	}
+}
+
+func TestWorker_SameKeySameWorker(t *testing.T) {
+	const workers = 8
+
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("user-%d", i))
+		first := worker(kafka.Message{Key: key, Partition: 0, Offset: int64(i)}, workers)
+
+		assert.GreaterOrEqual(t, first, 0)
+		assert.Less(t, first, workers)
+		for offset := int64(0); offset < 5; offset++ {
+			assert.Equal(t, first, worker(kafka.Message{Key: key, Partition: 1, Offset: offset}, workers), "key %s", key)
+		}
+	}
+}
+
+func TestWorker_SpreadsKeysOverWorkers(t *testing.T) {
+	const workers = 4
+
+	used := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		used[worker(kafka.Message{Key: []byte(fmt.Sprintf("user-%d", i))}, workers)] = true
+	}
+
+	assert.Len(t, used, workers)
+	assert.Equal(t, 0, worker(kafka.Message{Key: []byte("user-1")}, 1))
+}
@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"github.com/sefikcan/address-consumer/internal/consumer"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	"github.com/sefikcan/address-consumer/pkg/metric"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/segmentio/kafka-go"
+	"strings"
+	"time"
+)
+
+// handler skips the events the consumer group already processed, the messages of a key are processed
+// by one worker in order so a duplicate is never processed concurrently with its original
+type handler struct {
+	next      consumer.BusinessLogic
+	store     Store
+	ttl       time.Duration
+	keyPrefix string
+	metrics   metric.Metrics
+	logger    logger.Logger
+}
+
+func NewHandler(next consumer.BusinessLogic, store Store, ttl time.Duration, keyPrefix string, metrics metric.Metrics, logger logger.Logger) consumer.BusinessLogic {
+	return &handler{
+		next:      next,
+		store:     store,
+		ttl:       ttl,
+		keyPrefix: keyPrefix,
+		metrics:   metrics,
+		logger:    logger,
+	}
+}
+
+// ProcessMessage marks the event only after it is processed, a store failure is retried like any other error
+func (h *handler) ProcessMessage(ctx context.Context, msg kafka.Message) error {
+	key := h.keyPrefix + ":" + EventKey(msg)
+
+	processed, err := h.store.Exists(ctx, key)
+	if err != nil {
+		return fmt.Errorf("dedup.handler.Exists: %w", err)
+	}
+	if processed {
+		h.metrics.IncreaseDuplicates(msg.Topic)
+		h.logger.Infof("Duplicate event skipped (%s): partition %d, offset %d, key %s", msg.Topic, msg.Partition, msg.Offset, key)
+		return nil
+	}
+
+	if err := h.next.ProcessMessage(ctx, msg); err != nil {
+		return err
+	}
+
+	// the event is processed, failing here would only process it once more
+	if err := h.store.Mark(ctx, key, h.ttl); err != nil {
+		h.logger.Errorf("Processed event could not be marked (%s): key %s: %v", msg.Topic, key, err)
+	}
+
+	return nil
+}
+
+// EventKey is the CloudEvents id, which stays the same when the event is retried or re-driven, messages
+// without an id fall back to their position in the topic
+func EventKey(msg kafka.Message) string {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[strings.ToLower(header.Key)] = string(header.Value)
+	}
+
+	event, err := cloudevents.Decode(headers, msg.Value)
+	if err == nil && event.Id != "" {
+		return "event:" + event.Id
+	}
+
+	return fmt.Sprintf("message:%s:%d:%d", msg.Topic, msg.Partition, msg.Offset)
+}
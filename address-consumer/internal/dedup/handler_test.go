@@ -0,0 +1,97 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"github.com/sefikcan/address-consumer/pkg/logger/mocks"
+	mocks2 "github.com/sefikcan/address-consumer/pkg/metric/mocks"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+// countingLogic counts the messages passed to the wrapped business logic and fails with err
+type countingLogic struct {
+	err   error
+	calls int
+}
+
+func (l *countingLogic) ProcessMessage(context.Context, kafka.Message) error {
+	l.calls++
+	return l.err
+}
+
+func newTestHandler(t *testing.T, next *countingLogic) (*handler, Store, *mocks2.Metrics) {
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	metrics := mocks2.NewMetrics(t)
+	store := NewMemoryStore()
+
+	return NewHandler(next, store, time.Hour, "address-consumer", metrics, mockLogger).(*handler), store, metrics
+}
+
+func newEventMessage(t *testing.T, offset int64) kafka.Message {
+	event, err := cloudevents.New("/address-api", "com.sefikcan.address.created", "urn:test:v1", map[string]int{"addressId": 1})
+	assert.NoError(t, err)
+	event.Id = "event-1"
+
+	headers, value, err := event.Encode(cloudevents.ModeBinary)
+	assert.NoError(t, err)
+
+	msg := kafka.Message{Topic: "address-created", Partition: 0, Offset: offset, Value: value}
+	for key, header := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(header)})
+	}
+
+	return msg
+}
+
+func TestHandler_SkipsDuplicate(t *testing.T) {
+	next := &countingLogic{}
+	h, _, metrics := newTestHandler(t, next)
+	metrics.On("IncreaseDuplicates", "address-created").Once()
+
+	// the event is redelivered at another offset, e.g. after it was re-driven from the dead-letter topic
+	assert.NoError(t, h.ProcessMessage(context.Background(), newEventMessage(t, 1)))
+	assert.NoError(t, h.ProcessMessage(context.Background(), newEventMessage(t, 9)))
+
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestHandler_FailureDoesNotMarkTheEvent(t *testing.T) {
+	next := &countingLogic{err: errors.New("database unavailable")}
+	h, store, _ := newTestHandler(t, next)
+	msg := newEventMessage(t, 1)
+
+	err := h.ProcessMessage(context.Background(), msg)
+
+	assert.ErrorIs(t, err, next.err)
+	processed, _ := store.Exists(context.Background(), "address-consumer:event:event-1")
+	assert.False(t, processed)
+
+	// the retry is processed again
+	next.err = nil
+	assert.NoError(t, h.ProcessMessage(context.Background(), msg))
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestHandler_SuccessMarksTheEvent(t *testing.T) {
+	next := &countingLogic{}
+	h, store, _ := newTestHandler(t, next)
+
+	assert.NoError(t, h.ProcessMessage(context.Background(), newEventMessage(t, 1)))
+
+	processed, err := store.Exists(context.Background(), "address-consumer:event:event-1")
+	assert.NoError(t, err)
+	assert.True(t, processed)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestEventKey_FallsBackToThePosition(t *testing.T) {
+	assert.Equal(t, "event:event-1", EventKey(newEventMessage(t, 1)))
+	assert.Equal(t, "message:address-created:2:7", EventKey(kafka.Message{Topic: "address-created", Partition: 2, Offset: 7, Value: []byte("legacy")}))
+}
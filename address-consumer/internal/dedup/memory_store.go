@@ -0,0 +1,54 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps the processed events in the process, it is meant for tests and single instance setups
+type memoryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	sweptAt time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryStore) Exists(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.expires[key]
+	if ok && time.Now().After(expiresAt) {
+		delete(s.expires, key)
+		return false, nil
+	}
+
+	return ok, nil
+}
+
+func (s *memoryStore) Mark(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expires[key] = now.Add(ttl)
+
+	// expired keys are dropped while marking, so the map does not grow with every event
+	if now.Sub(s.sweptAt) < sweepInterval {
+		return nil
+	}
+	s.sweptAt = now
+	for storedKey, expiresAt := range s.expires {
+		if now.After(expiresAt) {
+			delete(s.expires, storedKey)
+		}
+	}
+
+	return nil
+}
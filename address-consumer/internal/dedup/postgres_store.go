@@ -0,0 +1,73 @@
+package dedup
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// processedEvent is a row of the processed_events table
+type processedEvent struct {
+	Key         string `gorm:"primaryKey"`
+	ProcessedAt time.Time
+	ExpiresAt   time.Time
+}
+
+func (processedEvent) TableName() string {
+	return "processed_events"
+}
+
+// postgresStore shares the processed events between the consumer instances, expired rows are ignored
+// and deleted while marking
+type postgresStore struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+func NewPostgresStore(db *gorm.DB) Store {
+	return &postgresStore{
+		db: db,
+	}
+}
+
+func (s *postgresStore) Exists(ctx context.Context, key string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&processedEvent{}).
+		Where("key = ? AND expires_at > ?", key, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *postgresStore) Mark(ctx context.Context, key string, ttl time.Duration) error {
+	now := time.Now()
+
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"processed_at", "expires_at"}),
+		}).
+		Create(&processedEvent{Key: key, ProcessedAt: now, ExpiresAt: now.Add(ttl)}).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sweep := now.Sub(s.sweptAt) >= sweepInterval
+	if sweep {
+		s.sweptAt = now
+	}
+	s.mu.Unlock()
+
+	if !sweep {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&processedEvent{}).Error
+}
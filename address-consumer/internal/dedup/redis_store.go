@@ -0,0 +1,31 @@
+package dedup
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// redisStore shares the processed events between the consumer instances, redis expires them
+type redisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{
+		client: client,
+	}
+}
+
+func (s *redisStore) Exists(ctx context.Context, key string) (bool, error) {
+	count, err := s.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *redisStore) Mark(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, time.Now().Unix(), ttl).Err()
+}
@@ -0,0 +1,46 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/redis"
+	"github.com/sefikcan/address-consumer/pkg/storage/postgres"
+	"time"
+)
+
+const (
+	StoreMemory   = "memory"
+	StoreRedis    = "redis"
+	StorePostgres = "postgres"
+
+	// sweepInterval is the minimum time between two deletions of expired events
+	sweepInterval = time.Minute
+)
+
+// Store remembers the processed events until their ttl expires
+type Store interface {
+	Exists(ctx context.Context, key string) (bool, error)
+	Mark(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// NewStore connects the configured store
+func NewStore(cfg *config.Config) (Store, error) {
+	switch cfg.Dedup.Store {
+	case "", StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		if err := redis.InitializeRedis(cfg); err != nil {
+			return nil, fmt.Errorf("dedup.NewStore.InitializeRedis: %w", err)
+		}
+		return NewRedisStore(redis.GetClient()), nil
+	case StorePostgres:
+		db, err := postgres.NewPsqlDb(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("dedup.NewStore.NewPsqlDb: %w", err)
+		}
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("dedup.NewStore: unsupported store %q", cfg.Dedup.Store)
+	}
+}
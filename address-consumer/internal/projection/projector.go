@@ -0,0 +1,265 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// addressProjection is a row of the address_projections table
+type addressProjection struct {
+	AddressId int `gorm:"primaryKey;autoIncrement:false"`
+	TenantId  string
+	UserId    string
+	Version   int64
+	Snapshot  string
+	Deleted   bool
+	ChangedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (addressProjection) TableName() string {
+	return "address_projections"
+}
+
+// addressBook is a row of the address_books table, Addresses is the json document of its entries
+type addressBook struct {
+	TenantId         string `gorm:"primaryKey"`
+	UserId           string `gorm:"primaryKey"`
+	AddressCount     int
+	DefaultAddressId *int
+	Addresses        string
+	LastChangedAt    time.Time
+	UpdatedAt        time.Time
+}
+
+func (addressBook) TableName() string {
+	return "address_books"
+}
+
+// AddressBookEntry is an address of the address book document
+type AddressBookEntry struct {
+	AddressId int       `json:"addressId"`
+	Version   int64     `json:"version"`
+	ChangedAt time.Time `json:"changedAt"`
+	events.AddressSnapshot
+}
+
+// Projector keeps the address book of every user up to date with the address events
+type Projector interface {
+	Apply(ctx context.Context, cloudEvent cloudevents.Event, addressEvent events.AddressEvent) error
+	// Reset deletes the read model before it is rebuilt from the beginning of the topics
+	Reset(ctx context.Context) error
+}
+
+// projector stores the latest state of every address and derives the address book of its user from them,
+// the default address of a book is its oldest address
+type projector struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+func NewProjector(db *gorm.DB, logger logger.Logger) Projector {
+	return &projector{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Apply stores the event unless the address already has the same or a later change, so redelivered and
+// out-of-order events leave the read model as it is, events without a version are ordered by their time
+func (p *projector) Apply(ctx context.Context, cloudEvent cloudevents.Event, addressEvent events.AddressEvent) error {
+	deleted := addressEvent.EventType == events.AddressDeleted
+	snapshot := addressEvent.After
+	if deleted {
+		snapshot = addressEvent.Before
+	}
+
+	changedAt := cloudEvent.Time.UTC()
+	if changedAt.IsZero() {
+		// events published before the envelope have no time, they are ordered by their arrival
+		changedAt = time.Now().UTC()
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tenantId, userId, err := owner(tx, cloudEvent, addressEvent, snapshot)
+		if err != nil {
+			return err
+		}
+
+		// the book is locked first, so the changes of the addresses of a user rebuild it one after another
+		if userId != "" {
+			if err := lockBook(tx, tenantId, userId); err != nil {
+				return err
+			}
+		}
+
+		current, found, err := findAddress(tx, addressEvent.AddressId)
+		if err != nil {
+			return err
+		}
+		if found && !newer(current, deleted, addressEvent.Version, changedAt) {
+			p.logger.Infof("Stale address event skipped, EventId: %s, AddressId: %d, Version: %d, Projected Version: %d",
+				cloudEvent.Id, addressEvent.AddressId, addressEvent.Version, current.Version)
+			return nil
+		}
+
+		projected := addressProjection{
+			AddressId: addressEvent.AddressId,
+			TenantId:  tenantId,
+			UserId:    userId,
+			Version:   addressEvent.Version,
+			Snapshot:  current.Snapshot,
+			Deleted:   deleted,
+			ChangedAt: changedAt,
+		}
+		if snapshot != nil {
+			encoded, err := json.Marshal(snapshot)
+			if err != nil {
+				return err
+			}
+			projected.Snapshot = string(encoded)
+		}
+
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&projected).Error; err != nil {
+			return fmt.Errorf("projection.Apply.SaveAddress: %w", err)
+		}
+
+		if userId == "" {
+			return nil
+		}
+		return rebuildBook(tx, tenantId, userId)
+	})
+}
+
+func (p *projector) Reset(ctx context.Context) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx = tx.Session(&gorm.Session{AllowGlobalUpdate: true})
+		if err := tx.Delete(&addressBook{}).Error; err != nil {
+			return fmt.Errorf("projection.Reset.AddressBooks: %w", err)
+		}
+		if err := tx.Delete(&addressProjection{}).Error; err != nil {
+			return fmt.Errorf("projection.Reset.Addresses: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// newer reports whether the event is a later change than the projected address, a deleted address never comes back
+func newer(current addressProjection, deleted bool, version int64, changedAt time.Time) bool {
+	switch {
+	case current.Deleted:
+		return false
+	case deleted:
+		return true
+	case version != 0 || current.Version != 0:
+		return version > current.Version
+	default:
+		return changedAt.After(current.ChangedAt)
+	}
+}
+
+// owner returns the tenant and user of the address, version 1 deletes only carry the address id so
+// their owner is taken from the projected address
+func owner(tx *gorm.DB, cloudEvent cloudevents.Event, addressEvent events.AddressEvent, snapshot *events.AddressSnapshot) (string, string, error) {
+	tenantId, userId := addressEvent.TenantId, addressEvent.UserId
+	if tenantId == "" {
+		tenantId = cloudEvent.TenantId
+	}
+	if userId == "" && snapshot != nil {
+		userId = snapshot.UserId
+	}
+	if userId != "" {
+		return tenantId, userId, nil
+	}
+
+	current, found, err := findAddress(tx, addressEvent.AddressId)
+	if err != nil || !found {
+		return tenantId, "", err
+	}
+
+	return current.TenantId, current.UserId, nil
+}
+
+func findAddress(tx *gorm.DB, addressId int) (addressProjection, bool, error) {
+	var address addressProjection
+	result := tx.Where("address_id = ?", addressId).Limit(1).Find(&address)
+	if result.Error != nil {
+		return address, false, fmt.Errorf("projection.Apply.GetAddress(%d): %w", addressId, result.Error)
+	}
+
+	return address, result.RowsAffected > 0, nil
+}
+
+func lockBook(tx *gorm.DB, tenantId, userId string) error {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&addressBook{TenantId: tenantId, UserId: userId, Addresses: "[]"}).Error
+	if err != nil {
+		return fmt.Errorf("projection.Apply.CreateAddressBook: %w", err)
+	}
+
+	var book addressBook
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND user_id = ?", tenantId, userId).
+		Take(&book).Error
+	if err != nil {
+		return fmt.Errorf("projection.Apply.LockAddressBook: %w", err)
+	}
+
+	return nil
+}
+
+// rebuildBook derives the address book from the projected addresses of the user
+func rebuildBook(tx *gorm.DB, tenantId, userId string) error {
+	var addresses []addressProjection
+	err := tx.Where("tenant_id = ? AND user_id = ?", tenantId, userId).
+		Order("address_id").
+		Find(&addresses).Error
+	if err != nil {
+		return fmt.Errorf("projection.Apply.GetAddresses: %w", err)
+	}
+
+	book := addressBook{TenantId: tenantId, UserId: userId}
+	entries := make([]AddressBookEntry, 0, len(addresses))
+	for _, address := range addresses {
+		// deletes count as changes of the book too
+		if address.ChangedAt.After(book.LastChangedAt) {
+			book.LastChangedAt = address.ChangedAt
+		}
+		if address.Deleted {
+			continue
+		}
+
+		entry := AddressBookEntry{AddressId: address.AddressId, Version: address.Version, ChangedAt: address.ChangedAt}
+		if address.Snapshot != "" {
+			if err := json.Unmarshal([]byte(address.Snapshot), &entry.AddressSnapshot); err != nil {
+				return fmt.Errorf("projection.Apply.DecodeAddress(%d): %w", address.AddressId, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	book.AddressCount = len(entries)
+	if len(entries) > 0 {
+		book.DefaultAddressId = &entries[0].AddressId
+	}
+
+	encoded, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	book.Addresses = string(encoded)
+
+	if err := tx.Save(&book).Error; err != nil {
+		return fmt.Errorf("projection.Apply.SaveAddressBook: %w", err)
+	}
+
+	return nil
+}
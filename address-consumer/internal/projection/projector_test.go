@@ -0,0 +1,205 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sefikcan/address-consumer/pkg/logger/mocks"
+	"github.com/sefikcan/address-consumer/pkg/storage/postgres"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+const testTenant = "default"
+
+var baseTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func setupProjector(t *testing.T) (Projector, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	// every connection of an in-memory sqlite database is a separate database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, postgres.Migrate(db))
+
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Infof", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	return NewProjector(db, mockLogger), db
+}
+
+// change is an address event together with its envelope
+type change struct {
+	cloudEvent   cloudevents.Event
+	addressEvent events.AddressEvent
+}
+
+func newChange(eventType string, addressId int, version int64, minute int, userId, city string) change {
+	snapshot := &events.AddressSnapshot{City: city, Country: "Turkey", FullAddress: city + " Cad. No 1", UserId: userId}
+	addressEvent := events.AddressEvent{EventType: eventType, AddressId: addressId, UserId: userId, TenantId: testTenant, Version: version}
+	if eventType == events.AddressDeleted {
+		addressEvent.Before = snapshot
+	} else {
+		addressEvent.After = snapshot
+	}
+
+	return change{
+		cloudEvent:   cloudevents.Event{Id: eventType + "-" + city, Time: baseTime.Add(time.Duration(minute) * time.Minute)},
+		addressEvent: addressEvent,
+	}
+}
+
+func apply(t *testing.T, projector Projector, changes ...change) {
+	for _, c := range changes {
+		assert.NoError(t, projector.Apply(context.Background(), c.cloudEvent, c.addressEvent))
+	}
+}
+
+func getBook(t *testing.T, db *gorm.DB, userId string) (addressBook, []AddressBookEntry) {
+	var book addressBook
+	assert.NoError(t, db.Where("tenant_id = ? AND user_id = ?", testTenant, userId).Take(&book).Error)
+
+	var entries []AddressBookEntry
+	assert.NoError(t, json.Unmarshal([]byte(book.Addresses), &entries))
+
+	return book, entries
+}
+
+func TestProjector_SkipsStaleAndOutOfOrderEvents(t *testing.T) {
+	projector, db := setupProjector(t)
+
+	apply(t, projector,
+		newChange(events.AddressCreated, 1, 1, 0, "42", "Istanbul"),
+		newChange(events.AddressUpdated, 1, 3, 2, "42", "Izmir"),
+		// version 2 arrives after version 3 and a redelivery of version 3 follows
+		newChange(events.AddressUpdated, 1, 2, 1, "42", "Ankara"),
+		newChange(events.AddressUpdated, 1, 3, 2, "42", "Bursa"),
+	)
+
+	book, entries := getBook(t, db, "42")
+	assert.Equal(t, 1, book.AddressCount)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int64(3), entries[0].Version)
+	assert.Equal(t, "Izmir", entries[0].City)
+	assert.Equal(t, baseTime.Add(2*time.Minute), book.LastChangedAt.UTC())
+}
+
+func TestProjector_OrdersEventsWithoutVersionByTime(t *testing.T) {
+	projector, db := setupProjector(t)
+
+	apply(t, projector,
+		newChange(events.AddressCreated, 1, 0, 0, "42", "Istanbul"),
+		newChange(events.AddressUpdated, 1, 0, 5, "42", "Izmir"),
+		newChange(events.AddressUpdated, 1, 0, 3, "42", "Ankara"),
+	)
+
+	_, entries := getBook(t, db, "42")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Izmir", entries[0].City)
+}
+
+func TestProjector_DeleteBeforeCreateKeepsTheAddressDeleted(t *testing.T) {
+	projector, db := setupProjector(t)
+
+	apply(t, projector,
+		newChange(events.AddressDeleted, 1, 2, 1, "42", "Istanbul"),
+		newChange(events.AddressCreated, 1, 1, 0, "42", "Istanbul"),
+	)
+
+	book, entries := getBook(t, db, "42")
+	assert.Equal(t, 0, book.AddressCount)
+	assert.Empty(t, entries)
+	assert.Nil(t, book.DefaultAddressId)
+
+	var projected addressProjection
+	assert.NoError(t, db.Where("address_id = ?", 1).Take(&projected).Error)
+	assert.True(t, projected.Deleted)
+}
+
+func TestProjector_DeleteWithoutOwnerUsesTheProjectedAddress(t *testing.T) {
+	projector, db := setupProjector(t)
+	apply(t, projector, newChange(events.AddressCreated, 1, 1, 0, "42", "Istanbul"))
+
+	// version 1 deletes only carry the address id
+	err := projector.Apply(context.Background(),
+		cloudevents.Event{Id: "delete-1", Time: baseTime.Add(time.Minute)},
+		events.AddressEvent{EventType: events.AddressDeleted, AddressId: 1})
+	assert.NoError(t, err)
+
+	book, entries := getBook(t, db, "42")
+	assert.Equal(t, 0, book.AddressCount)
+	assert.Empty(t, entries)
+	assert.Equal(t, baseTime.Add(time.Minute), book.LastChangedAt.UTC())
+}
+
+func TestProjector_DefaultAddressIsTheOldest(t *testing.T) {
+	projector, db := setupProjector(t)
+
+	apply(t, projector,
+		newChange(events.AddressCreated, 5, 1, 1, "42", "Izmir"),
+		newChange(events.AddressCreated, 3, 1, 0, "42", "Istanbul"),
+		newChange(events.AddressCreated, 4, 1, 0, "7", "Ankara"),
+	)
+
+	book, entries := getBook(t, db, "42")
+	assert.Equal(t, 2, book.AddressCount)
+	assert.Equal(t, []int{3, 5}, []int{entries[0].AddressId, entries[1].AddressId})
+	if assert.NotNil(t, book.DefaultAddressId) {
+		assert.Equal(t, 3, *book.DefaultAddressId)
+	}
+
+	apply(t, projector, newChange(events.AddressDeleted, 3, 2, 2, "42", "Istanbul"))
+
+	book, _ = getBook(t, db, "42")
+	assert.Equal(t, 1, book.AddressCount)
+	if assert.NotNil(t, book.DefaultAddressId) {
+		assert.Equal(t, 5, *book.DefaultAddressId)
+	}
+
+	// the books of other users are not touched
+	other, _ := getBook(t, db, "7")
+	assert.Equal(t, 1, other.AddressCount)
+	if assert.NotNil(t, other.DefaultAddressId) {
+		assert.Equal(t, 4, *other.DefaultAddressId)
+	}
+}
+
+func TestProjector_RebuildAfterReset(t *testing.T) {
+	projector, db := setupProjector(t)
+	history := []change{
+		newChange(events.AddressCreated, 1, 1, 0, "42", "Istanbul"),
+		newChange(events.AddressCreated, 2, 1, 1, "42", "Izmir"),
+		newChange(events.AddressUpdated, 1, 2, 2, "42", "Ankara"),
+		newChange(events.AddressDeleted, 2, 2, 3, "42", "Izmir"),
+	}
+	apply(t, projector, history...)
+	before, beforeEntries := getBook(t, db, "42")
+
+	assert.NoError(t, projector.Reset(context.Background()))
+
+	var books, addresses int64
+	db.Model(&addressBook{}).Count(&books)
+	db.Model(&addressProjection{}).Count(&addresses)
+	assert.Equal(t, int64(0), books)
+	assert.Equal(t, int64(0), addresses)
+
+	// the topics are read from the beginning again, the partitions of the topics interleave differently
+	apply(t, projector, history[0], history[3], history[2], history[1])
+	after, afterEntries := getBook(t, db, "42")
+
+	assert.Equal(t, before.AddressCount, after.AddressCount)
+	assert.Equal(t, before.DefaultAddressId, after.DefaultAddressId)
+	assert.Equal(t, before.LastChangedAt.UTC(), after.LastChangedAt.UTC())
+	assert.Equal(t, beforeEntries, afterEntries)
+	assert.Equal(t, "Ankara", afterEntries[0].City)
+}
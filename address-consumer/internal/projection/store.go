@@ -0,0 +1,34 @@
+package projection
+
+import (
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/storage/postgres"
+	"github.com/sefikcan/address-consumer/pkg/storage/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	StorePostgres = "postgres"
+	StoreSqlite   = "sqlite"
+)
+
+// NewDb connects the configured store of the read model
+func NewDb(cfg *config.Config) (*gorm.DB, error) {
+	switch cfg.Projection.Store {
+	case "", StorePostgres:
+		db, err := postgres.NewPsqlDb(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("projection.NewDb.NewPsqlDb: %w", err)
+		}
+		return db, nil
+	case StoreSqlite:
+		db, err := sqlite.NewSqliteDb(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("projection.NewDb.NewSqliteDb: %w", err)
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("projection.NewDb: unsupported store %q", cfg.Projection.Store)
+	}
+}
@@ -2,22 +2,47 @@ package service
 
 import (
 	"context"
+	"github.com/sefikcan/address-consumer/internal/projection"
+	"github.com/sefikcan/address-consumer/internal/webhook"
 	"github.com/sefikcan/address-consumer/pkg/logger"
 	"github.com/segmentio/kafka-go"
 )
 
 type AddressCreatedService struct {
-	logger logger.Logger
+	logger  logger.Logger
+	decoder *AddressEventDecoder
+	// projector is nil when the read model is disabled
+	projector projection.Projector
+	// notifier is nil when webhooks are disabled
+	notifier webhook.Notifier
 }
 
-func NewAddressCreatedService(logger logger.Logger) *AddressCreatedService {
+func NewAddressCreatedService(logger logger.Logger, decoder *AddressEventDecoder, projector projection.Projector, notifier webhook.Notifier) *AddressCreatedService {
 	return &AddressCreatedService{
-		logger: logger,
+		logger:    logger,
+		decoder:   decoder,
+		projector: projector,
+		notifier:  notifier,
 	}
 }
 
 func (s *AddressCreatedService) ProcessMessage(ctx context.Context, msg kafka.Message) error {
-	s.logger.Infof("Address Created being processed: %s", string(msg.Value))
+	cloudEvent, addressEvent, err := s.decoder.Decode(msg)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Address Created being processed, EventId: %s, AddressId: %d, Tenant: %s, Actor: %s, RequestId: %s, Traceparent: %s, Address: %+v",
+		cloudEvent.Id, addressEvent.AddressId, cloudEvent.TenantId, cloudEvent.Actor, cloudEvent.RequestId, cloudEvent.Traceparent, addressEvent.After)
 
-	return nil
+	if s.projector != nil {
+		if err := s.projector.Apply(ctx, cloudEvent, addressEvent); err != nil {
+			return err
+		}
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	return s.notifier.Notify(ctx, cloudEvent, addressEvent)
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/sefikcan/address-consumer/internal/consumer"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/sefikcan/address-events/registry"
+	"github.com/sefikcan/address-events/serde"
+	"github.com/segmentio/kafka-go"
+	"strings"
+)
+
+// AddressEventDecoder reads address events in every CloudEvents content mode and serialization format
+type AddressEventDecoder struct {
+	registry registry.Registry
+}
+
+func NewAddressEventDecoder(registry registry.Registry) *AddressEventDecoder {
+	return &AddressEventDecoder{
+		registry: registry,
+	}
+}
+
+// Decode reads the address event of a structured or binary mode CloudEvent and upcasts it to the
+// current contract version, messages published before the envelope are version 1 json payloads
+// a message that can not be decoded fails permanently, only a schema missing from the registry is retried
+func (d *AddressEventDecoder) Decode(msg kafka.Message) (cloudevents.Event, events.AddressEvent, error) {
+	cloudEvent, addressEvent, err := d.decode(msg)
+	if err != nil && !errors.Is(err, registry.ErrSchemaNotFound) {
+		return cloudEvent, addressEvent, consumer.Permanent(err)
+	}
+
+	return cloudEvent, addressEvent, err
+}
+
+func (d *AddressEventDecoder) decode(msg kafka.Message) (cloudevents.Event, events.AddressEvent, error) {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[strings.ToLower(header.Key)] = string(header.Value)
+	}
+
+	cloudEvent, err := cloudevents.Decode(headers, msg.Value)
+	if err != nil && !isLegacyMessage(headers, msg.Value) {
+		return cloudevents.Event{}, events.AddressEvent{}, err
+	}
+	if err != nil {
+		cloudEvent = cloudevents.Event{Data: msg.Value}
+	}
+
+	// the content type selects the serializer, protobuf and avro writer schemas come from the registry
+	serializer, err := serde.ForContentType(cloudEvent.DataContentType, d.registry)
+	if err != nil {
+		return cloudEvent, events.AddressEvent{}, err
+	}
+
+	data, err := serializer.Deserialize(cloudEvent.Data)
+	if err != nil {
+		return cloudEvent, events.AddressEvent{}, err
+	}
+
+	addressEvent, err := events.DecodeAddressEvent(cloudEvent.DataSchema, data)
+	return cloudEvent, addressEvent, err
+}
+
+// isLegacyMessage reports a json payload without the CloudEvents envelope
+func isLegacyMessage(headers map[string]string, value []byte) bool {
+	if contentType := headers[cloudevents.HeaderContentType]; contentType != "" && contentType != serde.ContentTypeJson {
+		return false
+	}
+
+	var envelope struct {
+		SpecVersion string `json:"specversion"`
+	}
+	return json.Unmarshal(value, &envelope) == nil && envelope.SpecVersion == ""
+}
@@ -2,22 +2,47 @@ package service
 
 import (
 	"context"
+	"github.com/sefikcan/address-consumer/internal/projection"
+	"github.com/sefikcan/address-consumer/internal/webhook"
 	"github.com/sefikcan/address-consumer/pkg/logger"
 	"github.com/segmentio/kafka-go"
 )
 
 type AddressUpdatedService struct {
-	logger logger.Logger
+	logger  logger.Logger
+	decoder *AddressEventDecoder
+	// projector is nil when the read model is disabled
+	projector projection.Projector
+	// notifier is nil when webhooks are disabled
+	notifier webhook.Notifier
 }
 
-func NewAddressUpdatedService(logger logger.Logger) *AddressUpdatedService {
+func NewAddressUpdatedService(logger logger.Logger, decoder *AddressEventDecoder, projector projection.Projector, notifier webhook.Notifier) *AddressUpdatedService {
 	return &AddressUpdatedService{
-		logger: logger,
+		logger:    logger,
+		decoder:   decoder,
+		projector: projector,
+		notifier:  notifier,
 	}
 }
 
 func (s *AddressUpdatedService) ProcessMessage(ctx context.Context, msg kafka.Message) error {
-	s.logger.Infof("Address Updated being processed: %s", string(msg.Value))
+	cloudEvent, addressEvent, err := s.decoder.Decode(msg)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Address Updated being processed, EventId: %s, AddressId: %d, Tenant: %s, Actor: %s, RequestId: %s, Traceparent: %s, Before: %+v, After: %+v",
+		cloudEvent.Id, addressEvent.AddressId, cloudEvent.TenantId, cloudEvent.Actor, cloudEvent.RequestId, cloudEvent.Traceparent, addressEvent.Before, addressEvent.After)
 
-	return nil
+	if s.projector != nil {
+		if err := s.projector.Apply(ctx, cloudEvent, addressEvent); err != nil {
+			return err
+		}
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	return s.notifier.Notify(ctx, cloudEvent, addressEvent)
 }
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker stops posting to an endpoint after threshold failures in a row, once the cooldown passed a single
+// delivery probes the endpoint and its success closes the breaker again
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	endpoints map[int]*endpoint
+}
+
+type endpoint struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		endpoints: make(map[int]*endpoint),
+	}
+}
+
+// allow reports whether a delivery may be posted to the subscription, otherwise it returns the time the breaker
+// lets the next delivery through
+func (b *breaker) allow(subscriptionId int, now time.Time) (time.Time, bool) {
+	if b.threshold <= 0 {
+		return time.Time{}, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.endpoints[subscriptionId]
+	if !ok || state.failures < b.threshold {
+		return time.Time{}, true
+	}
+	if now.Before(state.openUntil) {
+		return state.openUntil, false
+	}
+
+	// the probe holds the breaker open, so the other deliveries of the batch wait for its result
+	state.openUntil = now.Add(b.cooldown)
+	return time.Time{}, true
+}
+
+func (b *breaker) success(subscriptionId int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.endpoints, subscriptionId)
+}
+
+func (b *breaker) failure(subscriptionId int, now time.Time) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.endpoints[subscriptionId]
+	if !ok {
+		state = &endpoint{}
+		b.endpoints[subscriptionId] = state
+	}
+
+	state.failures++
+	if state.failures >= b.threshold {
+		state.openUntil = now.Add(b.cooldown)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBreaker_OpensAfterThresholdAndProbesAfterCooldown(t *testing.T) {
+	b := newBreaker(2, time.Minute)
+	now := time.Now()
+
+	b.failure(1, now)
+	_, ok := b.allow(1, now)
+	assert.True(t, ok, "below the threshold")
+
+	b.failure(1, now)
+	openUntil, ok := b.allow(1, now.Add(time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, now.Add(time.Minute), openUntil)
+
+	// other endpoints are not affected
+	_, ok = b.allow(2, now)
+	assert.True(t, ok)
+
+	// once the cooldown passed a single probe is let through
+	probeAt := now.Add(time.Minute)
+	_, ok = b.allow(1, probeAt)
+	assert.True(t, ok)
+	openUntil, ok = b.allow(1, probeAt)
+	assert.False(t, ok)
+	assert.Equal(t, probeAt.Add(time.Minute), openUntil)
+
+	b.success(1)
+	_, ok = b.allow(1, probeAt)
+	assert.True(t, ok)
+}
+
+func TestBreaker_FailedProbeOpensAgain(t *testing.T) {
+	b := newBreaker(1, time.Minute)
+	now := time.Now()
+
+	b.failure(1, now)
+	probeAt := now.Add(time.Minute)
+	_, ok := b.allow(1, probeAt)
+	assert.True(t, ok)
+
+	b.failure(1, probeAt)
+	openUntil, ok := b.allow(1, probeAt.Add(time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, probeAt.Add(time.Minute), openUntil)
+}
+
+func TestBreaker_DisabledWithoutThreshold(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		b.failure(1, now)
+	}
+
+	_, ok := b.allow(1, now)
+	assert.True(t, ok)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"strings"
+	"time"
+)
+
+// delivery states, the address api shows them in the delivery log and queues deliveries again as pending
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// subscription is a row of the webhook_subscriptions table of the address api
+type subscription struct {
+	Id         int
+	TenantId   string
+	Url        string
+	EventTypes string
+	Secret     string
+	Active     bool
+}
+
+func (subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (s subscription) subscribed(eventType string) bool {
+	for _, subscribed := range strings.Fields(s.EventTypes) {
+		if subscribed == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delivery is a row of the webhook_deliveries table, an event is delivered once per subscription
+type delivery struct {
+	Id             int `gorm:"primaryKey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	TenantId       string
+	SubscriptionId int
+	EventId        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+func (delivery) TableName() string {
+	return "webhook_deliveries"
+}
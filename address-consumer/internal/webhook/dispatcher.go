@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	contentType = "application/cloudevents+json"
+	// maxErrorBody is the part of an error response kept in the delivery log
+	maxErrorBody = 512
+)
+
+// Dispatcher posts the pending deliveries and retries the failed ones on the configured schedule, several
+// dispatchers can share the deliveries as a claimed delivery is skipped by the others
+type Dispatcher struct {
+	db           *gorm.DB
+	client       *http.Client
+	retryDelays  []time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	// lease is the time a claimed delivery is hidden from the other dispatchers
+	lease   time.Duration
+	breaker *breaker
+	logger  logger.Logger
+	now     func() time.Time
+}
+
+func NewDispatcher(cfg config.WebhookConfig, db *gorm.DB, logger logger.Logger) (*Dispatcher, error) {
+	retryDelays := make([]time.Duration, 0, len(cfg.RetryDelays))
+	for _, retryDelay := range cfg.RetryDelays {
+		delay, err := time.ParseDuration(retryDelay)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("webhook.NewDispatcher: invalid retry delay %q", retryDelay)
+		}
+		retryDelays = append(retryDelays, delay)
+	}
+
+	timeout := time.Duration(max(cfg.TimeoutMs, 1000)) * time.Millisecond
+
+	return &Dispatcher{
+		db:           db,
+		client:       newClient(timeout, cfg.AllowPrivateNetworks),
+		retryDelays:  retryDelays,
+		pollInterval: time.Duration(max(cfg.PollIntervalMs, 100)) * time.Millisecond,
+		batchSize:    max(cfg.BatchSize, 1),
+		lease:        2*timeout + time.Minute,
+		breaker:      newBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldownMs)*time.Millisecond),
+		logger:       logger,
+		now:          time.Now,
+	}, nil
+}
+
+// Run dispatches until the context is done, it polls again right away while full batches are due
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		claimed, err := d.dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Errorf("Webhook deliveries could not be dispatched: %v", err)
+		}
+
+		if claimed < d.batchSize || err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(d.pollInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	deliveries, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions, err := d.subscriptions(ctx, deliveries)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, claimed := range deliveries {
+		wg.Add(1)
+		go func(claimed delivery) {
+			defer wg.Done()
+			if err := d.deliver(ctx, claimed, subscriptions[claimed.SubscriptionId]); err != nil && ctx.Err() == nil {
+				d.logger.Errorf("Webhook delivery %d could not be recorded: %v", claimed.Id, err)
+			}
+		}(claimed)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// claim locks the due deliveries and moves their next attempt past the lease, a dispatcher stopped while
+// posting leaves the delivery to be retried once the lease expired
+func (d *Dispatcher) claim(ctx context.Context) ([]delivery, error) {
+	var deliveries []delivery
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := d.now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").
+			Limit(d.batchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.Id)
+		}
+
+		return tx.Model(&delivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(d.lease)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webhook.Dispatcher.Claim: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (d *Dispatcher) subscriptions(ctx context.Context, deliveries []delivery) (map[int]*subscription, error) {
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.SubscriptionId)
+	}
+
+	var subscriptions []subscription
+	if err := d.db.WithContext(ctx).Where("id IN ?", ids).Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("webhook.Dispatcher.GetSubscriptions: %w", err)
+	}
+
+	result := make(map[int]*subscription, len(subscriptions))
+	for i := range subscriptions {
+		result[subscriptions[i].Id] = &subscriptions[i]
+	}
+
+	return result, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery delivery, subscription *subscription) error {
+	now := d.now()
+
+	// deliveries of a deactivated webhook stay in its log, they can be re-delivered once it is active again
+	if subscription == nil || !subscription.Active {
+		return d.record(ctx, delivery.Id, map[string]interface{}{
+			"status":     StatusFailed,
+			"last_error": "webhook is inactive",
+		})
+	}
+
+	if openUntil, ok := d.breaker.allow(subscription.Id, now); !ok {
+		return d.record(ctx, delivery.Id, map[string]interface{}{
+			"next_attempt_at": openUntil,
+		})
+	}
+
+	statusCode, err := d.post(ctx, delivery, subscription, now)
+	if err != nil && ctx.Err() != nil {
+		// the attempt was cut short by the shutdown, the delivery is retried once its lease expired
+		return nil
+	}
+
+	attempts := delivery.Attempts + 1
+	if err == nil {
+		d.breaker.success(subscription.Id)
+		deliveredAt := d.now()
+		return d.record(ctx, delivery.Id, map[string]interface{}{
+			"status":           StatusDelivered,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     &deliveredAt,
+		})
+	}
+
+	d.breaker.failure(subscription.Id, now)
+	update := map[string]interface{}{
+		"attempts":         attempts,
+		"last_status_code": statusCode,
+		"last_error":       err.Error(),
+	}
+	if attempts > len(d.retryDelays) {
+		update["status"] = StatusFailed
+		d.logger.Warnf("Webhook delivery failed for good, WebhookId: %d, DeliveryId: %d, EventId: %s, Attempts: %d: %v",
+			subscription.Id, delivery.Id, delivery.EventId, attempts, err)
+	} else {
+		update["next_attempt_at"] = now.Add(d.retryDelays[attempts-1])
+	}
+
+	return d.record(ctx, delivery.Id, update)
+}
+
+// post sends the signed payload, every response but a 2xx fails the attempt
+func (d *Dispatcher) post(ctx context.Context, delivery delivery, subscription *subscription, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, now.Unix(), body))
+	request.Header.Set(HeaderEventId, delivery.EventId)
+	request.Header.Set(HeaderEventType, delivery.EventType)
+	request.Header.Set(HeaderDeliveryId, strconv.Itoa(delivery.Id))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d: %s", response.StatusCode, responseBody)
+	}
+
+	return response.StatusCode, nil
+}
+
+// record stores the result of an attempt, it outlives the shutdown so a finished attempt is never posted again
+func (d *Dispatcher) record(ctx context.Context, id int, update map[string]interface{}) error {
+	return d.db.WithContext(context.WithoutCancel(ctx)).
+		Model(&delivery{}).
+		Where("id = ?", id).
+		Updates(update).Error
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/logger/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test_secret"
+
+func setupDispatcher(t *testing.T, cfg config.WebhookConfig) (*Dispatcher, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	// every connection of an in-memory sqlite database is a separate database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, db.AutoMigrate(&subscription{}, &delivery{}))
+
+	mockLogger := new(mocks.Logger)
+	mockLogger.On("Warnf", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	mockLogger.On("Errorf", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	dispatcher, err := NewDispatcher(cfg, db, mockLogger)
+	assert.NoError(t, err)
+
+	return dispatcher, db
+}
+
+func addDelivery(t *testing.T, db *gorm.DB, url string, active bool) delivery {
+	webhook := subscription{TenantId: "default", Url: url, EventTypes: "AddressCreated", Secret: testSecret, Active: active}
+	assert.NoError(t, db.Create(&webhook).Error)
+
+	pending := delivery{
+		TenantId:       "default",
+		SubscriptionId: webhook.Id,
+		EventId:        "event-1",
+		EventType:      "AddressCreated",
+		Payload:        `{"id":"event-1"}`,
+		Status:         StatusPending,
+		NextAttemptAt:  time.Now().Add(-time.Second),
+	}
+	assert.NoError(t, db.Create(&pending).Error)
+
+	return pending
+}
+
+func getDelivery(t *testing.T, db *gorm.DB, id int) delivery {
+	var stored delivery
+	assert.NoError(t, db.Where("id = ?", id).Take(&stored).Error)
+	return stored
+}
+
+func TestDispatcher_PostsSignedDelivery(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher, db := setupDispatcher(t, config.WebhookConfig{RetryDelays: []string{"1m"}, AllowPrivateNetworks: true})
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	pending := addDelivery(t, db, server.URL, true)
+
+	claimed, err := dispatcher.dispatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claimed)
+	if assert.NotNil(t, received) {
+		assert.Equal(t, `{"id":"event-1"}`, string(body))
+		assert.Equal(t, contentType, received.Header.Get("Content-Type"))
+		assert.Equal(t, Sign(testSecret, now.Unix(), body), received.Header.Get(HeaderSignature))
+		assert.Equal(t, "event-1", received.Header.Get(HeaderEventId))
+		assert.Equal(t, "AddressCreated", received.Header.Get(HeaderEventType))
+	}
+
+	stored := getDelivery(t, db, pending.Id)
+	assert.Equal(t, StatusDelivered, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, http.StatusNoContent, stored.LastStatusCode)
+	assert.NotNil(t, stored.DeliveredAt)
+}
+
+func TestDispatcher_RetriesOnScheduleThenFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dispatcher, db := setupDispatcher(t, config.WebhookConfig{RetryDelays: []string{"1m"}, AllowPrivateNetworks: true})
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	pending := addDelivery(t, db, server.URL, true)
+
+	_, err := dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+
+	stored := getDelivery(t, db, pending.Id)
+	assert.Equal(t, StatusPending, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, stored.LastStatusCode)
+	assert.WithinDuration(t, now.Add(time.Minute), stored.NextAttemptAt, time.Second)
+
+	// nothing is due before the retry delay passed
+	claimed, err := dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, claimed)
+
+	now = now.Add(2 * time.Minute)
+	_, err = dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+
+	stored = getDelivery(t, db, pending.Id)
+	assert.Equal(t, StatusFailed, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	var targetHits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHits.Add(1)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	dispatcher, db := setupDispatcher(t, config.WebhookConfig{RetryDelays: []string{"1m"}, AllowPrivateNetworks: true})
+	pending := addDelivery(t, db, redirect.URL, true)
+
+	_, err := dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+
+	stored := getDelivery(t, db, pending.Id)
+	assert.Equal(t, StatusPending, stored.Status)
+	assert.Equal(t, http.StatusFound, stored.LastStatusCode)
+	assert.Equal(t, int32(0), targetHits.Load())
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	dispatcher, db := setupDispatcher(t, config.WebhookConfig{RetryDelays: []string{"1m"}})
+	// the host name resolves to the loopback address, it is refused once it is resolved
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	pending := addDelivery(t, db, "http://localhost:"+port, true)
+
+	_, err := dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+
+	stored := getDelivery(t, db, pending.Id)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Contains(t, stored.LastError, errForbiddenAddress.Error())
+	assert.Equal(t, int32(0), hits.Load())
+}
+
+func TestDispatcher_InactiveWebhookFails(t *testing.T) {
+	dispatcher, db := setupDispatcher(t, config.WebhookConfig{})
+	pending := addDelivery(t, db, "https://example.com/hooks", false)
+
+	_, err := dispatcher.dispatch(context.Background())
+	assert.NoError(t, err)
+
+	stored := getDelivery(t, db, pending.Id)
+	assert.Equal(t, StatusFailed, stored.Status)
+	assert.Equal(t, "webhook is inactive", stored.LastError)
+	assert.Equal(t, 0, stored.Attempts)
+}
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, public(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/logger"
+	events "github.com/sefikcan/address-events"
+	"github.com/sefikcan/address-events/cloudevents"
+	"github.com/sefikcan/address-events/serde"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Notifier queues an address event for the webhooks subscribed to it, the dispatcher posts the queued deliveries
+type Notifier interface {
+	Notify(ctx context.Context, cloudEvent cloudevents.Event, addressEvent events.AddressEvent) error
+}
+
+type notifier struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+func NewNotifier(db *gorm.DB, logger logger.Logger) Notifier {
+	return &notifier{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Notify stores a pending delivery per subscription, a redelivered event does not queue a second delivery
+func (n *notifier) Notify(ctx context.Context, cloudEvent cloudevents.Event, addressEvent events.AddressEvent) error {
+	// receivers dedupe by the event id, events published before the CloudEvents envelope have none
+	if cloudEvent.Id == "" {
+		n.logger.Warnf("Address event without id is not delivered to webhooks, AddressId: %d", addressEvent.AddressId)
+		return nil
+	}
+
+	tenantId := addressEvent.TenantId
+	if tenantId == "" {
+		tenantId = cloudEvent.TenantId
+	}
+
+	var subscriptions []subscription
+	err := n.db.WithContext(ctx).
+		Where("tenant_id = ? AND active = ?", tenantId, true).
+		Find(&subscriptions).Error
+	if err != nil {
+		return fmt.Errorf("webhook.Notify.GetSubscriptions: %w", err)
+	}
+
+	var subscribed []subscription
+	for _, subscription := range subscriptions {
+		if subscription.subscribed(addressEvent.EventType) {
+			subscribed = append(subscribed, subscription)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	encoded, err := payload(cloudEvent, addressEvent)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]delivery, 0, len(subscribed))
+	for _, subscription := range subscribed {
+		deliveries = append(deliveries, delivery{
+			TenantId:       tenantId,
+			SubscriptionId: subscription.Id,
+			EventId:        cloudEvent.Id,
+			EventType:      addressEvent.EventType,
+			Payload:        encoded,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+		})
+	}
+
+	err = n.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}}, DoNothing: true}).
+		Create(&deliveries).Error
+	if err != nil {
+		return fmt.Errorf("webhook.Notify.CreateDeliveries: %w", err)
+	}
+
+	return nil
+}
+
+// payload is the event as a structured CloudEvent with json data in the current contract version, whatever
+// format it was published in
+func payload(cloudEvent cloudevents.Event, addressEvent events.AddressEvent) (string, error) {
+	data, err := json.Marshal(addressEvent)
+	if err != nil {
+		return "", err
+	}
+
+	cloudEvent.Data = data
+	cloudEvent.DataContentType = serde.ContentTypeJson
+	cloudEvent.DataSchema = events.DataSchema(events.CurrentVersion)
+
+	encoded, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// headers of a delivery request
+const (
+	// HeaderSignature is t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>" keyed by the webhook secret>
+	HeaderSignature = "X-Webhook-Signature"
+	// HeaderEventId is the same for every attempt and redelivery of an event, receivers dedupe by it
+	HeaderEventId    = "X-Webhook-Event-Id"
+	HeaderEventType  = "X-Webhook-Event-Type"
+	HeaderDeliveryId = "X-Webhook-Delivery-Id"
+)
+
+// Sign returns the signature header of the body, the timestamp lets receivers reject replayed requests
+func Sign(secret string, timestamp int64, body []byte) string {
+	unix := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,17 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+
+	signature := Sign("whsec_test_secret", 1714564800, body)
+
+	assert.Equal(t, "t=1714564800,v1=b56d3a2b3e6ad4801f83b444363678c8bea91948336e14a5cee818cb805183b7", signature)
+	assert.NotEqual(t, signature, Sign("whsec_other_secret", 1714564800, body))
+	assert.NotEqual(t, signature, Sign("whsec_test_secret", 1714564801, body))
+	assert.NotEqual(t, signature, Sign("whsec_test_secret", 1714564800, []byte(`{"id":"event-2"}`)))
+}
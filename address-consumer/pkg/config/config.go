@@ -18,8 +18,81 @@ const (
 )
 
 type Config struct {
-	Logger LoggerConfig `mapstructure:"logger"`
-	Kafka  KafkaConfig  `mapstructure:"kafka"`
+	Logger   LoggerConfig   `mapstructure:"logger"`
+	Kafka    KafkaConfig    `mapstructure:"kafka"`
+	Consumer ConsumerConfig `mapstructure:"consumer"`
+	Dedup    DedupConfig    `mapstructure:"dedup"`
+	Redis    RedisConfig    `mapstructure:"redis"`
+	Postgres PostgresConfig `mapstructure:"postgres"`
+	Metric   MetricConfig   `mapstructure:"metric"`
+	// Projection is the per-user address book the consumer builds from the address events
+	Projection ProjectionConfig `mapstructure:"projection"`
+	// Webhook posts the address events to the webhook subscriptions of the address api, it uses the postgres settings
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+type WebhookConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RetryDelays is the wait before every retry of a failed delivery, a delivery fails for good after the last one
+	RetryDelays    []string `mapstructure:"retryDelays"`
+	TimeoutMs      int      `mapstructure:"timeoutMs"`
+	PollIntervalMs int      `mapstructure:"pollIntervalMs"`
+	BatchSize      int      `mapstructure:"batchSize"`
+	// an endpoint failing BreakerThreshold deliveries in a row gets no delivery for BreakerCooldownMs, 0 disables the breaker
+	BreakerThreshold  int `mapstructure:"breakerThreshold"`
+	BreakerCooldownMs int `mapstructure:"breakerCooldownMs"`
+	// AllowPrivateNetworks lets webhooks post to loopback and private addresses, e.g. to local receivers in development
+	AllowPrivateNetworks bool `mapstructure:"allowPrivateNetworks"`
+}
+
+type ProjectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store is postgres or sqlite, postgres uses the postgres settings
+	Store      string `mapstructure:"store"`
+	SqlitePath string `mapstructure:"sqlitePath"`
+}
+
+type MetricConfig struct {
+	// Url is the address of the admin server serving the metrics and the health endpoints
+	Url         string `mapstructure:"url"`
+	ServiceName string `mapstructure:"serviceName"`
+}
+
+// DedupConfig skips events the consumer group already processed
+type DedupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store is memory, redis or postgres, the memory store only dedupes within the process
+	Store string `mapstructure:"store"`
+	// TtlHours is how long a processed event id is remembered, it has to cover the redelivery window
+	TtlHours  int    `mapstructure:"ttlHours"`
+	KeyPrefix string `mapstructure:"keyPrefix"`
+}
+
+type RedisConfig struct {
+	Addr string `mapstructure:"addr"`
+}
+
+type PostgresConfig struct {
+	Host               string `mapstructure:"host"`
+	Port               string `mapstructure:"port"`
+	UserName           string `mapstructure:"username"`
+	Password           string `mapstructure:"password"`
+	DbName             string `mapstructure:"dbname"`
+	SSLMode            bool   `mapstructure:"ssl-mode"`
+	MaxOpenConnections int    `mapstructure:"maxOpenConnections"`
+	ConnMaxLifeTime    int    `mapstructure:"connMaxLifetime"`
+	MaxIdleConnections int    `mapstructure:"maxIdleConnections"`
+	ConnMaxIdleTime    int    `mapstructure:"connMaxIdleTime"`
+}
+
+type ConsumerConfig struct {
+	// DrainTimeoutMs is the time in-flight messages get to finish after a shutdown signal
+	DrainTimeoutMs int `mapstructure:"drainTimeoutMs"`
+	// RestartBackoffMs doubles after every crash of a consumer up to MaxRestartBackoffMs
+	RestartBackoffMs    int `mapstructure:"restartBackoffMs"`
+	MaxRestartBackoffMs int `mapstructure:"maxRestartBackoffMs"`
+	// StatsIntervalMs is how often the reader stats of a consumer are collected for its readiness and lag
+	StatsIntervalMs int `mapstructure:"statsIntervalMs"`
 }
 
 type LoggerConfig struct {
@@ -31,12 +104,123 @@ type LoggerConfig struct {
 }
 
 type KafkaConfig struct {
-	Brokers        []string `mapstructure:"brokers"`
-	ConsumerGroup  string   `mapstructure:"consumerGroup"`
-	MaxPollRecords int      `mapstructure:"maxPollRecords"`
-	GroupID        string   `mapstructure:"groupId"`
-	AutoCommit     bool     `mapstructure:"autoCommit"`
-	FetchMaxWaitMs int      `mapstructure:"fetchMaxWaitMs"`
+	Brokers []string `mapstructure:"brokers"`
+	// ConsumerGroup is only used when GroupID is empty
+	ConsumerGroup string `mapstructure:"consumerGroup"`
+	// MaxPollRecords is the number of fetched messages buffered ahead of the handler
+	MaxPollRecords int `mapstructure:"maxPollRecords"`
+	// Workers process the messages of a topic concurrently, the messages of a key are processed in order
+	Workers int `mapstructure:"workers"`
+	// WorkerQueueSize bounds the messages waiting for a worker, fetching pauses while the queue is full
+	WorkerQueueSize int    `mapstructure:"workerQueueSize"`
+	GroupID         string `mapstructure:"groupId"`
+	// AutoCommit commits processed messages every CommitIntervalMs, otherwise every message is committed synchronously
+	AutoCommit     bool `mapstructure:"autoCommit"`
+	FetchMaxWaitMs int  `mapstructure:"fetchMaxWaitMs"`
+	// StartOffset is earliest or latest, used by a group without committed offsets
+	StartOffset         string                `mapstructure:"startOffset"`
+	MinBytes            int                   `mapstructure:"minBytes"`
+	MaxBytes            int                   `mapstructure:"maxBytes"`
+	CommitIntervalMs    int                   `mapstructure:"commitIntervalMs"`
+	SessionTimeoutMs    int                   `mapstructure:"sessionTimeoutMs"`
+	HeartbeatIntervalMs int                   `mapstructure:"heartbeatIntervalMs"`
+	TLS                 KafkaTLSConfig        `mapstructure:"tls"`
+	SASL                KafkaSASLConfig       `mapstructure:"sasl"`
+	Retry               KafkaRetryConfig      `mapstructure:"retry"`
+	DeadLetter          KafkaDeadLetterConfig `mapstructure:"deadLetter"`
+	// Topics overrides the reader settings per topic, zero values inherit the settings above
+	Topics map[string]KafkaTopicConfig `mapstructure:"topics"`
+	// SchemaRegistryPath is the local schema registry file written by the address api
+	SchemaRegistryPath string `mapstructure:"schemaRegistryPath"`
+}
+
+type KafkaTopicConfig struct {
+	GroupID          string `mapstructure:"groupId"`
+	Workers          int    `mapstructure:"workers"`
+	WorkerQueueSize  int    `mapstructure:"workerQueueSize"`
+	MaxPollRecords   int    `mapstructure:"maxPollRecords"`
+	FetchMaxWaitMs   int    `mapstructure:"fetchMaxWaitMs"`
+	StartOffset      string `mapstructure:"startOffset"`
+	MinBytes         int    `mapstructure:"minBytes"`
+	MaxBytes         int    `mapstructure:"maxBytes"`
+	CommitIntervalMs int    `mapstructure:"commitIntervalMs"`
+}
+
+// KafkaRetryConfig is the backoff of a message whose processing failed with a retryable error
+type KafkaRetryConfig struct {
+	MaxAttempts      int     `mapstructure:"maxAttempts"`
+	InitialBackoffMs int     `mapstructure:"initialBackoffMs"`
+	MaxBackoffMs     int     `mapstructure:"maxBackoffMs"`
+	Multiplier       float64 `mapstructure:"multiplier"`
+	// Jitter between 0 and 1 is the fraction of the backoff randomly taken off
+	Jitter float64 `mapstructure:"jitter"`
+}
+
+// KafkaDeadLetterConfig moves messages failing every attempt through the retry topics to the dead-letter topic
+type KafkaDeadLetterConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RetryDelays name the retry topics in order, 1m and 10m are address-created.retry.1m and address-created.retry.10m
+	RetryDelays []string `mapstructure:"retryDelays"`
+	// RedriveGroupId is the group the dlq command reads the dead-letter topics with
+	RedriveGroupId string `mapstructure:"redriveGroupId"`
+}
+
+type KafkaTLSConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CaFile             string `mapstructure:"caFile"`
+	CertFile           string `mapstructure:"certFile"`
+	KeyFile            string `mapstructure:"keyFile"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+}
+
+type KafkaSASLConfig struct {
+	// Mechanism is plain, scram-sha-256 or scram-sha-512, empty disables SASL
+	Mechanism string `mapstructure:"mechanism"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+}
+
+// ForTopic returns the settings of the topic with its overrides applied
+func (k KafkaConfig) ForTopic(topic string) KafkaConfig {
+	cfg := k
+	if cfg.GroupID == "" {
+		cfg.GroupID = cfg.ConsumerGroup
+	}
+
+	override, ok := k.Topics[topic]
+	if !ok {
+		return cfg
+	}
+
+	if override.GroupID != "" {
+		cfg.GroupID = override.GroupID
+	}
+	if override.Workers != 0 {
+		cfg.Workers = override.Workers
+	}
+	if override.WorkerQueueSize != 0 {
+		cfg.WorkerQueueSize = override.WorkerQueueSize
+	}
+	if override.MaxPollRecords != 0 {
+		cfg.MaxPollRecords = override.MaxPollRecords
+	}
+	if override.FetchMaxWaitMs != 0 {
+		cfg.FetchMaxWaitMs = override.FetchMaxWaitMs
+	}
+	if override.StartOffset != "" {
+		cfg.StartOffset = override.StartOffset
+	}
+	if override.MinBytes != 0 {
+		cfg.MinBytes = override.MinBytes
+	}
+	if override.MaxBytes != 0 {
+		cfg.MaxBytes = override.MaxBytes
+	}
+	if override.CommitIntervalMs != 0 {
+		cfg.CommitIntervalMs = override.CommitIntervalMs
+	}
+
+	return cfg
 }
 
 func NewConfig() *Config {
@@ -60,6 +244,10 @@ func getAllKeys(t reflect.Type) []string {
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		n := strings.ToUpper(f.Tag.Get(tagName))
+		// maps can not be defaulted to an empty string, they are only read from the yml files
+		if reflect.Map == f.Type.Kind() {
+			continue
+		}
 		if reflect.Struct == f.Type.Kind() {
 			subKeys := getAllKeys(f.Type)
 			for _, k := range subKeys {
@@ -31,6 +31,9 @@ type Logger interface {
 	Errorf(template string, args ...interface{})
 	DPanicf(template string, args ...interface{})
 	Fatalf(template string, args ...interface{})
+
+	// Sync flushes the buffered log entries, it is called before the process exits
+	Sync() error
 }
 
 // logger struct, implements Logger interface
@@ -156,6 +159,10 @@ var loggerLevelMap = map[string]zapcore.Level{
 }
 
 // getLogLevel, return zapcore.level based on the log level in config
+func (l *logger) Sync() error {
+	return l.sugarLogger.Sync()
+}
+
 func (l *logger) getLogLevel(cfg *config.Config) zapcore.Level {
 	level, exist := loggerLevelMap[cfg.Logger.Level]
 	if !exist {
@@ -0,0 +1,147 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"log"
+)
+
+// Metrics records the consumption of the kafka messages per topic, the topic of a retry consumer is its retry topic
+type Metrics interface {
+	IncreaseDuplicates(topic string)
+	IncreaseConsumed(topic string)
+	IncreaseProcessed(topic string)
+	IncreaseFailed(topic string)
+	IncreaseRetries(topic string)
+	IncreaseDeadLetters(topic string)
+	ObserveProcessingTime(topic string, seconds float64)
+	// SetLag is the lag the reader reported for the partitions it reads, in a group it is not split by partition
+	SetLag(topic string, lag int64)
+	// ResetLag drops the lag of the topic, the consumer may not own its partitions after a rebalance
+	ResetLag(topic string)
+}
+
+type metrics struct {
+	Duplicates     *prometheus.CounterVec
+	Consumed       *prometheus.CounterVec
+	Processed      *prometheus.CounterVec
+	Failed         *prometheus.CounterVec
+	Retries        *prometheus.CounterVec
+	DeadLetters    *prometheus.CounterVec
+	ProcessingTime *prometheus.HistogramVec
+	Lag            *prometheus.GaugeVec
+}
+
+func (metric *metrics) IncreaseDuplicates(topic string) {
+	metric.Duplicates.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) IncreaseConsumed(topic string) {
+	metric.Consumed.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) IncreaseProcessed(topic string) {
+	metric.Processed.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) IncreaseFailed(topic string) {
+	metric.Failed.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) IncreaseRetries(topic string) {
+	metric.Retries.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) IncreaseDeadLetters(topic string) {
+	metric.DeadLetters.WithLabelValues(topic).Inc()
+}
+
+func (metric *metrics) ObserveProcessingTime(topic string, seconds float64) {
+	metric.ProcessingTime.WithLabelValues(topic).Observe(seconds)
+}
+
+func (metric *metrics) SetLag(topic string, lag int64) {
+	metric.Lag.WithLabelValues(topic).Set(float64(lag))
+}
+
+func (metric *metrics) ResetLag(topic string) {
+	metric.Lag.DeleteLabelValues(topic)
+}
+
+// CreateMetrics registers the consumer metrics, they are served by the admin server
+func CreateMetrics(name string) (Metrics, error) {
+	var metric metrics
+	metric.Duplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_duplicate_events_total",
+		Help: "Redelivered events dropped because they were already processed.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Duplicates); err != nil {
+		log.Printf("Error registering Duplicates: %v", err)
+		return nil, err
+	}
+
+	metric.Consumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_messages_consumed_total",
+		Help: "Messages fetched from kafka.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Consumed); err != nil {
+		log.Printf("Error registering Consumed: %v", err)
+		return nil, err
+	}
+
+	metric.Processed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_messages_processed_total",
+		Help: "Messages processed successfully.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Processed); err != nil {
+		log.Printf("Error registering Processed: %v", err)
+		return nil, err
+	}
+
+	metric.Failed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_messages_failed_total",
+		Help: "Messages the consumer gave up on, they are moved to a retry or dead-letter topic or skipped.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Failed); err != nil {
+		log.Printf("Error registering Failed: %v", err)
+		return nil, err
+	}
+
+	metric.Retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_message_retries_total",
+		Help: "Retries of failed messages, in process or through a retry topic.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Retries); err != nil {
+		log.Printf("Error registering Retries: %v", err)
+		return nil, err
+	}
+
+	metric.DeadLetters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name + "_dead_letters_total",
+		Help: "Messages moved to the dead-letter topic.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.DeadLetters); err != nil {
+		log.Printf("Error registering DeadLetters: %v", err)
+		return nil, err
+	}
+
+	metric.ProcessingTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name + "_processing_time_seconds",
+		Help:    "Time from the start of processing a message until it is handled, retries included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.ProcessingTime); err != nil {
+		log.Printf("Error registering ProcessingTime: %v", err)
+		return nil, err
+	}
+
+	metric.Lag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name + "_consumer_lag",
+		Help: "Messages of the topic not fetched yet by this consumer, as reported by its reader.",
+	}, []string{"topic"})
+	if err := prometheus.Register(metric.Lag); err != nil {
+		log.Printf("Error registering Lag: %v", err)
+		return nil, err
+	}
+
+	return &metric, nil
+}
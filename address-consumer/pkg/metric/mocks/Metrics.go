@@ -0,0 +1,69 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Metrics is an autogenerated mock type for the Metrics type
+type Metrics struct {
+	mock.Mock
+}
+
+// IncreaseConsumed provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseConsumed(topic string) {
+	_m.Called(topic)
+}
+
+// IncreaseDeadLetters provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseDeadLetters(topic string) {
+	_m.Called(topic)
+}
+
+// IncreaseDuplicates provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseDuplicates(topic string) {
+	_m.Called(topic)
+}
+
+// IncreaseFailed provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseFailed(topic string) {
+	_m.Called(topic)
+}
+
+// IncreaseProcessed provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseProcessed(topic string) {
+	_m.Called(topic)
+}
+
+// IncreaseRetries provides a mock function with given fields: topic
+func (_m *Metrics) IncreaseRetries(topic string) {
+	_m.Called(topic)
+}
+
+// ObserveProcessingTime provides a mock function with given fields: topic, seconds
+func (_m *Metrics) ObserveProcessingTime(topic string, seconds float64) {
+	_m.Called(topic, seconds)
+}
+
+// ResetLag provides a mock function with given fields: topic
+func (_m *Metrics) ResetLag(topic string) {
+	_m.Called(topic)
+}
+
+// SetLag provides a mock function with given fields: topic, lag
+func (_m *Metrics) SetLag(topic string, lag int64) {
+	_m.Called(topic, lag)
+}
+
+// NewMetrics creates a new instance of Metrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMetrics(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Metrics {
+	mock := &Metrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// Migration is a single versioned schema change
+// Up runs inside a transaction and every migration is applied only once
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *gorm.DB) error
+}
+
+// schemaMigration keeps the applied migration versions, the consumer shares the database with the
+// address api so its versions are kept apart from the api migrations
+type schemaMigration struct {
+	Version     int `gorm:"primaryKey;autoIncrement:false"`
+	Description string
+	AppliedAt   time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "consumer_schema_migrations"
+}
+
+// processedEventV1 remembers the events a consumer group processed
+type processedEventV1 struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	ProcessedAt time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+func (processedEventV1) TableName() string {
+	return "processed_events"
+}
+
+// addressProjectionV2 is the latest state of an address, deleted addresses stay as tombstones
+type addressProjectionV2 struct {
+	AddressId int    `gorm:"primaryKey;autoIncrement:false"`
+	TenantId  string `gorm:"size:64;index:idx_address_projections_owner"`
+	UserId    string `gorm:"size:255;index:idx_address_projections_owner"`
+	Version   int64
+	Snapshot  string
+	Deleted   bool
+	ChangedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (addressProjectionV2) TableName() string {
+	return "address_projections"
+}
+
+// addressBookV2 is the denormalized address book of a user
+type addressBookV2 struct {
+	TenantId         string `gorm:"primaryKey;size:64"`
+	UserId           string `gorm:"primaryKey;size:255"`
+	AddressCount     int
+	DefaultAddressId *int
+	Addresses        string
+	LastChangedAt    time.Time
+	UpdatedAt        time.Time
+}
+
+func (addressBookV2) TableName() string {
+	return "address_books"
+}
+
+// Migrations returns every known migration ordered by version
+func Migrations() []Migration {
+	migrations := []Migration{
+		{
+			Version:     1,
+			Description: "create processed_events table",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&processedEventV1{})
+			},
+		},
+		{
+			Version:     2,
+			Description: "create address read model tables",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&addressProjectionV2{}, &addressBookV2{})
+			},
+		},
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations
+}
+
+// Migrate applies the pending migrations in version order
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("postgres.Migrate.SchemaMigrationsTable: %w", err)
+	}
+
+	var applied []int
+	if err := db.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return fmt.Errorf("postgres.Migrate.AppliedVersions: %w", err)
+	}
+
+	appliedVersions := make(map[int]bool, len(applied))
+	for _, version := range applied {
+		appliedVersions[version] = true
+	}
+
+	for _, migration := range Migrations() {
+		if appliedVersions[migration.Version] {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&schemaMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("postgres.Migrate.Version%d: %w", migration.Version, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"time"
+)
+
+// NewPsqlDb function connect postgresql database
+// The gorm.DB object is returned according to the connection settings in the config.
+func NewPsqlDb(c *config.Config) (*gorm.DB, error) {
+	// create postgresql connection string
+	connectionString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		c.Postgres.UserName,
+		c.Postgres.Password,
+		c.Postgres.Host,
+		c.Postgres.Port,
+		c.Postgres.DbName)
+
+	// open postgresql connection
+	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{
+		// dialect errors such as unique violations are translated to gorm errors, e.g. gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// we reach *sql.DB object for set database configuration settings
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// database connection pool settings
+	sqlDB.SetMaxOpenConns(c.Postgres.MaxOpenConnections)
+	sqlDB.SetConnMaxLifetime(time.Duration(c.Postgres.ConnMaxLifeTime) * time.Second)
+	sqlDB.SetMaxIdleConns(c.Postgres.MaxIdleConnections)
+	sqlDB.SetConnMaxIdleTime(time.Duration(c.Postgres.ConnMaxIdleTime) * time.Second)
+	// check database connection
+	if err = sqlDB.Ping(); err != nil {
+		return nil, err
+	}
+
+	// Apply versioned schema migrations
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
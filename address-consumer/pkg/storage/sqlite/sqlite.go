@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"github.com/sefikcan/address-consumer/pkg/config"
+	"github.com/sefikcan/address-consumer/pkg/storage/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSqliteDb opens the sqlite database file of the projection, it runs the same migrations as postgres
+func NewSqliteDb(c *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(c.Projection.SqlitePath), &gorm.Config{
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite allows a single writer, the workers wait for each other instead of failing with a busy database
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := postgres.Migrate(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,51 @@
+package events
+
+const (
+	AddressCreated = "AddressCreated"
+	AddressUpdated = "AddressUpdated"
+	AddressDeleted = "AddressDeleted"
+
+	// Source is the CloudEvents source of every address event
+	Source = "/address-api"
+)
+
+var cloudEventTypes = map[string]string{
+	AddressCreated: "com.sefikcan.address.created",
+	AddressUpdated: "com.sefikcan.address.updated",
+	AddressDeleted: "com.sefikcan.address.deleted",
+}
+
+// CloudEventType returns the reverse-DNS CloudEvents type of the event type
+func CloudEventType(eventType string) string {
+	return cloudEventTypes[eventType]
+}
+
+// AddressEvent is the data of an address CloudEvent in the CurrentVersion of the contract
+// Created carries After, Updated carries Before and After, Deleted carries Before
+type AddressEvent struct {
+	EventType string `json:"event_type"`
+	AddressId int    `json:"addressId"`
+	UserId    string `json:"userId"`
+	TenantId  string `json:"tenantId,omitempty"`
+	// Version is the version of the address after the change, events published before version 3 of the contract carry 0
+	Version int64            `json:"version,omitempty"`
+	Before  *AddressSnapshot `json:"before,omitempty"`
+	After   *AddressSnapshot `json:"after,omitempty"`
+}
+
+// AddressSnapshot is the state of an address at one side of a change
+type AddressSnapshot struct {
+	City         string   `json:"city"`
+	Country      string   `json:"country"`
+	FullAddress  string   `json:"fullAddress"`
+	UserId       string   `json:"userId"`
+	AddressLine1 string   `json:"addressLine1,omitempty"`
+	AddressLine2 string   `json:"addressLine2,omitempty"`
+	HouseNumber  string   `json:"houseNumber,omitempty"`
+	District     string   `json:"district,omitempty"`
+	Region       string   `json:"region,omitempty"`
+	PostalCode   string   `json:"postalCode,omitempty"`
+	CountryCode  string   `json:"countryCode,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+}
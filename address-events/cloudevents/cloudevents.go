@@ -0,0 +1,196 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// ContentTypeStructured marks a message whose value is the whole event
+	ContentTypeStructured = "application/cloudevents+json"
+	ContentTypeJson       = "application/json"
+
+	ModeStructured = "structured"
+	ModeBinary     = "binary"
+
+	HeaderContentType = "content-type"
+	// HeaderRequestId and the W3C trace context headers are sent in every mode for correlation
+	HeaderRequestId   = "x-request-id"
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+	// headerPrefix is the kafka protocol binding prefix of the attribute headers in binary mode
+	headerPrefix = "ce_"
+)
+
+var ErrInvalidEvent = errors.New("invalid cloudevent")
+
+// Event is a CloudEvents 1.0 event carrying a json payload
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	Id              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	RequestId       string    `json:"requestid,omitempty"`
+	Actor           string    `json:"actor,omitempty"`
+	TenantId        string    `json:"tenantid,omitempty"`
+	// Traceparent and Tracestate are the attributes of the distributed tracing extension
+	Traceparent string          `json:"traceparent,omitempty"`
+	Tracestate  string          `json:"tracestate,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// New returns an event with a unique id and the current time, data is encoded as json
+func New(source, eventType, dataSchema string, data interface{}) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		Id:              uuid.NewString(),
+		Source:          source,
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJson,
+		DataSchema:      dataSchema,
+		Data:            encoded,
+	}, nil
+}
+
+// Validate checks the attributes required by the specification
+func (e Event) Validate() error {
+	if e.SpecVersion != SpecVersion {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidEvent, e.SpecVersion)
+	}
+	if e.Id == "" || e.Source == "" || e.Type == "" {
+		return fmt.Errorf("%w: id, source and type are required", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// DataAs decodes the payload of the event into v
+func (e Event) DataAs(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Structured encodes the event in structured mode, the whole event is the message value
+func (e Event) Structured() (map[string]string, []byte, error) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return map[string]string{HeaderContentType: ContentTypeStructured}, value, nil
+}
+
+// Binary encodes the event in binary mode, attributes travel as ce_ headers and data is the message value
+func (e Event) Binary() (map[string]string, []byte) {
+	headers := map[string]string{
+		headerPrefix + "specversion": e.SpecVersion,
+		headerPrefix + "id":          e.Id,
+		headerPrefix + "source":      e.Source,
+		headerPrefix + "type":        e.Type,
+		headerPrefix + "time":        e.Time.Format(time.RFC3339Nano),
+	}
+
+	optional := map[string]string{
+		"subject":     e.Subject,
+		"dataschema":  e.DataSchema,
+		"requestid":   e.RequestId,
+		"actor":       e.Actor,
+		"tenantid":    e.TenantId,
+		"traceparent": e.Traceparent,
+		"tracestate":  e.Tracestate,
+	}
+	for name, value := range optional {
+		if value != "" {
+			headers[headerPrefix+name] = value
+		}
+	}
+
+	if e.DataContentType != "" {
+		headers[HeaderContentType] = e.DataContentType
+	}
+
+	return headers, e.Data
+}
+
+// CorrelationHeaders returns the request id and trace context as plain headers, so consumers
+// can correlate a message without decoding the event
+func (e Event) CorrelationHeaders() map[string]string {
+	headers := map[string]string{}
+	for name, value := range map[string]string{
+		HeaderRequestId:   e.RequestId,
+		HeaderTraceparent: e.Traceparent,
+		HeaderTracestate:  e.Tracestate,
+	} {
+		if value != "" {
+			headers[name] = value
+		}
+	}
+
+	return headers
+}
+
+// Encode encodes the event in the given mode, structured is used for unknown modes
+func (e Event) Encode(mode string) (map[string]string, []byte, error) {
+	if mode == ModeBinary {
+		headers, value := e.Binary()
+		return headers, value, nil
+	}
+
+	return e.Structured()
+}
+
+// Decode reads an event in either mode, binary mode is detected by the ce_specversion header
+func Decode(headers map[string]string, value []byte) (Event, error) {
+	var event Event
+
+	if _, ok := headers[headerPrefix+"specversion"]; ok {
+		event = Event{
+			SpecVersion:     headers[headerPrefix+"specversion"],
+			Id:              headers[headerPrefix+"id"],
+			Source:          headers[headerPrefix+"source"],
+			Type:            headers[headerPrefix+"type"],
+			Subject:         headers[headerPrefix+"subject"],
+			DataContentType: headers[HeaderContentType],
+			DataSchema:      headers[headerPrefix+"dataschema"],
+			RequestId:       headers[headerPrefix+"requestid"],
+			Actor:           headers[headerPrefix+"actor"],
+			TenantId:        headers[headerPrefix+"tenantid"],
+			Traceparent:     headers[headerPrefix+"traceparent"],
+			Tracestate:      headers[headerPrefix+"tracestate"],
+			Data:            value,
+		}
+
+		if eventTime := headers[headerPrefix+"time"]; eventTime != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, eventTime)
+			if err != nil {
+				return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+			}
+			event.Time = parsed
+		}
+	} else {
+		contentType := headers[HeaderContentType]
+		if contentType != "" && !strings.HasPrefix(contentType, ContentTypeStructured) {
+			return Event{}, fmt.Errorf("%w: unsupported content type %q", ErrInvalidEvent, contentType)
+		}
+
+		if err := json.Unmarshal(value, &event); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+	}
+
+	return event, event.Validate()
+}
@@ -0,0 +1,70 @@
+package cloudevents
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestEvent(t *testing.T) Event {
+	event, err := New("/address-api", "com.sefikcan.address.updated", "urn:test:v2", map[string]string{"city": "Istanbul"})
+	assert.NoError(t, err)
+
+	event.Subject = "7"
+	event.RequestId = "req-1"
+	event.Actor = "42"
+	event.TenantId = "brand-a"
+	event.Traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	return event
+}
+
+func TestDecode_BothModes(t *testing.T) {
+	event := newTestEvent(t)
+
+	structuredHeaders, structuredValue, err := event.Encode(ModeStructured)
+	assert.NoError(t, err)
+	binaryHeaders, binaryValue, err := event.Encode(ModeBinary)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ContentTypeStructured, structuredHeaders[HeaderContentType])
+	assert.Equal(t, "req-1", binaryHeaders["ce_requestid"])
+	assert.JSONEq(t, `{"city":"Istanbul"}`, string(binaryValue))
+
+	for _, encoded := range []struct {
+		headers map[string]string
+		value   []byte
+	}{{structuredHeaders, structuredValue}, {binaryHeaders, binaryValue}} {
+		decoded, err := Decode(encoded.headers, encoded.value)
+
+		assert.NoError(t, err)
+		assert.Equal(t, event.Id, decoded.Id)
+		assert.True(t, event.Time.Equal(decoded.Time))
+		assert.Equal(t, "urn:test:v2", decoded.DataSchema)
+		assert.Equal(t, "42", decoded.Actor)
+		assert.Equal(t, "brand-a", decoded.TenantId)
+		assert.Equal(t, event.Traceparent, decoded.Traceparent)
+
+		var data map[string]string
+		assert.NoError(t, decoded.DataAs(&data))
+		assert.Equal(t, "Istanbul", data["city"])
+	}
+}
+
+func TestDecode_RejectsInvalidEvents(t *testing.T) {
+	_, err := Decode(nil, []byte(`{"event_type":"AddressCreated","addressId":1}`))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	_, err = Decode(map[string]string{"ce_specversion": "0.3", "ce_id": "1", "ce_source": "s", "ce_type": "t"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	_, err = Decode(map[string]string{HeaderContentType: "text/plain"}, []byte("plain"))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestCorrelationHeaders(t *testing.T) {
+	event := newTestEvent(t)
+
+	assert.Equal(t, map[string]string{
+		HeaderRequestId:   "req-1",
+		HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}, event.CorrelationHeaders())
+}
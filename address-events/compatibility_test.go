@@ -0,0 +1,176 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sefikcan/address-events/registry"
+	"github.com/sefikcan/address-events/serde"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// publishedSchemas pins every released schema file, consumers rely on them so a published
+// schema must never change, add a new version with an upcaster instead
+var publishedSchemas = map[string]string{
+	"address-event.v1.json":  "a2aa87df0d8f4f16fe3481ef09f746a175ddeed57badd0dbfb831bf89a7ee9de",
+	"address-event.v2.json":  "5085e441fca31b32c959aba4eb9fe56591a92d69a8aac9b1583ebe251be53323",
+	"address-event.v2.avsc":  "933392d19ac2575a7aa10d4a6b26d936fdbf0c445ddba23002661a202c84f8a4",
+	"address-event.v2.proto": "0667dcaf10fb128d05401d43527c6f9b708224d78ff5c4a2b5b150292c8644cb",
+	"address-event.v3.json":  "3b8dc380baccebbf7f6f4d2fdf0aee1b047d7dd1060b036ee68bf872fd5117e7",
+	"address-event.v3.avsc":  "33d4893b98e6626af5c73308dcf64b15ee35d32df95495a153a403a3be1a63c8",
+	"address-event.v3.proto": "27d2bfc96118ae47762125e553429a30c815cdcd09f6dcf12e1d08e05f0ea472",
+}
+
+func TestCompatibility_PublishedSchemasAreUnchanged(t *testing.T) {
+	files, err := schemas.ReadDir("schemas")
+	assert.NoError(t, err)
+	assert.Len(t, files, len(publishedSchemas), "pin the hash of new schema files")
+
+	for _, file := range files {
+		schema, err := schemas.ReadFile("schemas/" + file.Name())
+		assert.NoError(t, err)
+
+		sum := sha256.Sum256(schema)
+		assert.Equal(t, publishedSchemas[file.Name()], hex.EncodeToString(sum[:]), "schema %s changed", file.Name())
+	}
+
+	for _, read := range []func(int) ([]byte, error){Schema, AvroSchema, ProtobufSchema} {
+		_, err := read(CurrentVersion)
+		assert.NoError(t, err)
+
+		_, err = read(CurrentVersion + 1)
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	}
+}
+
+type jsonSchema struct {
+	Type       string                 `json:"type"`
+	Ref        string                 `json:"$ref"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Defs       map[string]*jsonSchema `json:"$defs"`
+}
+
+// TestCompatibility_EventMatchesCurrentSchema fails when AddressEvent drifts from the current schema
+func TestCompatibility_EventMatchesCurrentSchema(t *testing.T) {
+	raw, err := Schema(CurrentVersion)
+	assert.NoError(t, err)
+
+	var schema jsonSchema
+	assert.NoError(t, json.Unmarshal(raw, &schema))
+
+	assertMatchesSchema(t, "AddressEvent", reflect.TypeOf(AddressEvent{}), &schema, schema.Defs)
+}
+
+func assertMatchesSchema(t *testing.T, path string, typ reflect.Type, schema *jsonSchema, defs map[string]*jsonSchema) {
+	if schema.Ref != "" {
+		schema = defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	kinds := map[reflect.Kind]string{
+		reflect.String: "string", reflect.Int: "integer", reflect.Int64: "integer",
+		reflect.Float64: "number", reflect.Bool: "boolean", reflect.Struct: "object",
+	}
+	if !assert.Equal(t, schema.Type, kinds[typ.Kind()], "type of %s", path) || typ.Kind() != reflect.Struct {
+		return
+	}
+
+	fields := map[string]bool{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fields[name] = true
+
+		property, ok := schema.Properties[name]
+		if !assert.True(t, ok, "%s.%s is not in the schema", path, name) {
+			continue
+		}
+		assertMatchesSchema(t, path+"."+name, field.Type, property, defs)
+
+		if isRequired(schema, name) {
+			assert.NotContains(t, options, "omitempty", "required %s.%s must always be encoded", path, name)
+		}
+	}
+
+	for name := range schema.Properties {
+		assert.True(t, fields[name], "%s.%s of the schema is missing in the struct", path, name)
+	}
+}
+
+func isRequired(schema *jsonSchema, name string) bool {
+	for _, required := range schema.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TestCompatibility_FixturesDecodeToCurrentVersion decodes a stored event of every published version
+func TestCompatibility_FixturesDecodeToCurrentVersion(t *testing.T) {
+	files, _ := filepath.Glob("testdata/v*/*.json")
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			var version int
+			_, err := fmt.Sscanf(filepath.Base(filepath.Dir(file)), "v%d", &version)
+			assert.NoError(t, err)
+
+			data, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			addressEvent, err := DecodeAddressEvent(DataSchema(version), data)
+			assert.NoError(t, err)
+			assert.Equal(t, 7, addressEvent.AddressId)
+			assert.Equal(t, "brand-a", addressEvent.TenantId)
+			assert.NotEmpty(t, CloudEventType(addressEvent.EventType))
+
+			// events of the current version survive a round trip without losing fields
+			if version == CurrentVersion {
+				encoded, err := json.Marshal(addressEvent)
+				assert.NoError(t, err)
+				assert.JSONEq(t, string(data), string(encoded))
+			}
+		})
+	}
+}
+
+// TestCompatibility_FixturesSurviveEveryFormat fails when the avro or protobuf schema drops data of the json contract
+func TestCompatibility_FixturesSurviveEveryFormat(t *testing.T) {
+	schemaRegistry, err := registry.NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(fmt.Sprintf("testdata/v%d/*.json", CurrentVersion))
+	assert.NotEmpty(t, files)
+
+	for _, format := range []string{serde.FormatJson, serde.FormatAvro, serde.FormatProtobuf} {
+		serializer, err := NewSerializer(format, schemaRegistry)
+		assert.NoError(t, err)
+
+		for _, file := range files {
+			data, _ := os.ReadFile(file)
+
+			value, err := serializer.Serialize(format+"-value", data)
+			assert.NoError(t, err, "%s %s", format, file)
+
+			decoded, err := serializer.Deserialize(value)
+			assert.NoError(t, err, "%s %s", format, file)
+
+			// binary formats may write absent optional fields as defaults, the typed event must be the same
+			var expected, actual AddressEvent
+			assert.NoError(t, json.Unmarshal(data, &expected))
+			assert.NoError(t, json.Unmarshal(decoded, &actual))
+			assert.Equal(t, expected, actual, "%s %s", format, file)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package protoschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const fileName = "schema.proto"
+
+var ErrNoMessage = errors.New("protobuf schema has no message")
+
+// Compile parses a .proto schema and returns its first message, which is the message of the value
+func Compile(schema string) (protoreflect.MessageDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{fileName: schema}),
+		},
+	}
+
+	files, err := compiler.Compile(context.Background(), fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := files[0].Messages()
+	if messages.Len() == 0 {
+		return nil, ErrNoMessage
+	}
+
+	return messages.Get(0), nil
+}
+
+// FromJson encodes json data as a message of the descriptor, fields unknown to the schema are dropped
+func FromJson(descriptor protoreflect.MessageDescriptor, data []byte) ([]byte, error) {
+	message := dynamicpb.NewMessage(descriptor)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(message)
+}
+
+// ToJson decodes a message of the descriptor into json data
+// unlike protojson, 64 bit integers stay json numbers so the data matches the json contract
+func ToJson(descriptor protoreflect.MessageDescriptor, value []byte) ([]byte, error) {
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(value, message); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(toMap(message))
+}
+
+func toMap(message protoreflect.Message) map[string]interface{} {
+	result := map[string]interface{}{}
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsList():
+			list := value.List()
+			items := make([]interface{}, list.Len())
+			for i := range items {
+				items[i] = toValue(field, list.Get(i))
+			}
+			result[field.JSONName()] = items
+		case field.IsMap():
+			entries := map[string]interface{}{}
+			value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				entries[key.String()] = toValue(field.MapValue(), value)
+				return true
+			})
+			result[field.JSONName()] = entries
+		default:
+			result[field.JSONName()] = toValue(field, value)
+		}
+		return true
+	})
+
+	return result
+}
+
+func toValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return toMap(value.Message())
+	case protoreflect.EnumKind:
+		if enum := field.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+		return int32(value.Enum())
+	case protoreflect.BytesKind:
+		return value.Bytes()
+	default:
+		return value.Interface()
+	}
+}
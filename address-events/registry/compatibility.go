@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"github.com/sefikcan/address-events/internal/protoschema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func validate(schemaType, schema string) error {
+	switch schemaType {
+	case TypeAvro:
+		_, err := avro.Parse(schema)
+		return err
+	case TypeProtobuf:
+		_, err := protoschema.Compile(schema)
+		return err
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, schemaType)
+	}
+}
+
+// checkCompatibility enforces backward compatibility, consumers using the new schema must read data of the latest one
+func checkCompatibility(schemaType, schema string, latest Schema) error {
+	if schemaType != latest.Type {
+		return fmt.Errorf("%w: type changed from %s to %s", ErrIncompatibleSchema, latest.Type, schemaType)
+	}
+
+	switch schemaType {
+	case TypeAvro:
+		reader, err := avro.Parse(schema)
+		if err != nil {
+			return err
+		}
+		writer, err := avro.Parse(latest.Schema)
+		if err != nil {
+			return err
+		}
+
+		if err := avro.NewSchemaCompatibility().Compatible(reader, writer); err != nil {
+			return fmt.Errorf("%w: %v", ErrIncompatibleSchema, err)
+		}
+		return nil
+	case TypeProtobuf:
+		reader, err := protoschema.Compile(schema)
+		if err != nil {
+			return err
+		}
+		writer, err := protoschema.Compile(latest.Schema)
+		if err != nil {
+			return err
+		}
+
+		return compatibleMessages(reader, writer, string(reader.FullName()), map[protoreflect.FullName]bool{})
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, schemaType)
+	}
+}
+
+// compatibleMessages reports fields whose number was reused for another type or whose name moved to another number
+// removing a field is allowed, its number should be reserved in the new schema
+func compatibleMessages(reader, writer protoreflect.MessageDescriptor, path string, seen map[protoreflect.FullName]bool) error {
+	// recursive messages are only compared once
+	if seen[writer.FullName()] {
+		return nil
+	}
+	seen[writer.FullName()] = true
+
+	fields := writer.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		old := fields.Get(i)
+
+		if renamed := reader.Fields().ByName(old.Name()); renamed != nil && renamed.Number() != old.Number() {
+			return fmt.Errorf("%w: %s.%s moved from field %d to %d", ErrIncompatibleSchema, path, old.Name(), old.Number(), renamed.Number())
+		}
+
+		current := reader.Fields().ByNumber(old.Number())
+		if current == nil {
+			continue
+		}
+
+		if current.Kind() != old.Kind() || current.Cardinality() != old.Cardinality() || current.IsMap() != old.IsMap() {
+			return fmt.Errorf("%w: %s field %d changed from %s %s to %s %s", ErrIncompatibleSchema, path, old.Number(),
+				old.Cardinality(), old.Kind(), current.Cardinality(), current.Kind())
+		}
+
+		if old.Message() != nil && !old.IsMap() {
+			if err := compatibleMessages(current.Message(), old.Message(), path+"."+string(old.Name()), seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
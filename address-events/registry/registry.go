@@ -0,0 +1,235 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+)
+
+const (
+	lockRetry   = 10 * time.Millisecond
+	lockTimeout = 5 * time.Second
+	// a lock file older than staleLock was left by a process that stopped while registering
+	staleLock = 30 * time.Second
+)
+
+var (
+	ErrSchemaNotFound     = errors.New("schema not found")
+	ErrIncompatibleSchema = errors.New("schema is incompatible with the latest version of the subject")
+	ErrUnsupportedType    = errors.New("unsupported schema type")
+	ErrRegistryLocked     = errors.New("schema registry is locked by another process")
+)
+
+// Schema is a registered version of a subject, ids are global like in the Confluent schema registry
+type Schema struct {
+	Id      int    `json:"id"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+	Type    string `json:"schemaType"`
+	Schema  string `json:"schema"`
+}
+
+// Registry stores the schemas referenced by the id of Confluent wire format messages
+type Registry interface {
+	// Register adds the schema as the next version of the subject unless it is already registered,
+	// the schema must be backward compatible with the latest version of the subject
+	Register(subject, schemaType, schema string) (Schema, error)
+	GetById(id int) (Schema, error)
+	GetLatest(subject string) (Schema, error)
+}
+
+// fileRegistry keeps the schemas in a json file, so producers and consumers sharing the file
+// agree on schema ids without an external registry. The mutex orders the registrations of a process,
+// a lock file next to the registry those of processes sharing the file
+type fileRegistry struct {
+	path    string
+	mu      sync.Mutex
+	schemas []Schema
+}
+
+func (f *fileRegistry) Register(subject, schemaType, schema string) (Schema, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := f.lock()
+	if err != nil {
+		return Schema{}, err
+	}
+	defer unlock()
+
+	// read under the lock, so the next version and id account for registrations of other processes
+	if err := f.load(); err != nil {
+		return Schema{}, err
+	}
+
+	id, latest := 0, Schema{}
+	for _, registered := range f.schemas {
+		if registered.Type == schemaType && registered.Schema == schema {
+			if registered.Subject == subject {
+				return registered, nil
+			}
+			id = registered.Id
+		}
+		if registered.Subject == subject && registered.Version > latest.Version {
+			latest = registered
+		}
+	}
+
+	if latest.Version > 0 {
+		if err := checkCompatibility(schemaType, schema, latest); err != nil {
+			return Schema{}, err
+		}
+	} else if err := validate(schemaType, schema); err != nil {
+		return Schema{}, err
+	}
+
+	if id == 0 {
+		for _, registered := range f.schemas {
+			if registered.Id > id {
+				id = registered.Id
+			}
+		}
+		id++
+	}
+
+	registered := Schema{Id: id, Subject: subject, Version: latest.Version + 1, Type: schemaType, Schema: schema}
+	f.schemas = append(f.schemas, registered)
+
+	return registered, f.save()
+}
+
+func (f *fileRegistry) GetById(id int) (Schema, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if schema, ok := f.find(func(s Schema) bool { return s.Id == id }); ok {
+		return schema, nil
+	}
+
+	// the schema may have been registered by another process since the file was read
+	if err := f.load(); err != nil {
+		return Schema{}, err
+	}
+	if schema, ok := f.find(func(s Schema) bool { return s.Id == id }); ok {
+		return schema, nil
+	}
+
+	return Schema{}, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+}
+
+func (f *fileRegistry) GetLatest(subject string) (Schema, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.load(); err != nil {
+		return Schema{}, err
+	}
+
+	latest := Schema{}
+	for _, registered := range f.schemas {
+		if registered.Subject == subject && registered.Version > latest.Version {
+			latest = registered
+		}
+	}
+	if latest.Version == 0 {
+		return Schema{}, fmt.Errorf("%w: subject %s", ErrSchemaNotFound, subject)
+	}
+
+	return latest, nil
+}
+
+func (f *fileRegistry) find(match func(Schema) bool) (Schema, bool) {
+	for _, schema := range f.schemas {
+		if match(schema) {
+			return schema, true
+		}
+	}
+	return Schema{}, false
+}
+
+func (f *fileRegistry) load() error {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var schemas []Schema
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return fmt.Errorf("schema registry %s: %w", f.path, err)
+	}
+
+	f.schemas = schemas
+	return nil
+}
+
+// lock creates the lock file of the registry, it exists while a process registers a schema
+func (f *fileRegistry) lock() (func(), error) {
+	path := f.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrRegistryLocked, path)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// save replaces the file atomically so readers never see a partially written registry
+func (f *fileRegistry) save() error {
+	data, err := json.MarshalIndent(f.schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// NewFileRegistry returns a registry backed by the json file at path, the file is created on the first Register
+func NewFileRegistry(path string) (Registry, error) {
+	registry := &fileRegistry{path: path}
+	if err := registry.load(); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
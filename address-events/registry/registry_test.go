@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const avroV1 = `{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}`
+
+func newTestRegistry(t *testing.T) (Registry, string) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	schemaRegistry, err := NewFileRegistry(path)
+	assert.NoError(t, err)
+	return schemaRegistry, path
+}
+
+func TestFileRegistry_RegisterIsIdempotentAndShared(t *testing.T) {
+	schemaRegistry, path := newTestRegistry(t)
+
+	created, err := schemaRegistry.Register("address-created-value", TypeAvro, avroV1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created.Id)
+	assert.Equal(t, 1, created.Version)
+
+	again, err := schemaRegistry.Register("address-created-value", TypeAvro, avroV1)
+	assert.NoError(t, err)
+	assert.Equal(t, created, again)
+
+	// the same schema under another subject keeps its global id
+	updated, err := schemaRegistry.Register("address-updated-value", TypeAvro, avroV1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated.Id)
+
+	// another process reading the same file resolves the id
+	other, err := NewFileRegistry(path)
+	assert.NoError(t, err)
+	schema, err := other.GetById(1)
+	assert.NoError(t, err)
+	assert.Equal(t, avroV1, schema.Schema)
+
+	_, err = other.GetById(2)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+	_, err = other.GetLatest("address-deleted-value")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestFileRegistry_EnforcesAvroBackwardCompatibility(t *testing.T) {
+	schemaRegistry, _ := newTestRegistry(t)
+	_, err := schemaRegistry.Register("address-value", TypeAvro, avroV1)
+	assert.NoError(t, err)
+
+	// a new field without a default cannot be read from old data
+	_, err = schemaRegistry.Register("address-value", TypeAvro,
+		`{"type":"record","name":"Address","fields":[{"name":"city","type":"string"},{"name":"country","type":"string"}]}`)
+	assert.ErrorIs(t, err, ErrIncompatibleSchema)
+
+	compatible, err := schemaRegistry.Register("address-value", TypeAvro,
+		`{"type":"record","name":"Address","fields":[{"name":"city","type":"string"},{"name":"country","type":"string","default":""}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, compatible.Id)
+	assert.Equal(t, 2, compatible.Version)
+
+	latest, err := schemaRegistry.GetLatest("address-value")
+	assert.NoError(t, err)
+	assert.Equal(t, compatible, latest)
+}
+
+func TestFileRegistry_EnforcesProtobufBackwardCompatibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		wantOk bool
+	}{
+		{name: "new field", schema: `syntax = "proto3"; message Address { string city = 1; int64 id = 2; string country = 3; }`, wantOk: true},
+		{name: "removed field", schema: `syntax = "proto3"; message Address { string city = 1; reserved 2; }`, wantOk: true},
+		{name: "changed type", schema: `syntax = "proto3"; message Address { string city = 1; string id = 2; }`},
+		{name: "moved field", schema: `syntax = "proto3"; message Address { string city = 3; int64 id = 2; }`},
+		{name: "repeated field", schema: `syntax = "proto3"; message Address { repeated string city = 1; int64 id = 2; }`},
+		{name: "other type", schema: avroV1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemaRegistry, _ := newTestRegistry(t)
+			_, err := schemaRegistry.Register("address-value", TypeProtobuf,
+				`syntax = "proto3"; message Address { string city = 1; int64 id = 2; }`)
+			assert.NoError(t, err)
+
+			schemaType := TypeProtobuf
+			if tt.schema == avroV1 {
+				schemaType = TypeAvro
+			}
+
+			_, err = schemaRegistry.Register("address-value", schemaType, tt.schema)
+
+			if tt.wantOk {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrIncompatibleSchema)
+			}
+		})
+	}
+}
+
+func TestFileRegistry_ProcessesSharingTheFileDoNotOverwriteEachOther(t *testing.T) {
+	_, path := newTestRegistry(t)
+
+	// every registry stands for another process, they only share the file
+	const processes, subjects = 8, 10
+	var wg sync.WaitGroup
+	registered := make([][]Schema, processes)
+	for i := 0; i < processes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			schemaRegistry, err := NewFileRegistry(path)
+			assert.NoError(t, err)
+
+			for j := 0; j < subjects; j++ {
+				schema := fmt.Sprintf(`{"type":"record","name":"Address%d_%d","fields":[{"name":"city","type":"string"}]}`, i, j)
+				created, err := schemaRegistry.Register(fmt.Sprintf("subject-%d-%d-value", i, j), TypeAvro, schema)
+				assert.NoError(t, err)
+				registered[i] = append(registered[i], created)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	other, err := NewFileRegistry(path)
+	assert.NoError(t, err)
+	ids := map[int]bool{}
+	for i := range registered {
+		for j, schema := range registered[i] {
+			ids[schema.Id] = true
+			latest, err := other.GetLatest(fmt.Sprintf("subject-%d-%d-value", i, j))
+			assert.NoError(t, err)
+			assert.Equal(t, schema, latest)
+		}
+	}
+	assert.Len(t, ids, processes*subjects)
+}
+
+func TestFileRegistry_StaleLockIsTakenOver(t *testing.T) {
+	schemaRegistry, path := newTestRegistry(t)
+
+	// left by a process that stopped while registering
+	assert.NoError(t, os.WriteFile(path+".lock", nil, 0o644))
+	stale := time.Now().Add(-2 * staleLock)
+	assert.NoError(t, os.Chtimes(path+".lock", stale, stale))
+
+	_, err := schemaRegistry.Register("address-created-value", TypeAvro, avroV1)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path+".lock")
+}
@@ -0,0 +1,311 @@
+package serde
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"github.com/sefikcan/address-events/registry"
+	"strconv"
+	"sync"
+)
+
+type avroSerializer struct {
+	registry registry.Registry
+	schema   string
+	writer   avro.Schema
+
+	mu sync.Mutex
+	// ids caches the registered schema id per subject
+	ids map[string]int
+	// readers caches the parsed writer schemas of consumed values
+	readers sync.Map
+}
+
+func (a *avroSerializer) ContentType() string {
+	return ContentTypeAvro
+}
+
+func (a *avroSerializer) Serialize(subject string, data []byte) ([]byte, error) {
+	id, err := a.schemaId(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as text so longs beyond the precision of float64 survive
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	native, err := toAvro(a.writer, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := avro.Marshal(a.writer, native)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(writeHeader(id), encoded...), nil
+}
+
+func (a *avroSerializer) Deserialize(value []byte) ([]byte, error) {
+	id, value, err := readHeader(value)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := a.reader(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var native interface{}
+	if err := avro.Unmarshal(schema, value, &native); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fromAvro(schema, native))
+}
+
+func (a *avroSerializer) schemaId(subject string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if id, ok := a.ids[subject]; ok {
+		return id, nil
+	}
+	if a.writer == nil {
+		return 0, fmt.Errorf("%w: no writer schema", ErrUnsupportedFormat)
+	}
+
+	registered, err := a.registry.Register(subject, registry.TypeAvro, a.schema)
+	if err != nil {
+		return 0, err
+	}
+
+	a.ids[subject] = registered.Id
+	return registered.Id, nil
+}
+
+func (a *avroSerializer) reader(id int) (avro.Schema, error) {
+	if schema, ok := a.readers.Load(id); ok {
+		return schema.(avro.Schema), nil
+	}
+
+	registered, err := a.registry.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := avro.Parse(registered.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	a.readers.Store(id, schema)
+	return schema, nil
+}
+
+// toAvro converts decoded json to the native values the avro encoder expects for the schema,
+// json numbers become the integer types of the schema and union values are wrapped in their type name
+func toAvro(schema avro.Schema, value interface{}) (interface{}, error) {
+	switch s := dereference(schema).(type) {
+	case *avro.RecordSchema:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: %s expects an object", s.FullName())
+		}
+
+		record := make(map[string]interface{}, len(s.Fields()))
+		for _, field := range s.Fields() {
+			fieldValue, ok := object[field.Name()]
+			if !ok && field.HasDefault() {
+				fieldValue = field.Default()
+			}
+
+			converted, err := toAvro(field.Type(), fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%w", field.Name(), err)
+			}
+			record[field.Name()] = converted
+		}
+		return record, nil
+	case *avro.UnionSchema:
+		if value == nil {
+			if s.Nullable() {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("avro: union without null got null")
+		}
+
+		for _, member := range s.Types() {
+			if member.Type() == avro.Null {
+				continue
+			}
+			if converted, err := toAvro(member, value); err == nil {
+				return map[string]interface{}{unionName(member): converted}, nil
+			}
+		}
+		return nil, fmt.Errorf("avro: no union member matches %v", value)
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: expects an array")
+		}
+
+		converted := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if converted[i], err = toAvro(s.Items(), item); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	case *avro.MapSchema:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: expects an object")
+		}
+
+		converted := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			var err error
+			if converted[key], err = toAvro(s.Values(), entry); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	case *avro.PrimitiveSchema:
+		return toPrimitive(s.Type(), value)
+	default:
+		// enums and fixed values keep their json representation
+		return value, nil
+	}
+}
+
+func toPrimitive(schemaType avro.Type, value interface{}) (interface{}, error) {
+	number, isNumber := value.(json.Number)
+
+	switch {
+	case schemaType == avro.Null && value == nil:
+		return nil, nil
+	case (schemaType == avro.Int || schemaType == avro.Long) && isNumber:
+		integer, err := strconv.ParseInt(string(number), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("avro: %s is not a %s", number, schemaType)
+		}
+		if schemaType == avro.Int {
+			return int32(integer), nil
+		}
+		return integer, nil
+	case (schemaType == avro.Float || schemaType == avro.Double) && isNumber:
+		float, err := number.Float64()
+		if err != nil {
+			return nil, err
+		}
+		if schemaType == avro.Float {
+			return float32(float), nil
+		}
+		return float, nil
+	case schemaType == avro.String || schemaType == avro.Bytes:
+		if text, ok := value.(string); ok {
+			if schemaType == avro.Bytes {
+				return []byte(text), nil
+			}
+			return text, nil
+		}
+	case schemaType == avro.Boolean:
+		if flag, ok := value.(bool); ok {
+			return flag, nil
+		}
+	}
+
+	return nil, fmt.Errorf("avro: %v is not a %s", value, schemaType)
+}
+
+// fromAvro converts decoded avro back to json values, unwrapping the union values
+func fromAvro(schema avro.Schema, value interface{}) interface{} {
+	switch s := dereference(schema).(type) {
+	case *avro.RecordSchema:
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+
+		object := make(map[string]interface{}, len(record))
+		for _, field := range s.Fields() {
+			if fieldValue := fromAvro(field.Type(), record[field.Name()]); fieldValue != nil {
+				object[field.Name()] = fieldValue
+			}
+		}
+		return object
+	case *avro.UnionSchema:
+		wrapped, ok := value.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return value
+		}
+
+		for _, member := range s.Types() {
+			if inner, ok := wrapped[unionName(member)]; ok {
+				return fromAvro(member, inner)
+			}
+		}
+		return value
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+
+		converted := make([]interface{}, len(items))
+		for i, item := range items {
+			converted[i] = fromAvro(s.Items(), item)
+		}
+		return converted
+	case *avro.MapSchema:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+
+		converted := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			converted[key] = fromAvro(s.Values(), entry)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// dereference resolves the reference to a named type defined earlier in the schema
+func dereference(schema avro.Schema) avro.Schema {
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		return ref.Schema()
+	}
+	return schema
+}
+
+// unionName is the key of a union value, the full name for named types and the type otherwise
+func unionName(schema avro.Schema) string {
+	schema = dereference(schema)
+	if named, ok := schema.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	return string(schema.Type())
+}
+
+func newAvroSerializer(schemaRegistry registry.Registry, schema string) (Serializer, error) {
+	writer, err := avro.Parse(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &avroSerializer{registry: schemaRegistry, schema: schema, writer: writer, ids: map[string]int{}}, nil
+}
@@ -0,0 +1,111 @@
+package serde
+
+import (
+	"fmt"
+	"github.com/sefikcan/address-events/internal/protoschema"
+	"github.com/sefikcan/address-events/registry"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sync"
+)
+
+type protobufSerializer struct {
+	registry registry.Registry
+	schema   string
+	writer   protoreflect.MessageDescriptor
+
+	mu sync.Mutex
+	// ids caches the registered schema id per subject
+	ids map[string]int
+	// readers caches the compiled writer schemas of consumed values
+	readers sync.Map
+}
+
+func (p *protobufSerializer) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (p *protobufSerializer) Serialize(subject string, data []byte) ([]byte, error) {
+	id, err := p.schemaId(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := protoschema.FromJson(p.writer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the zero message index selects the first message of the schema
+	value := append(writeHeader(id), 0)
+	return append(value, encoded...), nil
+}
+
+func (p *protobufSerializer) Deserialize(value []byte) ([]byte, error) {
+	id, value, err := readHeader(value)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, value, err := readMessageIndexes(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) != 1 || indexes[0] != 0 {
+		return nil, fmt.Errorf("%w: only the first message of a schema is supported", ErrInvalidWireFormat)
+	}
+
+	descriptor, err := p.reader(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoschema.ToJson(descriptor, value)
+}
+
+func (p *protobufSerializer) schemaId(subject string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.ids[subject]; ok {
+		return id, nil
+	}
+	if p.writer == nil {
+		return 0, fmt.Errorf("%w: no writer schema", ErrUnsupportedFormat)
+	}
+
+	registered, err := p.registry.Register(subject, registry.TypeProtobuf, p.schema)
+	if err != nil {
+		return 0, err
+	}
+
+	p.ids[subject] = registered.Id
+	return registered.Id, nil
+}
+
+func (p *protobufSerializer) reader(id int) (protoreflect.MessageDescriptor, error) {
+	if descriptor, ok := p.readers.Load(id); ok {
+		return descriptor.(protoreflect.MessageDescriptor), nil
+	}
+
+	schema, err := p.registry.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := protoschema.Compile(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	p.readers.Store(id, descriptor)
+	return descriptor, nil
+}
+
+func newProtobufSerializer(schemaRegistry registry.Registry, schema string) (Serializer, error) {
+	writer, err := protoschema.Compile(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protobufSerializer{registry: schemaRegistry, schema: schema, writer: writer, ids: map[string]int{}}, nil
+}
@@ -0,0 +1,79 @@
+package serde
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sefikcan/address-events/registry"
+	"strings"
+)
+
+const (
+	FormatJson     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+
+	ContentTypeJson     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported serialization format")
+
+// ValueSubject is the registry subject of the values of a topic, the Confluent topic name strategy
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+// Serializer converts the json data of an event to and from the value of a kafka message
+type Serializer interface {
+	// ContentType is sent in the content-type header so consumers pick the matching serializer
+	ContentType() string
+	// Serialize encodes json data with the writer schema registered for the subject
+	Serialize(subject string, data []byte) ([]byte, error)
+	// Deserialize decodes a value into json data
+	Deserialize(value []byte) ([]byte, error)
+}
+
+// NewSerializer returns the serializer of the format, schema is the writer schema for protobuf and avro
+func NewSerializer(format string, schemaRegistry registry.Registry, schema string) (Serializer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJson:
+		return jsonSerializer{}, nil
+	case FormatProtobuf:
+		return newProtobufSerializer(schemaRegistry, schema)
+	case FormatAvro:
+		return newAvroSerializer(schemaRegistry, schema)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// ForContentType returns a serializer able to read values of the content type, writer schemas come from the registry
+func ForContentType(contentType string, schemaRegistry registry.Registry) (Serializer, error) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+
+	switch strings.TrimSpace(mediaType) {
+	case "", ContentTypeJson:
+		return jsonSerializer{}, nil
+	case ContentTypeProtobuf:
+		return &protobufSerializer{registry: schemaRegistry, ids: map[string]int{}}, nil
+	case ContentTypeAvro:
+		return &avroSerializer{registry: schemaRegistry, ids: map[string]int{}}, nil
+	default:
+		return nil, fmt.Errorf("%w: content type %s", ErrUnsupportedFormat, contentType)
+	}
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) ContentType() string {
+	return ContentTypeJson
+}
+
+func (jsonSerializer) Serialize(_ string, data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (jsonSerializer) Deserialize(value []byte) ([]byte, error) {
+	return value, nil
+}
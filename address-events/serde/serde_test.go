@@ -0,0 +1,99 @@
+package serde
+
+import (
+	"github.com/sefikcan/address-events/registry"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testAvroSchema = `{"type":"record","name":"Address","namespace":"test","fields":[
+		{"name":"id","type":"long"},
+		{"name":"city","type":"string","default":""},
+		{"name":"latitude","type":["null","double"],"default":null},
+		{"name":"owner","type":["null",{"type":"record","name":"Owner","fields":[{"name":"userId","type":"string"}]}],"default":null}]}`
+	testProtobufSchema = `syntax = "proto3";
+		message Address { int64 id = 1; string city = 2; optional double latitude = 3; Owner owner = 4; }
+		message Owner { string user_id = 1; }`
+)
+
+func newTestRegistry(t *testing.T) registry.Registry {
+	schemaRegistry, err := registry.NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	assert.NoError(t, err)
+	return schemaRegistry
+}
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	schemaRegistry := newTestRegistry(t)
+	data := `{"id":9007199254740993,"city":"Istanbul","latitude":41.01,"owner":{"userId":"1"}}`
+
+	tests := []struct {
+		format      string
+		schema      string
+		contentType string
+	}{
+		{format: FormatJson, contentType: ContentTypeJson},
+		{format: FormatAvro, schema: testAvroSchema, contentType: ContentTypeAvro},
+		{format: FormatProtobuf, schema: testProtobufSchema, contentType: ContentTypeProtobuf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			serializer, err := NewSerializer(tt.format, schemaRegistry, tt.schema)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.contentType, serializer.ContentType())
+
+			value, err := serializer.Serialize(tt.format+"-value", []byte(data))
+			assert.NoError(t, err)
+
+			// consumers only know the content type, the writer schema comes from the registry
+			deserializer, err := ForContentType(tt.contentType, schemaRegistry)
+			assert.NoError(t, err)
+
+			decoded, err := deserializer.Deserialize(value)
+			assert.NoError(t, err)
+			assert.JSONEq(t, data, string(decoded))
+			assert.Contains(t, string(decoded), "9007199254740993")
+		})
+	}
+}
+
+func TestSerializer_ConfluentWireFormat(t *testing.T) {
+	schemaRegistry := newTestRegistry(t)
+	_, _ = schemaRegistry.Register("other-value", registry.TypeAvro, `"string"`)
+
+	serializer, err := NewSerializer(FormatProtobuf, schemaRegistry, testProtobufSchema)
+	assert.NoError(t, err)
+
+	value, err := serializer.Serialize("address-created-value", []byte(`{"id":1}`))
+	assert.NoError(t, err)
+
+	// magic byte, schema id 2 in big endian and the message index shortcut of the first message
+	assert.Equal(t, []byte{0, 0, 0, 0, 2, 0}, value[:6])
+
+	_, err = serializer.Deserialize([]byte{1, 0, 0, 0, 2})
+	assert.ErrorIs(t, err, ErrInvalidWireFormat)
+	_, err = serializer.Deserialize([]byte{0, 0, 0, 0, 42, 0})
+	assert.ErrorIs(t, err, registry.ErrSchemaNotFound)
+}
+
+func TestReadMessageIndexes(t *testing.T) {
+	indexes, rest, err := readMessageIndexes([]byte{0, 8})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, indexes)
+	assert.Equal(t, []byte{8}, rest)
+
+	// two zigzag encoded indexes 1 and 0
+	indexes, _, err = readMessageIndexes([]byte{4, 2, 0})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, indexes)
+}
+
+func TestNewSerializer_RejectsUnknownFormats(t *testing.T) {
+	_, err := NewSerializer("xml", nil, "")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = ForContentType("text/plain", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,53 @@
+package serde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// magicByte starts every value in the Confluent wire format, followed by the big endian schema id
+const magicByte = 0
+
+var ErrInvalidWireFormat = errors.New("invalid wire format")
+
+func writeHeader(schemaId int) []byte {
+	header := make([]byte, 5)
+	header[0] = magicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaId))
+	return header
+}
+
+func readHeader(value []byte) (int, []byte, error) {
+	if len(value) < 5 || value[0] != magicByte {
+		return 0, nil, fmt.Errorf("%w: missing magic byte and schema id", ErrInvalidWireFormat)
+	}
+
+	return int(binary.BigEndian.Uint32(value[1:5])), value[5:], nil
+}
+
+// readMessageIndexes reads the protobuf message indexes following the schema id,
+// a single zero byte is the shortcut for the first message of the schema
+func readMessageIndexes(value []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(value)
+	if n <= 0 || count < 0 {
+		return nil, nil, fmt.Errorf("%w: invalid message indexes", ErrInvalidWireFormat)
+	}
+	value = value[n:]
+
+	if count == 0 {
+		return []int{0}, value, nil
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(value)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("%w: invalid message indexes", ErrInvalidWireFormat)
+		}
+		indexes[i] = int(index)
+		value = value[n:]
+	}
+
+	return indexes, value, nil
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"github.com/sefikcan/address-events/registry"
+	"github.com/sefikcan/address-events/serde"
+	"strings"
+)
+
+// NewSerializer returns the serializer of the format writing the CurrentVersion of the address event schemas
+func NewSerializer(format string, schemaRegistry registry.Registry) (serde.Serializer, error) {
+	var schema []byte
+	var err error
+
+	switch strings.ToLower(format) {
+	case serde.FormatAvro:
+		schema, err = AvroSchema(CurrentVersion)
+	case serde.FormatProtobuf:
+		schema, err = ProtobufSchema(CurrentVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return serde.NewSerializer(format, schemaRegistry, string(schema))
+}
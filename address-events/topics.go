@@ -1,4 +1,4 @@
-package constants
+package events
 
 type KafkaTopicsStruct struct {
 	AddressCreated string
@@ -6,6 +6,7 @@ type KafkaTopicsStruct struct {
 	AddressDeleted string
 }
 
+// KafkaTopics are the topics address events are published to
 var KafkaTopics = KafkaTopicsStruct{
 	AddressUpdated: "address-updated",
 	AddressCreated: "address-created",
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// upcaster converts the data of an event from its version to the next version
+type upcaster func(data []byte) ([]byte, error)
+
+// upcasters are keyed by the version they convert from
+var upcasters = map[int]upcaster{
+	1: upcastV1,
+	2: upcastV2,
+}
+
+// Upcast converts the data of an event of the version to the CurrentVersion
+func Upcast(version int, data []byte) ([]byte, error) {
+	if version < 1 || version > CurrentVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	for ; version < CurrentVersion; version++ {
+		upcast, ok := upcasters[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no upcaster from version %d", ErrUnsupportedVersion, version)
+		}
+
+		var err error
+		if data, err = upcast(data); err != nil {
+			return nil, fmt.Errorf("upcast from version %d: %w", version, err)
+		}
+	}
+
+	return data, nil
+}
+
+// DecodeAddressEvent decodes the data of an event with the dataschema into the current AddressEvent
+func DecodeAddressEvent(dataSchema string, data []byte) (AddressEvent, error) {
+	var addressEvent AddressEvent
+
+	version, err := VersionOf(dataSchema)
+	if err != nil {
+		return addressEvent, err
+	}
+
+	data, err = Upcast(version, data)
+	if err != nil {
+		return addressEvent, err
+	}
+
+	err = json.Unmarshal(data, &addressEvent)
+	return addressEvent, err
+}
+
+// addressEventV1 is the flat payload published before the CloudEvents envelope
+type addressEventV1 struct {
+	EventType string `json:"event_type"`
+	AddressId int    `json:"addressId"`
+	TenantId  string `json:"tenantId,omitempty"`
+	AddressSnapshot
+}
+
+// upcastV1 moves the flat address into After, version 1 deletes carried only the id so they get no snapshot
+func upcastV1(data []byte) ([]byte, error) {
+	var v1 addressEventV1
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+
+	v2 := AddressEvent{
+		EventType: v1.EventType,
+		AddressId: v1.AddressId,
+		UserId:    v1.UserId,
+		TenantId:  v1.TenantId,
+	}
+	if v1.EventType != AddressDeleted {
+		snapshot := v1.AddressSnapshot
+		v2.After = &snapshot
+	}
+
+	return json.Marshal(v2)
+}
+
+// upcastV2 keeps the data as it is, version 2 events do not know the address version and decode with version 0
+func upcastV2(data []byte) ([]byte, error) {
+	return data, nil
+}
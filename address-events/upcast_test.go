@@ -0,0 +1,37 @@
+package events
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionOf(t *testing.T) {
+	version, err := VersionOf("")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, err = VersionOf(DataSchema(CurrentVersion))
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentVersion, version)
+
+	for _, dataSchema := range []string{DataSchema(CurrentVersion + 1), DataSchema(0), "urn:other:v2", "urn:sefikcan:address-event:vx"} {
+		_, err = VersionOf(dataSchema)
+		assert.ErrorIs(t, err, ErrUnsupportedVersion, dataSchema)
+	}
+}
+
+func TestDecodeAddressEvent_UpcastsV1(t *testing.T) {
+	created, err := DecodeAddressEvent("", []byte(`{"event_type":"AddressCreated","addressId":7,"city":"Istanbul","userId":"1"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1", created.UserId)
+	assert.Nil(t, created.Before)
+	assert.Equal(t, "Istanbul", created.After.City)
+
+	deleted, err := DecodeAddressEvent(DataSchema(1), []byte(`{"event_type":"AddressDeleted","addressId":7}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, AddressDeleted, deleted.EventType)
+	assert.Nil(t, deleted.Before)
+	assert.Nil(t, deleted.After)
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CurrentVersion is the version of the address event contract published by the address api
+// Bump it together with a new schema file and an upcaster from the previous version
+const CurrentVersion = 3
+
+const dataSchemaPrefix = "urn:sefikcan:address-event:v"
+
+var ErrUnsupportedVersion = errors.New("unsupported address event version")
+
+//go:embed schemas
+var schemas embed.FS
+
+// DataSchema returns the CloudEvents dataschema of the version
+func DataSchema(version int) string {
+	return dataSchemaPrefix + strconv.Itoa(version)
+}
+
+// VersionOf returns the version of a dataschema, events without a dataschema predate versioning and are version 1
+func VersionOf(dataSchema string) (int, error) {
+	if dataSchema == "" {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(dataSchema, dataSchemaPrefix))
+	if !strings.HasPrefix(dataSchema, dataSchemaPrefix) || err != nil || version < 1 || version > CurrentVersion {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedVersion, dataSchema)
+	}
+
+	return version, nil
+}
+
+// Schema returns the JSON schema of the version
+func Schema(version int) ([]byte, error) {
+	return readSchema(version, "json")
+}
+
+// AvroSchema returns the avro schema of the version, avro is published since version 2
+func AvroSchema(version int) ([]byte, error) {
+	return readSchema(version, "avsc")
+}
+
+// ProtobufSchema returns the protobuf schema of the version, protobuf is published since version 2
+func ProtobufSchema(version int) ([]byte, error) {
+	return readSchema(version, "proto")
+}
+
+func readSchema(version int, extension string) ([]byte, error) {
+	schema, err := schemas.ReadFile(fmt.Sprintf("schemas/address-event.v%d.%s", version, extension))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	return schema, nil
+}